@@ -0,0 +1,255 @@
+package gosnowflake
+
+import (
+	"container/heap"
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AdmissionControlConfig configures an AdmissionController.
+type AdmissionControlConfig struct {
+	MaxInFlight int // maximum number of queries running at once per key. Required.
+	// MaxInFlightPerKey overrides MaxInFlight for some keys, e.g. to give a small warehouse a lower limit.
+	// Warehouse keys are in upper case.
+	MaxInFlightPerKey map[string]int
+	// MaxQueued is the maximum number of queries waiting per key. Queries beyond it are rejected with
+	// ErrAdmissionQueueFull. Unlimited if not set.
+	MaxQueued int
+}
+
+// AdmissionStats is a snapshot of the statistics of a key of an AdmissionController.
+type AdmissionStats struct {
+	Key       string
+	InFlight  int           // queries currently running
+	Queued    int           // queries currently waiting
+	Admitted  int64         // queries started, with or without waiting
+	Waited    int64         // queries that had to wait before starting
+	Rejected  int64         // queries rejected because the queue was full
+	Abandoned int64         // queries whose context was done while waiting
+	TotalWait time.Duration // total time the admitted queries waited
+	MaxWait   time.Duration // longest time an admitted query waited
+}
+
+// AdmissionController is an opt-in client-side limit of the number of queries running at once, set with
+// Config.AdmissionController. Queries are keyed by the current warehouse of the connection in upper case, or by
+// the key set with WithAdmissionKey. Once MaxInFlight queries of a key are running, the next ones wait in a queue,
+// the ones with the highest priority set with WithAdmissionPriority first and in arrival order otherwise, until a
+// query finishes or their context is done. A controller is shared by all connections using the config, and can be
+// shared by several connectors.
+//
+// A query is running from the moment it is submitted until its result is returned by the server, which is
+// when the submission returns for asynchronous queries. Fetching result chunks is not limited.
+type AdmissionController struct {
+	cfg AdmissionControlConfig
+	now func() time.Time
+
+	mu   sync.Mutex
+	keys map[string]*admissionQueue
+	seq  uint64
+}
+
+type admissionQueue struct {
+	limit    int
+	inFlight int
+	waiters  admissionWaiters
+	stats    AdmissionStats
+}
+
+type admissionWaiter struct {
+	priority int
+	seq      uint64
+	enqueued time.Time
+	index    int           // index in the heap, -1 once admitted
+	ready    chan struct{} // closed when admitted
+	wait     time.Duration
+}
+
+// admissionWaiters is a heap of waiters, the highest priority first and the earliest in arrival order first.
+type admissionWaiters []*admissionWaiter
+
+func (w admissionWaiters) Len() int { return len(w) }
+
+func (w admissionWaiters) Less(i, j int) bool {
+	if w[i].priority != w[j].priority {
+		return w[i].priority > w[j].priority
+	}
+	return w[i].seq < w[j].seq
+}
+
+func (w admissionWaiters) Swap(i, j int) {
+	w[i], w[j] = w[j], w[i]
+	w[i].index = i
+	w[j].index = j
+}
+
+func (w *admissionWaiters) Push(x any) {
+	waiter := x.(*admissionWaiter)
+	waiter.index = len(*w)
+	*w = append(*w, waiter)
+}
+
+func (w *admissionWaiters) Pop() any {
+	old := *w
+	n := len(old)
+	waiter := old[n-1]
+	old[n-1] = nil
+	waiter.index = -1
+	*w = old[:n-1]
+	return waiter
+}
+
+// NewAdmissionController creates an AdmissionController.
+func NewAdmissionController(cfg AdmissionControlConfig) (*AdmissionController, error) {
+	if cfg.MaxInFlight <= 0 {
+		return nil, &SnowflakeError{
+			Number:      ErrCodeInvalidAdmissionLimit,
+			Message:     errMsgInvalidAdmissionLimit,
+			MessageArgs: []interface{}{cfg.MaxInFlight},
+		}
+	}
+	return &AdmissionController{
+		cfg:  cfg,
+		now:  time.Now,
+		keys: make(map[string]*admissionQueue),
+	}, nil
+}
+
+// Stats returns the statistics of every key that had queries, sorted by key.
+func (ac *AdmissionController) Stats() []AdmissionStats {
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+	stats := make([]AdmissionStats, 0, len(ac.keys))
+	for _, q := range ac.keys {
+		stat := q.stats
+		stat.InFlight = q.inFlight
+		stat.Queued = q.waiters.Len()
+		stats = append(stats, stat)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Key < stats[j].Key
+	})
+	return stats
+}
+
+// WithAdmissionKey returns a context whose queries are limited by Config.AdmissionController under the given key
+// instead of the warehouse of the connection, e.g. to limit the queries of a tenant or of a job.
+func WithAdmissionKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, admissionKeyKey, key)
+}
+
+// WithAdmissionPriority returns a context whose queries waiting for Config.AdmissionController are started before
+// the waiting queries with a lower priority. The default priority is 0.
+func WithAdmissionPriority(ctx context.Context, priority int) context.Context {
+	return context.WithValue(ctx, admissionPriorityKey, priority)
+}
+
+// admissionFor returns the controller and the key limiting the query, or nil if it is not limited.
+// Internal queries are not limited, so that session maintenance and cancellation are not queued behind
+// the queries they serve.
+func (sc *snowflakeConn) admissionFor(ctx context.Context, isInternal bool) (*AdmissionController, string) {
+	if sc.cfg == nil || sc.cfg.AdmissionController == nil || isInternal || ctx.Value(admissionBypassKey) != nil {
+		return nil, ""
+	}
+	if key, ok := ctx.Value(admissionKeyKey).(string); ok {
+		return sc.cfg.AdmissionController, key
+	}
+	sc.sessionState.mu.Lock()
+	defer sc.sessionState.mu.Unlock()
+	return sc.cfg.AdmissionController, strings.ToUpper(sc.cfg.Warehouse)
+}
+
+// admit waits until the query may run according to Config.AdmissionController, and returns the function to call
+// once it finished.
+func (sc *snowflakeConn) admit(ctx context.Context, op *instrumentedOperation, isInternal bool) (func(), error) {
+	ac, key := sc.admissionFor(ctx, isInternal)
+	if ac == nil {
+		return func() {}, nil
+	}
+	release, wait, err := ac.acquire(ctx, key)
+	op.recordAdmissionWait(ctx, key, wait, err)
+	if err != nil {
+		logger.WithContext(ctx).Warnf("query not admitted for %q after %v. err: %v", key, wait, err)
+		return nil, err
+	}
+	if wait > 0 {
+		logger.WithContext(ctx).Debugf("query admitted for %q after waiting %v", key, wait)
+	}
+	return release, nil
+}
+
+// acquire waits until a query of the key may run and returns the function to call once it finished,
+// and how long the query waited.
+func (ac *AdmissionController) acquire(ctx context.Context, key string) (func(), time.Duration, error) {
+	priority, _ := ctx.Value(admissionPriorityKey).(int)
+	ac.mu.Lock()
+	q := ac.queueLocked(key)
+	if q.inFlight < q.limit && q.waiters.Len() == 0 {
+		q.inFlight++
+		q.stats.Admitted++
+		ac.mu.Unlock()
+		return func() { ac.release(q) }, 0, nil
+	}
+	if ac.cfg.MaxQueued > 0 && q.waiters.Len() >= ac.cfg.MaxQueued {
+		q.stats.Rejected++
+		ac.mu.Unlock()
+		return nil, 0, &SnowflakeError{
+			Number:      ErrAdmissionQueueFull,
+			Message:     errMsgAdmissionQueueFull,
+			MessageArgs: []interface{}{key, ac.cfg.MaxQueued},
+		}
+	}
+	ac.seq++
+	w := &admissionWaiter{priority: priority, seq: ac.seq, enqueued: ac.now(), ready: make(chan struct{})}
+	heap.Push(&q.waiters, w)
+	ac.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return func() { ac.release(q) }, w.wait, nil
+	case <-ctx.Done():
+		ac.mu.Lock()
+		if w.index < 0 {
+			// admitted while the context was done, pass the slot on
+			ac.mu.Unlock()
+			ac.release(q)
+		} else {
+			heap.Remove(&q.waiters, w.index)
+			q.stats.Abandoned++
+			ac.mu.Unlock()
+		}
+		return nil, ac.now().Sub(w.enqueued), ctx.Err()
+	}
+}
+
+func (ac *AdmissionController) queueLocked(key string) *admissionQueue {
+	q, ok := ac.keys[key]
+	if !ok {
+		limit := ac.cfg.MaxInFlight
+		if l, ok := ac.cfg.MaxInFlightPerKey[key]; ok && l > 0 {
+			limit = l
+		}
+		q = &admissionQueue{limit: limit, stats: AdmissionStats{Key: key}}
+		ac.keys[key] = q
+	}
+	return q
+}
+
+// release ends a running query and starts the next waiting ones.
+func (ac *AdmissionController) release(q *admissionQueue) {
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+	q.inFlight--
+	for q.inFlight < q.limit && q.waiters.Len() > 0 {
+		w := heap.Pop(&q.waiters).(*admissionWaiter)
+		w.wait = ac.now().Sub(w.enqueued)
+		q.inFlight++
+		q.stats.Admitted++
+		q.stats.Waited++
+		q.stats.TotalWait += w.wait
+		q.stats.MaxWait = max(q.stats.MaxWait, w.wait)
+		close(w.ready)
+	}
+}
@@ -0,0 +1,173 @@
+package gosnowflake
+
+import (
+	"context"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestUnitAdmissionController(t *testing.T) {
+	_, err := NewAdmissionController(AdmissionControlConfig{})
+	assertEqualE(t, err.(*SnowflakeError).Number, ErrCodeInvalidAdmissionLimit)
+
+	ac, err := NewAdmissionController(AdmissionControlConfig{
+		MaxInFlight:       1,
+		MaxInFlightPerKey: map[string]int{"LARGE_WH": 3},
+		MaxQueued:         3,
+	})
+	assertNilF(t, err)
+	var nowMu sync.Mutex
+	now := time.Now()
+	ac.now = func() time.Time {
+		nowMu.Lock()
+		defer nowMu.Unlock()
+		return now
+	}
+	ctx := context.Background()
+	waitQueued := func(n int) {
+		for ac.Stats()[0].Queued != n {
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	release, wait, err := ac.acquire(ctx, "WH")
+	assertNilF(t, err)
+	assertEqualE(t, wait, time.Duration(0))
+
+	var order []string
+	var orderMu sync.Mutex
+	var wg sync.WaitGroup
+	waiter := func(name string, priority int) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release, _, err := ac.acquire(WithAdmissionPriority(ctx, priority), "WH")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			orderMu.Lock()
+			order = append(order, name)
+			orderMu.Unlock()
+			release()
+		}()
+	}
+	waiter("first", 0)
+	waitQueued(1)
+	waiter("second", 0)
+	waitQueued(2)
+	waiter("urgent", 5)
+	waitQueued(3)
+
+	_, _, err = ac.acquire(ctx, "WH")
+	assertEqualE(t, err.(*SnowflakeError).Number, ErrAdmissionQueueFull)
+
+	nowMu.Lock()
+	now = now.Add(time.Second)
+	nowMu.Unlock()
+	release()
+	wg.Wait()
+	assertDeepEqualE(t, order, []string{"urgent", "first", "second"})
+
+	stats := ac.Stats()
+	assertEqualF(t, len(stats), 1)
+	assertEqualE(t, stats[0].Key, "WH")
+	assertEqualE(t, stats[0].InFlight, 0)
+	assertEqualE(t, stats[0].Queued, 0)
+	assertEqualE(t, stats[0].Admitted, int64(4))
+	assertEqualE(t, stats[0].Waited, int64(3))
+	assertEqualE(t, stats[0].Rejected, int64(1))
+	assertEqualE(t, stats[0].TotalWait, 3*time.Second)
+	assertEqualE(t, stats[0].MaxWait, time.Second)
+
+	release, _, err = ac.acquire(ctx, "WH")
+	assertNilF(t, err)
+	timeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	_, _, err = ac.acquire(timeoutCtx, "WH")
+	assertErrIsE(t, err, context.DeadlineExceeded)
+	release()
+	stats = ac.Stats()
+	assertEqualE(t, stats[0].Abandoned, int64(1))
+	assertEqualE(t, stats[0].Queued, 0)
+	assertEqualE(t, stats[0].InFlight, 0)
+
+	for range 3 {
+		_, _, err = ac.acquire(ctx, "LARGE_WH")
+		assertNilE(t, err)
+	}
+	stats = ac.Stats()
+	assertEqualE(t, stats[0].Key, "LARGE_WH")
+	assertEqualE(t, stats[0].InFlight, 3)
+}
+
+func TestUnitAdmissionControlExec(t *testing.T) {
+	var running, maxRunning atomic.Int32
+	var queries atomic.Int32
+	postQueryMock := func(context.Context, *snowflakeRestful, *url.Values, map[string]string, []byte,
+		time.Duration, UUID, *Config) (*execResponse, error) {
+		queries.Add(1)
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			m := maxRunning.Load()
+			if n <= m || maxRunning.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		return &execResponse{Data: execResponseData{QueryID: NewUUID().String()}, Success: true}, nil
+	}
+	ac, err := NewAdmissionController(AdmissionControlConfig{MaxInFlight: 2})
+	assertNilF(t, err)
+	sc := &snowflakeConn{
+		cfg:                 &Config{Params: map[string]*string{}, Warehouse: "small_wh", AdmissionController: ac},
+		rest:                &snowflakeRestful{FuncPostQuery: postQueryMock, TokenAccessor: getSimpleTokenAccessor()},
+		telemetry:           &snowflakeTelemetry{enabled: false},
+		queryContextCache:   (&queryContextCache{}).init(),
+		currentTimeProvider: defaultTimeProvider,
+	}
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := sc.ExecContext(ctx, "INSERT INTO t VALUES (1)", nil)
+			assertNilE(t, err)
+		}()
+	}
+	wg.Wait()
+	assertEqualE(t, maxRunning.Load(), int32(2))
+	stats := ac.Stats()
+	assertEqualF(t, len(stats), 1)
+	assertEqualE(t, stats[0].Key, "SMALL_WH")
+	assertEqualE(t, stats[0].Admitted, int64(8))
+
+	_, err = sc.ExecContext(WithAdmissionKey(ctx, "tenant-1"), "SELECT 1", nil)
+	assertNilF(t, err)
+	stats = ac.Stats()
+	assertEqualF(t, len(stats), 2)
+	assertEqualE(t, stats[1].Key, "tenant-1")
+	assertEqualE(t, stats[1].Admitted, int64(1))
+
+	release1, _, err := ac.acquire(ctx, "SMALL_WH")
+	assertNilF(t, err)
+	release2, _, err := ac.acquire(ctx, "SMALL_WH")
+	assertNilF(t, err)
+	queries.Store(0)
+	timeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	_, err = sc.ExecContext(timeoutCtx, "SELECT 1", nil)
+	assertErrIsE(t, err, context.DeadlineExceeded)
+	assertEqualE(t, queries.Load(), int32(0), "a query not admitted should not be sent")
+	_, err = sc.ExecContext(WithInternal(ctx), "SELECT 1", nil)
+	assertNilE(t, err, "internal queries should not be limited")
+	assertEqualE(t, queries.Load(), int32(1))
+	release1()
+	release2()
+}
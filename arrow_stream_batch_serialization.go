@@ -0,0 +1,129 @@
+package gosnowflake
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+const serializedArrowStreamBatchVersion = 1
+
+// serializedArrowStreamBatch is the wire format of an ArrowStreamBatch handed to another process.
+type serializedArrowStreamBatch struct {
+	Version     int               `json:"version"`
+	NumRows     int64             `json:"numRows"`
+	Location    string            `json:"location,omitempty"`
+	URL         string            `json:"url,omitempty"`
+	ChunkHeader map[string]string `json:"chunkHeader,omitempty"`
+	Qrmk        string            `json:"qrmk,omitempty"`
+	Data        []byte            `json:"data,omitempty"`
+}
+
+// ArrowStreamBatchSerializer is implemented by the ArrowStreamLoader of the driver.
+type ArrowStreamBatchSerializer interface {
+	// SerializeBatches returns the batches of the current result set serialized with everything needed
+	// to download them without a connection. Use DeserializeBatch to restore a batch in another process.
+	SerializeBatches() ([][]byte, error)
+}
+
+// SerializeBatches serializes the batches of the current result set. Each batch carries the presigned URL
+// and the decryption key or headers of its chunk, so it should be handled like a credential and expires
+// together with the URL.
+func (scd *snowflakeArrowStreamChunkDownloader) SerializeBatches() ([][]byte, error) {
+	batches, err := scd.GetBatches()
+	if err != nil {
+		return nil, err
+	}
+	out := make([][]byte, len(batches))
+	for i := range batches {
+		if out[i], err = batches[i].serialize(); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+func (asb *ArrowStreamBatch) serialize() ([]byte, error) {
+	sb := serializedArrowStreamBatch{
+		Version: serializedArrowStreamBatchVersion,
+		NumRows: asb.numrows,
+	}
+	if asb.Loc != nil {
+		sb.Location = asb.Loc.String()
+	}
+	if asb.inline != nil {
+		sb.Data = asb.inline
+	} else {
+		sb.URL = asb.scd.ChunkMetas[asb.idx].URL
+		sb.ChunkHeader = asb.scd.ChunkHeader
+		sb.Qrmk = asb.scd.Qrmk
+	}
+	return json.Marshal(sb)
+}
+
+// DeserializeBatch restores a batch serialized with ArrowStreamBatchSerializer.SerializeBatches. The batch is downloaded
+// without a connection, using the transport, timeouts and retry settings of cfg. cfg can be nil to use defaults.
+func DeserializeBatch(data []byte, cfg *Config) (*ArrowStreamBatch, error) {
+	var sb serializedArrowStreamBatch
+	if err := json.Unmarshal(data, &sb); err != nil {
+		return nil, fmt.Errorf("failed to parse serialized batch: %w", err)
+	}
+	if sb.Version != serializedArrowStreamBatchVersion {
+		return nil, fmt.Errorf("unsupported serialized batch version: %v", sb.Version)
+	}
+	asb := &ArrowStreamBatch{numrows: sb.NumRows}
+	if sb.Location != "" {
+		loc, err := time.LoadLocation(sb.Location)
+		if err != nil {
+			return nil, err
+		}
+		asb.Loc = loc
+	}
+	if sb.URL == "" {
+		asb.inline = sb.Data
+		asb.rr = io.NopCloser(bytes.NewReader(sb.Data))
+		return asb, nil
+	}
+
+	sc, err := newDetachedChunkConn(cfg)
+	if err != nil {
+		return nil, err
+	}
+	asb.scd = &snowflakeArrowStreamChunkDownloader{
+		sc:          sc,
+		ChunkMetas:  []execResponseChunk{{URL: sb.URL, RowCount: int(sb.NumRows)}},
+		Total:       sb.NumRows,
+		Qrmk:        sb.Qrmk,
+		ChunkHeader: sb.ChunkHeader,
+		FuncGet:     getChunk,
+	}
+	return asb, nil
+}
+
+// newDetachedChunkConn returns a connection without a session, which can only be used to download result chunks.
+func newDetachedChunkConn(cfg *Config) (*snowflakeConn, error) {
+	if cfg == nil {
+		cfg = &Config{}
+	}
+	transport, err := newTransportFactory(cfg, nil).createTransport(cfg.transportConfigFor(transportTypeCloudProvider))
+	if err != nil {
+		return nil, err
+	}
+	maxRetryCount := cfg.MaxRetryCount
+	if maxRetryCount == 0 {
+		maxRetryCount = defaultMaxRetryCount
+	}
+	return &snowflakeConn{
+		cfg:                 cfg,
+		currentTimeProvider: defaultTimeProvider,
+		rest: &snowflakeRestful{
+			Client:         &http.Client{Transport: transport},
+			MaxRetryCount:  maxRetryCount,
+			RequestTimeout: cfg.RequestTimeout,
+			RetryPolicy:    cfg.RetryPolicy,
+		},
+	}, nil
+}
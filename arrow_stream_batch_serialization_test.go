@@ -0,0 +1,84 @@
+package gosnowflake
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+func newSerializationTestIPCStream(t *testing.T, values ...int64) []byte {
+	bldr := array.NewRecordBuilder(memory.DefaultAllocator, arrow.NewSchema([]arrow.Field{{Name: "C1", Type: arrow.PrimitiveTypes.Int64}}, nil))
+	defer bldr.Release()
+	bldr.Field(0).(*array.Int64Builder).AppendValues(values, nil)
+	record := bldr.NewRecord()
+	defer record.Release()
+
+	var buf bytes.Buffer
+	w := ipc.NewWriter(&buf, ipc.WithSchema(record.Schema()))
+	assertNilF(t, w.Write(record))
+	assertNilF(t, w.Close())
+	return buf.Bytes()
+}
+
+func TestSerializeAndDeserializeBatches(t *testing.T) {
+	inline := newSerializationTestIPCStream(t, 1, 2)
+	chunk := newSerializationTestIPCStream(t, 3, 4, 5)
+	var gotKey string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotKey = r.Header.Get(headerSseCKey)
+		_, _ = w.Write(chunk)
+	}))
+	defer server.Close()
+
+	warsaw, err := time.LoadLocation("Europe/Warsaw")
+	assertNilF(t, err)
+	timezone := "Europe/Warsaw"
+	scd := &snowflakeArrowStreamChunkDownloader{
+		sc:         &snowflakeConn{cfg: &Config{Params: map[string]*string{"timezone": &timezone}}},
+		ChunkMetas: []execResponseChunk{{URL: server.URL + "/chunk0", RowCount: 3}},
+		Total:      5,
+		Qrmk:       "secret",
+		RowSet:     rowSetType{RowSetBase64: base64.StdEncoding.EncodeToString(inline)},
+	}
+	serialized, err := scd.SerializeBatches()
+	assertNilF(t, err)
+	assertEqualF(t, len(serialized), 2)
+
+	first, err := DeserializeBatch(serialized[0], nil)
+	assertNilF(t, err)
+	assertEqualE(t, first.NumRows(), int64(2))
+	assertEqualE(t, first.Loc.String(), warsaw.String())
+	stream, err := first.GetStream(context.Background())
+	assertNilF(t, err)
+	data, err := io.ReadAll(stream)
+	assertNilF(t, err)
+	assertDeepEqualE(t, data, inline)
+
+	second, err := DeserializeBatch(serialized[1], &Config{MaxRetryCount: 1})
+	assertNilF(t, err)
+	assertEqualE(t, second.NumRows(), int64(3))
+	stream, err = second.GetStream(context.Background())
+	assertNilF(t, err)
+	defer stream.Close()
+	data, err = io.ReadAll(stream)
+	assertNilF(t, err)
+	assertDeepEqualE(t, data, chunk)
+	assertEqualE(t, gotKey, "secret")
+}
+
+func TestDeserializeBatchInvalid(t *testing.T) {
+	_, err := DeserializeBatch([]byte("{"), nil)
+	assertNotNilE(t, err)
+	_, err = DeserializeBatch([]byte(`{"version":99}`), nil)
+	assertNotNilE(t, err)
+}
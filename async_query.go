@@ -0,0 +1,176 @@
+package gosnowflake
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+const (
+	asyncQueryMinPollInterval = 500 * time.Millisecond
+	asyncQueryMaxPollInterval = 30 * time.Second
+)
+
+// AsyncQuery is a handle to a query submitted with SubmitQuery. It can be marshaled to JSON and stored, to
+// follow the query and fetch its result later from any connection of the same user, in any process.
+type AsyncQuery struct {
+	QueryID     string    `json:"queryId"`
+	SubmittedAt time.Time `json:"submittedAt"`
+}
+
+// AsyncQueryStatus is the status of an AsyncQuery.
+type AsyncQueryStatus struct {
+	// State is the status reported by Snowflake, e.g. QUEUED, RUNNING, SUCCESS or FAILED_WITH_ERROR.
+	State string
+	// Done is true once the query succeeded or failed.
+	Done bool
+	// Err is the error of a failed query.
+	Err *SnowflakeError
+	// StartTime and EndTime are when Snowflake started and finished the query, zero if not known yet.
+	StartTime time.Time
+	EndTime   time.Time
+}
+
+// SubmitQuery submits a query to run in the background of the session of conn, and returns without
+// waiting for it. Closing the connection logs out of the session and cancels the query, unless
+// Config.KeepSessionAlive is set.
+func SubmitQuery(ctx context.Context, conn *sql.Conn, query string, args ...any) (*AsyncQuery, error) {
+	var q *AsyncQuery
+	err := conn.Raw(func(x any) error {
+		sc, ok := x.(*snowflakeConn)
+		if !ok {
+			return fmt.Errorf("SubmitQuery requires a Snowflake connection, got %T", x)
+		}
+		if sc.rest == nil {
+			return driver.ErrBadConn
+		}
+		bindings, err := sc.namedValues(args)
+		if err != nil {
+			return err
+		}
+		_, _, sessionID := safeGetTokens(sc.rest)
+		ctx := context.WithValue(setResultType(WithAsyncMode(ctx), submitResultType), SFSessionIDKey, sessionID)
+		data, err := sc.exec(ctx, query, true, false, false, bindings)
+		if err != nil {
+			return err
+		}
+		q = &AsyncQuery{QueryID: data.Data.QueryID, SubmittedAt: time.Now()}
+		logger.WithContext(ctx).Infof("submitted query %v", q.QueryID)
+		return nil
+	})
+	return q, err
+}
+
+// Poll returns the status of the query without waiting for it. The error is only set if the status
+// could not be retrieved, the error of a failed query is in AsyncQueryStatus.Err.
+func (q *AsyncQuery) Poll(ctx context.Context, conn *sql.Conn) (*AsyncQueryStatus, error) {
+	var status *AsyncQueryStatus
+	err := conn.Raw(func(x any) error {
+		sc, ok := x.(*snowflakeConn)
+		if !ok {
+			return fmt.Errorf("AsyncQuery requires a Snowflake connection, got %T", x)
+		}
+		ret, err := sc.checkQueryStatus(ctx, q.QueryID)
+		if ret == nil {
+			return err
+		}
+		status = newAsyncQueryStatus(q.QueryID, ret)
+		return nil
+	})
+	return status, err
+}
+
+// newAsyncQueryStatus returns the status of a query from the query monitoring endpoint.
+func newAsyncQueryStatus(queryID string, ret *retStatus) *AsyncQueryStatus {
+	status := &AsyncQueryStatus{State: ret.Status}
+	if ret.StartTime > 0 {
+		status.StartTime = time.UnixMilli(ret.StartTime)
+	}
+	if ret.EndTime > 0 {
+		status.EndTime = time.UnixMilli(ret.EndTime)
+	}
+	qs := strToQueryStatus(ret.Status)
+	switch {
+	case ret.ErrorCode != "" || qs.isError() && qs != SFQueryBlocked:
+		code, err := strconv.Atoi(ret.ErrorCode)
+		if err != nil {
+			code = ErrQueryReportedError
+		}
+		status.Done = true
+		status.Err = &SnowflakeError{
+			Number:         code,
+			Message:        fmt.Sprintf("%s: status from server: [%s]", ret.ErrorMessage, ret.Status),
+			IncludeQueryID: true,
+			QueryID:        queryID,
+		}
+	case qs == SFQuerySuccess:
+		status.Done = true
+	}
+	return status
+}
+
+// Wait polls the query until it is done or ctx is done, starting every 500ms and backing off to every 30s.
+// If the query failed, its error is returned along with the status.
+func (q *AsyncQuery) Wait(ctx context.Context, conn *sql.Conn) (*AsyncQueryStatus, error) {
+	interval := asyncQueryMinPollInterval
+	for {
+		status, err := q.Poll(ctx, conn)
+		var se *SnowflakeError
+		switch {
+		case status != nil && status.Err != nil:
+			return status, status.Err
+		case status != nil && status.Done:
+			return status, nil
+		case err != nil && !(errors.As(err, &se) && se.Number == ErrQueryStatus):
+			return nil, err
+		}
+		// keep polling while the query runs, or its status is not available yet after it was submitted
+		select {
+		case <-ctx.Done():
+			return status, ctx.Err()
+		case <-time.After(interval):
+		}
+		interval = min(2*interval, asyncQueryMaxPollInterval)
+	}
+}
+
+// Cancel cancels the query if it is still running, see SnowflakeConnection.CancelQueryByID.
+func (q *AsyncQuery) Cancel(ctx context.Context, conn *sql.Conn) (CancelQueryResult, error) {
+	var result CancelQueryResult
+	err := conn.Raw(func(x any) (err error) {
+		sc, ok := x.(*snowflakeConn)
+		if !ok {
+			return fmt.Errorf("AsyncQuery requires a Snowflake connection, got %T", x)
+		}
+		result, err = sc.CancelQueryByID(ctx, q.QueryID)
+		return err
+	})
+	return result, err
+}
+
+// Results returns the rows of the query, waiting for it to finish if it is still running.
+func (q *AsyncQuery) Results(ctx context.Context, conn *sql.Conn) (*sql.Rows, error) {
+	return conn.QueryContext(WithFetchResultByID(ctx, q.QueryID), "")
+}
+
+// namedValues converts arguments the way database/sql does before they are passed to the connection.
+func (sc *snowflakeConn) namedValues(args []any) ([]driver.NamedValue, error) {
+	values := make([]driver.NamedValue, len(args))
+	for i, arg := range args {
+		nv := driver.NamedValue{Ordinal: i + 1, Value: arg}
+		if named, ok := arg.(sql.NamedArg); ok {
+			nv.Name, nv.Value = named.Name, named.Value
+		}
+		if err := sc.CheckNamedValue(&nv); err != nil {
+			if nv.Value, err = driver.DefaultParameterConverter.ConvertValue(nv.Value); err != nil {
+				return nil, fmt.Errorf("converting argument %v: %w", i+1, err)
+			}
+		}
+		values[i] = nv
+	}
+	return values, nil
+}
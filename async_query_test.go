@@ -0,0 +1,133 @@
+package gosnowflake
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUnitAsyncQuery(t *testing.T) {
+	var statuses []retStatus
+	var requests []execRequest
+	postQueryMock := func(_ context.Context, _ *snowflakeRestful, _ *url.Values, _ map[string]string, body []byte,
+		_ time.Duration, _ UUID, _ *Config) (*execResponse, error) {
+		var req execRequest
+		assertNilF(t, json.Unmarshal(body, &req))
+		requests = append(requests, req)
+		if strings.HasPrefix(req.SQLText, "SELECT SYSTEM$CANCEL_QUERY") {
+			msg := "query cancelled"
+			return &execResponse{Data: execResponseData{
+				QueryID:         "cancel",
+				StatementTypeID: statementTypeIDSelect,
+				RowType:         []execResponseRowType{{Name: "STATUS", Type: "text"}},
+				RowSet:          [][]*string{{&msg}},
+			}, Success: true}, nil
+		}
+		return &execResponse{Data: execResponseData{
+			QueryID:      "async-1",
+			GetResultURL: "/queries/async-1/result",
+		}, Code: queryInProgressAsyncCode, Success: true}, nil
+	}
+	getMock := func(_ context.Context, _ *snowflakeRestful, u *url.URL, _ map[string]string, _ time.Duration) (*http.Response, error) {
+		var resp any
+		if u.Path == "/queries/async-1/result" {
+			value := "42"
+			resp = &execResponse{Data: execResponseData{
+				QueryID:           "async-1",
+				StatementTypeID:   statementTypeIDSelect,
+				QueryResultFormat: "json",
+				RowType:           []execResponseRowType{{Name: "V", Type: "fixed"}},
+				RowSet:            [][]*string{{&value}},
+				Total:             1,
+				Returned:          1,
+			}, Success: true}
+		} else {
+			assertEqualE(t, u.Path, "/monitoring/queries/async-1")
+			status := &statusResponse{Success: true}
+			status.Data.Queries = statuses[:1]
+			if len(statuses) > 1 {
+				statuses = statuses[1:]
+			}
+			resp = status
+		}
+		b, err := json.Marshal(resp)
+		assertNilF(t, err)
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewReader(b))}, nil
+	}
+	sc := &snowflakeConn{
+		cfg:                 &Config{Params: map[string]*string{}, KeepSessionAlive: true},
+		rest:                &snowflakeRestful{FuncPostQuery: postQueryMock, FuncGet: getMock, TokenAccessor: getSimpleTokenAccessor()},
+		telemetry:           &snowflakeTelemetry{enabled: false},
+		queryContextCache:   (&queryContextCache{}).init(),
+		currentTimeProvider: defaultTimeProvider,
+	}
+	db := sql.OpenDB(scriptTestConnector{sc})
+	defer db.Close()
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	assertNilF(t, err)
+	defer conn.Close()
+
+	q, err := SubmitQuery(ctx, conn, "INSERT INTO t SELECT * FROM s WHERE d = ?", "2024-01-01")
+	assertNilF(t, err)
+	assertEqualE(t, q.QueryID, "async-1")
+	assertTrueE(t, requests[0].AsyncExec)
+	assertEqualE(t, requests[0].Bindings["1"].Value, "2024-01-01")
+
+	b, err := json.Marshal(q)
+	assertNilF(t, err)
+	var restored AsyncQuery
+	assertNilF(t, json.Unmarshal(b, &restored))
+	assertEqualE(t, restored.QueryID, q.QueryID)
+	assertTrueE(t, restored.SubmittedAt.Equal(q.SubmittedAt))
+
+	statuses = []retStatus{{Status: "RUNNING", StartTime: 1000}}
+	status, err := restored.Poll(ctx, conn)
+	assertNilF(t, err)
+	assertEqualE(t, status.State, "RUNNING")
+	assertFalseE(t, status.Done)
+
+	statuses = []retStatus{{Status: "QUEUED"}, {Status: "SUCCESS", StartTime: 1000, EndTime: 5000}}
+	status, err = restored.Wait(ctx, conn)
+	assertNilF(t, err)
+	assertTrueE(t, status.Done)
+	assertEqualE(t, status.EndTime.Sub(status.StartTime), 4*time.Second)
+
+	rows, err := restored.Results(ctx, conn)
+	assertNilF(t, err)
+	var v int
+	assertTrueF(t, rows.Next())
+	assertNilF(t, rows.Scan(&v))
+	assertEqualE(t, v, 42)
+	assertNilE(t, rows.Close())
+
+	statuses = []retStatus{{Status: "FAILED_WITH_ERROR", ErrorCode: "100038", ErrorMessage: "Numeric value 'a' is not recognized"}}
+	status, err = restored.Wait(ctx, conn)
+	var se *SnowflakeError
+	assertTrueF(t, errors.As(err, &se))
+	assertEqualE(t, se.Number, 100038)
+	assertEqualE(t, se.QueryID, "async-1")
+	assertTrueE(t, status.Done)
+
+	statuses = []retStatus{{Status: "RUNNING"}}
+	waitCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	_, err = restored.Wait(waitCtx, conn)
+	assertErrIsE(t, err, context.DeadlineExceeded)
+
+	statuses = []retStatus{{Status: "RUNNING"}}
+	result, err := restored.Cancel(ctx, conn)
+	assertNilF(t, err)
+	assertEqualE(t, result, QueryCancelled)
+	last := requests[len(requests)-1]
+	assertEqualE(t, last.SQLText, "SELECT SYSTEM$CANCEL_QUERY(?)")
+	assertEqualE(t, last.Bindings["1"].Value, "async-1")
+}
@@ -272,7 +272,7 @@ func postAuth(
 
 	fullURL := sr.getFullURL(loginRequestPath, params)
 	logger.WithContext(ctx).Infof("full URL: %v", fullURL)
-	resp, err := sr.FuncAuthPost(ctx, client, fullURL, headers, bodyCreator, timeout, sr.MaxRetryCount)
+	resp, err := sr.FuncAuthPost(withFallbackRetryPolicy(ctx, sr.RetryPolicy), client, fullURL, headers, bodyCreator, timeout, sr.MaxRetryCount)
 	if err != nil {
 		return nil, err
 	}
@@ -342,6 +342,14 @@ func authenticate(
 	samlResponse []byte,
 	proofKey []byte,
 ) (resp *authResponseMain, err error) {
+	ctx, op := sc.instrumentation().startOperation(ctx, spanNameAuthenticate, "login",
+		attrAuthType.String(sc.cfg.Authenticator.String()))
+	defer func() {
+		if resp != nil {
+			op.setAttributes(attrSessionID.Int64(resp.SessionID))
+		}
+		op.end(ctx, err)
+	}()
 	if sc.cfg.Authenticator == AuthTypeTokenAccessor {
 		logger.WithContext(ctx).Info("Bypass authentication using existing token from token accessor")
 		sessionInfo := authResponseSessionInfo{
@@ -849,6 +857,8 @@ func authenticateWithConfig(sc *snowflakeConn) error {
 		valueAwaiter.done()
 	}
 	sc.populateSessionParameters(authData.Parameters)
+	sc.setInitialSessionState(authData.SessionInfo)
+	sc.rest.session.setMasterTokenValidity(authData.MasterValidity)
 	sc.ctx = context.WithValue(sc.ctx, SFSessionIDKey, authData.SessionID)
 	return nil
 }
@@ -90,7 +90,7 @@ func (util *snowflakeAzureClient) getFileHeader(meta *fileMetadata, filename str
 	if meta.mockAzureClient != nil {
 		blobClient = meta.mockAzureClient
 	}
-	resp, err := withCloudStorageTimeout(util.cfg, func(ctx context.Context) (blob.GetPropertiesResponse, error) {
+	resp, err := withCloudStorageTimeout(meta.transferContext(), util.cfg, func(ctx context.Context) (blob.GetPropertiesResponse, error) {
 		return blobClient.GetProperties(ctx, &blob.GetPropertiesOptions{
 			AccessConditions: &blob.AccessConditions{},
 			CPKInfo:          &blob.CPKInfo{},
@@ -205,10 +205,20 @@ func (util *snowflakeAzureClient) uploadFile(
 	if meta.mockAzureClient != nil {
 		blobClient = meta.mockAzureClient
 	}
-	if meta.srcStream != nil {
+	if meta.uploadStream != nil {
+		// azureMeta points at meta.sha256Digest, which is set when the stream
+		// ends and is sent with the block list committed after it.
+		_, err = withCloudStorageTimeout(meta.transferContext(), util.cfg, func(ctx context.Context) (azblob.UploadStreamResponse, error) {
+			return blobClient.UploadStream(ctx, meta.progressReader(meta.uploadStream), &azblob.UploadStreamOptions{
+				BlockSize:   int64Max(multiPartThreshold, defaultStreamUploadPartSize),
+				Concurrency: maxConcurrency,
+				Metadata:    azureMeta,
+			})
+		})
+	} else if meta.srcStream != nil {
 		uploadSrc := cmp.Or(meta.realSrcStream, meta.srcStream)
-		_, err = withCloudStorageTimeout(util.cfg, func(ctx context.Context) (azblob.UploadStreamResponse, error) {
-			return blobClient.UploadStream(ctx, uploadSrc, &azblob.UploadStreamOptions{
+		_, err = withCloudStorageTimeout(meta.transferContext(), util.cfg, func(ctx context.Context) (azblob.UploadStreamResponse, error) {
+			return blobClient.UploadStream(ctx, meta.progressReader(uploadSrc), &azblob.UploadStreamOptions{
 				BlockSize: int64(uploadSrc.Len()),
 				Metadata:  azureMeta,
 			})
@@ -238,7 +248,16 @@ func (util *snowflakeAzureClient) uploadFile(
 		if meta.options.putAzureCallback != nil {
 			blobOptions.Progress = meta.options.putAzureCallback.call
 		}
-		_, err = withCloudStorageTimeout(util.cfg, func(ctx context.Context) (azblob.UploadFileResponse, error) {
+		if meta.progressListener() != nil {
+			putAzureCallback := blobOptions.Progress
+			blobOptions.Progress = func(bytesTransferred int64) {
+				if putAzureCallback != nil {
+					putAzureCallback(bytesTransferred)
+				}
+				meta.reportTotalBytes(bytesTransferred)
+			}
+		}
+		_, err = withCloudStorageTimeout(meta.transferContext(), util.cfg, func(ctx context.Context) (azblob.UploadFileResponse, error) {
 			return blobClient.UploadFile(ctx, f, blobOptions)
 		})
 	}
@@ -293,19 +312,19 @@ func (util *snowflakeAzureClient) nativeDownloadFile(
 		blobClient = meta.mockAzureClient
 	}
 	if meta.options != nil && meta.options.GetFileToStream {
-		blobDownloadResponse, err := withCloudStorageTimeout(util.cfg, func(ctx context.Context) (azblob.DownloadStreamResponse, error) {
+		blobDownloadResponse, err := withCloudStorageTimeout(meta.transferContext(), util.cfg, func(ctx context.Context) (azblob.DownloadStreamResponse, error) {
 			return blobClient.DownloadStream(ctx, &azblob.DownloadStreamOptions{})
 		})
 		if err != nil {
 			return err
 		}
-		retryReader := blobDownloadResponse.NewRetryReader(context.Background(), &azblob.RetryReaderOptions{})
+		retryReader := blobDownloadResponse.NewRetryReader(meta.transferContext(), &azblob.RetryReaderOptions{})
 		defer func() {
 			if err = retryReader.Close(); err != nil {
 				logger.Warnf("failed to close the Azure reader: %v", err)
 			}
 		}()
-		_, err = meta.dstStream.ReadFrom(retryReader)
+		_, err = meta.dstStream.ReadFrom(meta.progressReader(retryReader))
 		if err != nil {
 			return err
 		}
@@ -319,11 +338,12 @@ func (util *snowflakeAzureClient) nativeDownloadFile(
 				logger.Warnf("failed to close the %v file: %v", fullDstFileName, err)
 			}
 		}()
-		_, err = withCloudStorageTimeout(util.cfg, func(ctx context.Context) (any, error) {
+		_, err = withCloudStorageTimeout(meta.transferContext(), util.cfg, func(ctx context.Context) (any, error) {
 			return blobClient.DownloadFile(
 				ctx, f, &azblob.DownloadFileOptions{
 					Concurrency: uint16(maxConcurrency),
 					BlockSize:   int64Max(partSize, blob.DefaultDownloadBlockSize),
+					Progress:    meta.reportTotalBytes,
 				})
 		})
 		if err != nil {
@@ -5,11 +5,15 @@ import (
 	"context"
 	"database/sql"
 	"database/sql/driver"
+	"encoding/hex"
 	"fmt"
 	"math/big"
 	"reflect"
 	"strconv"
 	"strings"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
 )
 
 const (
@@ -72,6 +76,54 @@ func (bu *bindUploader) upload(bindings []driver.NamedValue) (*execResponse, err
 	return data, nil
 }
 
+// uploadArrow writes the rows of an arrow.Record or array.RecordReader as CSV directly from the arrow arrays
+// and uploads them to the stage.
+func (bu *bindUploader) uploadArrow(value any) error {
+	reader, err := arrowBindRecordReader(value)
+	if err != nil {
+		return err
+	}
+	defer reader.Release()
+	bu.fileCount = 0
+	var b bytes.Buffer
+	for reader.Next() {
+		record := reader.Record()
+		for row := 0; row < int(record.NumRows()); row++ {
+			line, err := appendArrowBindRow(b.AvailableBuffer(), record, row)
+			if err != nil {
+				return (&SnowflakeError{
+					Number:  ErrBindSerialization,
+					Message: err.Error(),
+				}).exceptionTelemetry(bu.sc)
+			}
+			b.Write(line)
+			if b.Len() >= inputStreamBufferSize {
+				bu.fileCount++
+				if _, err = bu.uploadStreamInternal(&b, bu.fileCount, true); err != nil {
+					return err
+				}
+				b = bytes.Buffer{}
+			}
+		}
+	}
+	if err = reader.Err(); err != nil {
+		return err
+	}
+	if b.Len() == 0 && bu.fileCount == 0 {
+		return (&SnowflakeError{
+			Number:  ErrBindSerialization,
+			Message: "no rows found in the arrow binding",
+		}).exceptionTelemetry(bu.sc)
+	}
+	if b.Len() > 0 {
+		bu.fileCount++
+		if _, err = bu.uploadStreamInternal(&b, bu.fileCount, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (bu *bindUploader) uploadStreamInternal(
 	inputStream *bytes.Buffer,
 	dstFileName int,
@@ -205,6 +257,19 @@ func (sc *snowflakeConn) processBindings(
 	describeOnly bool,
 	requestID UUID,
 	req *execRequest) error {
+	if isArrowBind(bindings) && !describeOnly {
+		uploader := bindUploader{
+			sc:        sc,
+			ctx:       ctx,
+			stagePath: "@" + bindStageName + "/" + requestID.String(),
+		}
+		if err := uploader.uploadArrow(bindings[0].Value); err != nil {
+			return err
+		}
+		req.Bindings = nil
+		req.BindStage = uploader.stagePath
+		return nil
+	}
 	arrayBindThreshold := sc.getArrayBindStageThreshold()
 	numBinds, err := arrayBindValueCount(bindings)
 	if err != nil {
@@ -387,3 +452,110 @@ func supportedStructuredMapBind(nv *driver.NamedValue) bool {
 	typ := reflect.TypeOf(nv.Value)
 	return typ != nil && (typ.Kind() == reflect.Map || typ == reflect.TypeOf(NilMapTypes{}))
 }
+
+func supportedArrowBind(nv *driver.NamedValue) bool {
+	switch nv.Value.(type) {
+	case arrow.Record, array.RecordReader:
+		return true
+	}
+	return false
+}
+
+// isArrowBind reports whether the only binding is an arrow.Record or array.RecordReader holding all rows.
+func isArrowBind(bindings []driver.NamedValue) bool {
+	return len(bindings) == 1 && supportedArrowBind(&bindings[0])
+}
+
+func arrowBindRecordReader(value any) (array.RecordReader, error) {
+	switch v := value.(type) {
+	case arrow.Record:
+		return array.NewRecordReader(v.Schema(), []arrow.Record{v})
+	case array.RecordReader:
+		v.Retain()
+		return v, nil
+	}
+	return nil, fmt.Errorf("unsupported arrow binding: %T", value)
+}
+
+// appendArrowBindRow appends a CSV line with the values of the row, in the same format as array binds uploaded to the stage.
+func appendArrowBindRow(buf []byte, record arrow.Record, row int) ([]byte, error) {
+	var err error
+	for col := 0; col < int(record.NumCols()); col++ {
+		if col > 0 {
+			buf = append(buf, ',')
+		}
+		if buf, err = appendArrowBindValue(buf, record.Column(col), row); err != nil {
+			return nil, fmt.Errorf("column %v: %w", record.ColumnName(col), err)
+		}
+	}
+	return append(buf, '\n'), nil
+}
+
+func appendArrowBindValue(buf []byte, arr arrow.Array, i int) ([]byte, error) {
+	if arr.IsNull(i) {
+		return buf, nil
+	}
+	switch a := arr.(type) {
+	case *array.Boolean:
+		return strconv.AppendBool(buf, a.Value(i)), nil
+	case *array.Int8:
+		return strconv.AppendInt(buf, int64(a.Value(i)), 10), nil
+	case *array.Int16:
+		return strconv.AppendInt(buf, int64(a.Value(i)), 10), nil
+	case *array.Int32:
+		return strconv.AppendInt(buf, int64(a.Value(i)), 10), nil
+	case *array.Int64:
+		return strconv.AppendInt(buf, a.Value(i), 10), nil
+	case *array.Uint8:
+		return strconv.AppendUint(buf, uint64(a.Value(i)), 10), nil
+	case *array.Uint16:
+		return strconv.AppendUint(buf, uint64(a.Value(i)), 10), nil
+	case *array.Uint32:
+		return strconv.AppendUint(buf, uint64(a.Value(i)), 10), nil
+	case *array.Uint64:
+		return strconv.AppendUint(buf, a.Value(i), 10), nil
+	case *array.Float32:
+		return strconv.AppendFloat(buf, float64(a.Value(i)), 'g', -1, 32), nil
+	case *array.Float64:
+		return strconv.AppendFloat(buf, a.Value(i), 'g', -1, 64), nil
+	case *array.Decimal128:
+		return append(buf, a.Value(i).ToString(a.DataType().(*arrow.Decimal128Type).Scale)...), nil
+	case *array.Decimal256:
+		return append(buf, a.Value(i).ToString(a.DataType().(*arrow.Decimal256Type).Scale)...), nil
+	case *array.String:
+		return append(buf, escapeForCSV(a.Value(i))...), nil
+	case *array.LargeString:
+		return append(buf, escapeForCSV(a.Value(i))...), nil
+	case *array.StringView:
+		return append(buf, escapeForCSV(a.Value(i))...), nil
+	case *array.Binary:
+		return hex.AppendEncode(buf, a.Value(i)), nil
+	case *array.LargeBinary:
+		return hex.AppendEncode(buf, a.Value(i)), nil
+	case *array.FixedSizeBinary:
+		return hex.AppendEncode(buf, a.Value(i)), nil
+	case *array.Date32:
+		return a.Value(i).ToTime().AppendFormat(buf, "2006-01-02"), nil
+	case *array.Date64:
+		return a.Value(i).ToTime().AppendFormat(buf, "2006-01-02"), nil
+	case *array.Time32:
+		unit := a.DataType().(*arrow.Time32Type).Unit
+		return a.Value(i).ToTime(unit).AppendFormat(buf, "15:04:05.000000000"), nil
+	case *array.Time64:
+		unit := a.DataType().(*arrow.Time64Type).Unit
+		return a.Value(i).ToTime(unit).AppendFormat(buf, "15:04:05.000000000"), nil
+	case *array.Timestamp:
+		tsType := a.DataType().(*arrow.TimestampType)
+		toTime, err := tsType.GetToTimeFunc()
+		if err != nil {
+			return nil, err
+		}
+		if tsType.TimeZone != "" {
+			// zone-aware values must keep their offset, otherwise TIMESTAMP_TZ and
+			// TIMESTAMP_LTZ columns would read them in the session time zone
+			return toTime(a.Value(i)).AppendFormat(buf, formatWithOffset), nil
+		}
+		return toTime(a.Value(i)).AppendFormat(buf, format), nil
+	}
+	return nil, fmt.Errorf("unsupported arrow type: %v", arr.DataType())
+}
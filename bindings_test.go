@@ -16,6 +16,11 @@ import (
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/decimal128"
+	"github.com/apache/arrow-go/v18/arrow/memory"
 )
 
 const (
@@ -1590,6 +1595,114 @@ func testInsertLOBData(t *testing.T, useArrowFormat bool, isLiteral bool) {
 	})
 }
 
+func newArrowBindTestRecord(pool memory.Allocator) arrow.Record {
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "C1", Type: arrow.PrimitiveTypes.Int64, Nullable: true},
+		{Name: "C2", Type: arrow.PrimitiveTypes.Float64, Nullable: true},
+		{Name: "C3", Type: arrow.FixedWidthTypes.Boolean, Nullable: true},
+		{Name: "C4", Type: arrow.BinaryTypes.String, Nullable: true},
+		{Name: "C5", Type: arrow.BinaryTypes.Binary, Nullable: true},
+		{Name: "C6", Type: &arrow.TimestampType{Unit: arrow.Microsecond}, Nullable: true},
+		{Name: "C7", Type: arrow.FixedWidthTypes.Date32, Nullable: true},
+		{Name: "C8", Type: &arrow.Decimal128Type{Precision: 10, Scale: 2}, Nullable: true},
+	}, nil)
+	builder := array.NewRecordBuilder(pool, schema)
+	defer builder.Release()
+	ts := time.Date(2024, 3, 1, 10, 20, 30, 123456000, time.UTC)
+	builder.Field(0).(*array.Int64Builder).AppendValues([]int64{1, 2}, nil)
+	builder.Field(1).(*array.Float64Builder).AppendValues([]float64{0.1, 0}, []bool{true, false})
+	builder.Field(2).(*array.BooleanBuilder).AppendValues([]bool{true, false}, nil)
+	builder.Field(3).(*array.StringBuilder).AppendValues([]string{"a,b", ""}, nil)
+	builder.Field(4).(*array.BinaryBuilder).AppendValues([][]byte{{0xca, 0xfe}, nil}, []bool{true, false})
+	builder.Field(5).(*array.TimestampBuilder).AppendValues([]arrow.Timestamp{arrow.Timestamp(ts.UnixMicro()), 0}, []bool{true, false})
+	builder.Field(6).(*array.Date32Builder).AppendValues([]arrow.Date32{arrow.Date32FromTime(ts), 0}, []bool{true, false})
+	builder.Field(7).(*array.Decimal128Builder).AppendValues([]decimal128.Num{decimal128.FromI64(12345), decimal128.FromI64(-1)}, nil)
+	return builder.NewRecord()
+}
+
+func TestAppendArrowBindRow(t *testing.T) {
+	record := newArrowBindTestRecord(memory.DefaultAllocator)
+	defer record.Release()
+	var lines []string
+	for row := 0; row < int(record.NumRows()); row++ {
+		line, err := appendArrowBindRow(nil, record, row)
+		assertNilF(t, err)
+		lines = append(lines, string(line))
+	}
+	assertDeepEqualE(t, lines, []string{
+		"1,0.1,true,\"a,b\",cafe,2024-03-01 10:20:30.123456,2024-03-01,123.45\n",
+		"2,,false,\"\",,,,-0.01\n",
+	})
+
+	assertTrueE(t, isArrowBind([]driver.NamedValue{{Ordinal: 1, Value: record}}))
+	assertFalseE(t, isArrowBind([]driver.NamedValue{{Ordinal: 1, Value: record}, {Ordinal: 2, Value: 1}}))
+	assertNilE(t, (&snowflakeConn{}).CheckNamedValue(&driver.NamedValue{Value: record}))
+}
+
+func TestAppendArrowBindRowZonedTimestamp(t *testing.T) {
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "C1", Type: &arrow.TimestampType{Unit: arrow.Microsecond, TimeZone: "America/New_York"}, Nullable: true},
+		{Name: "C2", Type: &arrow.TimestampType{Unit: arrow.Second, TimeZone: "UTC"}, Nullable: true},
+	}, nil)
+	builder := array.NewRecordBuilder(memory.DefaultAllocator, schema)
+	defer builder.Release()
+	ts := time.Date(2024, 3, 1, 10, 20, 30, 123456000, time.UTC)
+	builder.Field(0).(*array.TimestampBuilder).Append(arrow.Timestamp(ts.UnixMicro()))
+	builder.Field(1).(*array.TimestampBuilder).Append(arrow.Timestamp(ts.Unix()))
+	record := builder.NewRecord()
+	defer record.Release()
+
+	line, err := appendArrowBindRow(nil, record, 0)
+	assertNilF(t, err)
+	assertEqualE(t, string(line), "2024-03-01 05:20:30.123456 -05:00,2024-03-01 10:20:30 +00:00\n")
+}
+
+func TestBindingArrowRecord(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.DefaultAllocator)
+	defer pool.AssertSize(t, 0)
+	record := newArrowBindTestRecord(pool)
+	defer record.Release()
+
+	runDBTest(t, func(dbt *DBTest) {
+		dbt.mustExec(`create or replace table test_arrow_bind(c1 INTEGER, c2 FLOAT, c3 BOOLEAN, c4 STRING,
+			c5 BINARY, c6 TIMESTAMP_NTZ, c7 DATE, c8 NUMBER(10, 2))`)
+		defer dbt.mustExec("drop table if exists test_arrow_bind")
+
+		reader, err := array.NewRecordReader(record.Schema(), []arrow.Record{record, record})
+		assertNilF(t, err)
+		defer reader.Release()
+		dbt.mustExec("insert into test_arrow_bind values (?, ?, ?, ?, ?, ?, ?, ?)", reader)
+
+		rows := dbt.mustQuery("select c1, c2, c4, c5, c6, c8 from test_arrow_bind order by c1, c2")
+		defer func() {
+			assertNilF(t, rows.Close())
+		}()
+		var count int
+		for rows.Next() {
+			var c1 int64
+			var c2 sql.NullFloat64
+			var c4 string
+			var c5 []byte
+			var c6 sql.NullTime
+			var c8 string
+			assertNilF(t, rows.Scan(&c1, &c2, &c4, &c5, &c6, &c8))
+			if c1 == 1 {
+				assertEqualE(t, c2.Float64, 0.1)
+				assertEqualE(t, c4, "a,b")
+				assertBytesEqualE(t, c5, []byte{0xca, 0xfe})
+				assertEqualE(t, c6.Time.UnixMicro(), time.Date(2024, 3, 1, 10, 20, 30, 123456000, time.UTC).UnixMicro())
+				assertEqualE(t, c8, "123.45")
+			} else {
+				assertFalseE(t, c2.Valid)
+				assertEqualE(t, c4, "")
+				assertFalseE(t, c6.Valid)
+			}
+			count++
+		}
+		assertEqualE(t, count, 4)
+	})
+}
+
 func fastStringGeneration(size int) string {
 	if size <= 0 {
 		return ""
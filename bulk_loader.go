@@ -0,0 +1,576 @@
+package gosnowflake
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/apache/arrow-go/v18/parquet"
+	"github.com/apache/arrow-go/v18/parquet/compress"
+	"github.com/apache/arrow-go/v18/parquet/pqarrow"
+)
+
+const (
+	bulkLoadStageName            = "SYSTEM$BULK_LOAD"
+	createBulkLoadStageStmt      = "CREATE TEMPORARY STAGE IF NOT EXISTS " + bulkLoadStageName
+	defaultBulkLoadOnError       = "ABORT_STATEMENT"
+	bulkLoadCSVFileFormat        = "FILE_FORMAT=(TYPE=CSV FIELD_OPTIONALLY_ENCLOSED_BY='\"' BINARY_FORMAT=HEX)"
+	bulkLoadParquetFileFormat    = "FILE_FORMAT=(TYPE=PARQUET BINARY_AS_TEXT=FALSE) MATCH_BY_COLUMN_NAME=CASE_INSENSITIVE"
+	bulkLoadParquetTimestampUnit = arrow.Microsecond
+)
+
+// BulkLoadFormat is the format of the files a BulkLoader stages.
+type BulkLoadFormat int
+
+const (
+	// BulkLoadFormatCSV stages gzip compressed CSV files.
+	BulkLoadFormatCSV BulkLoadFormat = iota
+	// BulkLoadFormatParquet stages snappy compressed Parquet files. Column names are required.
+	BulkLoadFormatParquet
+)
+
+func (f BulkLoadFormat) String() string {
+	switch f {
+	case BulkLoadFormatCSV:
+		return "csv"
+	case BulkLoadFormatParquet:
+		return "parquet"
+	default:
+		return "unknown"
+	}
+}
+
+// BulkLoaderOptions configures a BulkLoader.
+type BulkLoaderOptions struct {
+	// Columns are the target columns, in the order of the values in each row.
+	// If empty, they are taken from the fields of struct rows, or all table columns are loaded for CSV.
+	Columns []string
+	// Format is the format of the staged files. Default is BulkLoadFormatCSV.
+	Format BulkLoadFormat
+	// FileSize is the approximate uncompressed size in bytes after which a file is uploaded. Default is 10MB.
+	FileSize int
+	// OnError is the ON_ERROR option of COPY INTO, e.g. CONTINUE or SKIP_FILE. Default is ABORT_STATEMENT.
+	OnError string
+}
+
+// BulkLoadFileResult is the result of loading a single staged file.
+type BulkLoadFileResult struct {
+	File                string
+	Status              string
+	RowsParsed          int64
+	RowsLoaded          int64
+	ErrorsSeen          int64
+	FirstError          string
+	FirstErrorLine      int64
+	FirstErrorCharacter int64
+	FirstErrorColumn    string
+}
+
+// BulkLoader loads rows into a table. Appended rows are written to files in memory, which are uploaded
+// to a temporary stage once they reach the configured size. Flush uploads the remaining rows and runs
+// COPY INTO for all staged files. A BulkLoader is bound to the connection which created it and
+// is not safe for concurrent use.
+type BulkLoader struct {
+	ctx        context.Context
+	sc         *snowflakeConn
+	table      string
+	options    BulkLoaderOptions
+	stagePath  string
+	columns    []string
+	numColumns int
+
+	csv          bytes.Buffer
+	rows         [][]any
+	pendingBytes int
+	fileCount    int
+	stagedFiles  int
+	stageCreated bool
+}
+
+// BulkLoadConnection is implemented by the connections of the driver, which can be reached with sql.Conn.Raw.
+type BulkLoadConnection interface {
+	NewBulkLoader(ctx context.Context, table string, options *BulkLoaderOptions) (*BulkLoader, error)
+}
+
+// NewBulkLoader creates a BulkLoader for the table. The context is used for all uploads and COPY INTO statements.
+func (sc *snowflakeConn) NewBulkLoader(ctx context.Context, table string, options *BulkLoaderOptions) (*BulkLoader, error) {
+	if options == nil {
+		options = &BulkLoaderOptions{}
+	}
+	if table == "" {
+		return nil, errBulkLoad("table name is empty")
+	}
+	if options.Format != BulkLoadFormatCSV && options.Format != BulkLoadFormatParquet {
+		return nil, errBulkLoad(fmt.Sprintf("unsupported format: %v", options.Format))
+	}
+	opts := *options
+	if opts.FileSize <= 0 {
+		opts.FileSize = inputStreamBufferSize
+	}
+	if opts.OnError == "" {
+		opts.OnError = defaultBulkLoadOnError
+	}
+	return &BulkLoader{
+		ctx:        ctx,
+		sc:         sc,
+		table:      table,
+		options:    opts,
+		stagePath:  "@" + bulkLoadStageName + "/" + NewUUID().String(),
+		columns:    opts.Columns,
+		numColumns: len(opts.Columns),
+	}, nil
+}
+
+// Append adds a row. A row is either a []any with one value per column, or a struct or a pointer to
+// a struct whose exported fields are the columns. Field names can be changed with the `sf` tag and
+// fields tagged with `sf:",ignore"` are skipped.
+func (bl *BulkLoader) Append(row any) error {
+	values, names, err := bulkLoadRowValues(row)
+	if err != nil {
+		return err
+	}
+	if bl.numColumns == 0 {
+		bl.numColumns = len(values)
+		if len(bl.columns) == 0 {
+			bl.columns = names
+		}
+	}
+	if len(values) != bl.numColumns {
+		return errBulkLoad(fmt.Sprintf("row has %v values, expected %v", len(values), bl.numColumns))
+	}
+	if bl.options.Format == BulkLoadFormatParquet && len(bl.columns) == 0 {
+		return errBulkLoad("column names are required for the parquet format")
+	}
+
+	switch bl.options.Format {
+	case BulkLoadFormatParquet:
+		for _, v := range values {
+			bl.pendingBytes += bulkLoadValueSize(v)
+		}
+		bl.rows = append(bl.rows, values)
+	default:
+		record, err := bulkLoadCSVRecord(values)
+		if err != nil {
+			return err
+		}
+		bl.csv.Write(record)
+		bl.pendingBytes += len(record)
+	}
+	if bl.pendingBytes >= bl.options.FileSize {
+		return bl.uploadPending()
+	}
+	return nil
+}
+
+// Flush uploads the remaining rows, runs COPY INTO for all staged files and returns the result per file.
+// Loaded files are removed from the stage.
+func (bl *BulkLoader) Flush() ([]BulkLoadFileResult, error) {
+	if err := bl.uploadPending(); err != nil {
+		return nil, err
+	}
+	if bl.stagedFiles == 0 {
+		return nil, nil
+	}
+	rows, err := bl.sc.QueryContext(bl.ctx, bl.copyStatement(), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logger.WithContext(bl.ctx).Warnf("failed to close COPY INTO result: %v", err)
+		}
+	}()
+	results, err := readBulkLoadResults(rows)
+	if err != nil {
+		return nil, err
+	}
+	bl.stagedFiles = 0
+	return results, nil
+}
+
+func (bl *BulkLoader) uploadPending() error {
+	if bl.pendingBytes == 0 {
+		return nil
+	}
+	var data *bytes.Buffer
+	var fileName string
+	compressData := false
+	bl.fileCount++
+	switch bl.options.Format {
+	case BulkLoadFormatParquet:
+		data = new(bytes.Buffer)
+		if err := writeBulkLoadParquet(data, getAllocator(bl.ctx), bl.columns, bl.rows); err != nil {
+			return err
+		}
+		fileName = fmt.Sprintf("%v.parquet", bl.fileCount)
+	default:
+		data = &bl.csv
+		fileName = fmt.Sprintf("%v.csv", bl.fileCount)
+		compressData = true
+	}
+	if err := bl.createStageIfNeeded(); err != nil {
+		return err
+	}
+
+	// use a placeholder for source file
+	putCommand := fmt.Sprintf("put 'file:///tmp/placeholder/%v' '%v' overwrite=true", fileName, bl.stagePath)
+	putCommand = strings.ReplaceAll(putCommand, "\\", "\\\\")
+	ctx := WithFileStream(bl.ctx, data)
+	ctx = WithFileTransferOptions(ctx, &SnowflakeFileTransferOptions{
+		RaisePutGetError:         true,
+		compressSourceFromStream: compressData})
+	if _, err := bl.sc.exec(ctx, putCommand, false, true, false, []driver.NamedValue{}); err != nil {
+		return err
+	}
+	bl.stagedFiles++
+	bl.csv.Reset()
+	bl.rows = nil
+	bl.pendingBytes = 0
+	return nil
+}
+
+func (bl *BulkLoader) createStageIfNeeded() error {
+	if bl.stageCreated {
+		return nil
+	}
+	if _, err := bl.sc.exec(bl.ctx, createBulkLoadStageStmt, false, false, false, []driver.NamedValue{}); err != nil {
+		return err
+	}
+	bl.stageCreated = true
+	return nil
+}
+
+func (bl *BulkLoader) copyStatement() string {
+	var b strings.Builder
+	b.WriteString("COPY INTO ")
+	b.WriteString(bl.table)
+	if bl.options.Format == BulkLoadFormatCSV && len(bl.columns) > 0 {
+		b.WriteString(" (")
+		b.WriteString(strings.Join(bl.columns, ", "))
+		b.WriteString(")")
+	}
+	b.WriteString(" FROM '")
+	b.WriteString(bl.stagePath)
+	b.WriteString("/' ")
+	if bl.options.Format == BulkLoadFormatParquet {
+		b.WriteString(bulkLoadParquetFileFormat)
+	} else {
+		b.WriteString(bulkLoadCSVFileFormat)
+	}
+	b.WriteString(" ON_ERROR=")
+	b.WriteString(bl.options.OnError)
+	b.WriteString(" PURGE=TRUE")
+	return b.String()
+}
+
+// bulkLoadRowValues returns the values of the row and, for struct rows, the column names.
+func bulkLoadRowValues(row any) ([]any, []string, error) {
+	if values, ok := row.([]any); ok {
+		return values, nil, nil
+	}
+	v := reflect.ValueOf(row)
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil, nil, errBulkLoad("row is nil")
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil, nil, errBulkLoad(fmt.Sprintf("unsupported row type: %T", row))
+	}
+	var values []any
+	var names []string
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if !field.IsExported() || shouldIgnoreField(field) {
+			continue
+		}
+		values = append(values, v.Field(i).Interface())
+		names = append(names, getSfFieldName(field))
+	}
+	return values, names, nil
+}
+
+func bulkLoadCSVRecord(values []any) ([]byte, error) {
+	var b strings.Builder
+	for i, v := range values {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		s, err := bulkLoadValueToString(v)
+		if err != nil {
+			return nil, err
+		}
+		if s != nil {
+			b.WriteString(escapeForCSV(*s))
+		}
+	}
+	b.WriteString("\n")
+	return []byte(b.String()), nil
+}
+
+// bulkLoadValueToString converts the value to its CSV representation. A nil result is loaded as NULL.
+func bulkLoadValueToString(v any) (*string, error) {
+	v, err := bulkLoadValue(v)
+	if err != nil || v == nil {
+		return nil, err
+	}
+	var s string
+	switch val := v.(type) {
+	case string:
+		s = val
+	case []byte:
+		s = hex.EncodeToString(val)
+	case bool:
+		s = strconv.FormatBool(val)
+	case int64:
+		s = strconv.FormatInt(val, 10)
+	case uint64:
+		s = strconv.FormatUint(val, 10)
+	case float64:
+		s = strconv.FormatFloat(val, 'g', -1, 64)
+	case time.Time:
+		s = val.Format(formatWithOffset)
+	default:
+		b, err := json.Marshal(val)
+		if err != nil {
+			return nil, errBulkLoad(fmt.Sprintf("failed to convert %T: %v", v, err))
+		}
+		s = string(b)
+	}
+	return &s, nil
+}
+
+// bulkLoadValue dereferences pointers, resolves driver.Valuer and widens numeric types.
+func bulkLoadValue(v any) (any, error) {
+	if valuer, ok := v.(driver.Valuer); ok {
+		rv := reflect.ValueOf(v)
+		if rv.Kind() == reflect.Pointer && rv.IsNil() {
+			return nil, nil
+		}
+		value, err := valuer.Value()
+		if err != nil {
+			return nil, err
+		}
+		v = value
+	}
+	if v == nil {
+		return nil, nil
+	}
+	switch val := v.(type) {
+	case string, []byte, bool, time.Time:
+		return val, nil
+	}
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil, nil
+		}
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Bool:
+		return rv.Bool(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return rv.Uint(), nil
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), nil
+	case reflect.String:
+		return rv.String(), nil
+	}
+	return rv.Interface(), nil
+}
+
+func bulkLoadValueSize(v any) int {
+	switch val := v.(type) {
+	case nil:
+		return 1
+	case string:
+		return len(val)
+	case []byte:
+		return len(val)
+	default:
+		return 8
+	}
+}
+
+func writeBulkLoadParquet(w io.Writer, pool memory.Allocator, columns []string, rows [][]any) error {
+	fields := make([]arrow.Field, len(columns))
+	for i, name := range columns {
+		typ, err := bulkLoadArrowType(rows, i)
+		if err != nil {
+			return err
+		}
+		fields[i] = arrow.Field{Name: name, Type: typ, Nullable: true}
+	}
+	schema := arrow.NewSchema(fields, nil)
+	builder := array.NewRecordBuilder(pool, schema)
+	defer builder.Release()
+	for _, row := range rows {
+		for i, v := range row {
+			if err := appendBulkLoadArrowValue(builder.Field(i), v); err != nil {
+				return err
+			}
+		}
+	}
+	record := builder.NewRecord()
+	defer record.Release()
+
+	props := parquet.NewWriterProperties(parquet.WithCompression(compress.Codecs.Snappy))
+	writer, err := pqarrow.NewFileWriter(schema, w, props, pqarrow.DefaultWriterProps())
+	if err != nil {
+		return err
+	}
+	if err = writer.Write(record); err != nil {
+		return err
+	}
+	return writer.Close()
+}
+
+// bulkLoadArrowType returns the arrow type of the column, based on its first non-null value.
+func bulkLoadArrowType(rows [][]any, col int) (arrow.DataType, error) {
+	for _, row := range rows {
+		v, err := bulkLoadValue(row[col])
+		if err != nil {
+			return nil, err
+		}
+		switch v.(type) {
+		case nil:
+			continue
+		case bool:
+			return arrow.FixedWidthTypes.Boolean, nil
+		case int64:
+			return arrow.PrimitiveTypes.Int64, nil
+		case uint64:
+			return arrow.PrimitiveTypes.Uint64, nil
+		case float64:
+			return arrow.PrimitiveTypes.Float64, nil
+		case []byte:
+			return arrow.BinaryTypes.Binary, nil
+		case time.Time:
+			return &arrow.TimestampType{Unit: bulkLoadParquetTimestampUnit, TimeZone: "UTC"}, nil
+		default:
+			return arrow.BinaryTypes.String, nil
+		}
+	}
+	return arrow.BinaryTypes.String, nil
+}
+
+func appendBulkLoadArrowValue(b array.Builder, v any) error {
+	v, err := bulkLoadValue(v)
+	if err != nil {
+		return err
+	}
+	if v == nil {
+		b.AppendNull()
+		return nil
+	}
+	switch builder := b.(type) {
+	case *array.BooleanBuilder:
+		if val, ok := v.(bool); ok {
+			builder.Append(val)
+			return nil
+		}
+	case *array.Int64Builder:
+		if val, ok := v.(int64); ok {
+			builder.Append(val)
+			return nil
+		}
+	case *array.Uint64Builder:
+		if val, ok := v.(uint64); ok {
+			builder.Append(val)
+			return nil
+		}
+	case *array.Float64Builder:
+		if val, ok := v.(float64); ok {
+			builder.Append(val)
+			return nil
+		}
+	case *array.BinaryBuilder:
+		if val, ok := v.([]byte); ok {
+			builder.Append(val)
+			return nil
+		}
+	case *array.TimestampBuilder:
+		if val, ok := v.(time.Time); ok {
+			builder.Append(arrow.Timestamp(val.UnixMicro()))
+			return nil
+		}
+	case *array.StringBuilder:
+		s, err := bulkLoadValueToString(v)
+		if err != nil {
+			return err
+		}
+		builder.Append(*s)
+		return nil
+	}
+	return errBulkLoad(fmt.Sprintf("value %v of type %T does not match column type %v", v, v, b.Type()))
+}
+
+func readBulkLoadResults(rows driver.Rows) ([]BulkLoadFileResult, error) {
+	columns := rows.Columns()
+	index := make(map[string]int, len(columns))
+	for i, name := range columns {
+		index[strings.ToLower(name)] = i
+	}
+	if _, ok := index["file"]; !ok {
+		// no files were loaded, the result only has a status column
+		return nil, nil
+	}
+	stringAt := func(row []driver.Value, name string) string {
+		i, ok := index[name]
+		if !ok || row[i] == nil {
+			return ""
+		}
+		return fmt.Sprint(row[i])
+	}
+	int64At := func(row []driver.Value, name string) int64 {
+		n, err := strconv.ParseInt(stringAt(row, name), 10, 64)
+		if err != nil {
+			return 0
+		}
+		return n
+	}
+
+	var results []BulkLoadFileResult
+	row := make([]driver.Value, len(columns))
+	for {
+		if err := rows.Next(row); err == io.EOF {
+			return results, nil
+		} else if err != nil {
+			return nil, err
+		}
+		results = append(results, BulkLoadFileResult{
+			File:                stringAt(row, "file"),
+			Status:              stringAt(row, "status"),
+			RowsParsed:          int64At(row, "rows_parsed"),
+			RowsLoaded:          int64At(row, "rows_loaded"),
+			ErrorsSeen:          int64At(row, "errors_seen"),
+			FirstError:          stringAt(row, "first_error"),
+			FirstErrorLine:      int64At(row, "first_error_line"),
+			FirstErrorCharacter: int64At(row, "first_error_character"),
+			FirstErrorColumn:    stringAt(row, "first_error_column_name"),
+		})
+	}
+}
+
+func errBulkLoad(message string) *SnowflakeError {
+	return &SnowflakeError{
+		Number:      ErrBulkLoad,
+		Message:     errMsgBulkLoad,
+		MessageArgs: []interface{}{message},
+	}
+}
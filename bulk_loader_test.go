@@ -0,0 +1,189 @@
+package gosnowflake
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/apache/arrow-go/v18/parquet/pqarrow"
+)
+
+type bulkLoadTestRow struct {
+	ID      int
+	Name    string `sf:"full_name"`
+	Score   *float64
+	Skipped string `sf:"skipped,ignore"`
+	hidden  string
+}
+
+func TestBulkLoadRowValues(t *testing.T) {
+	score := 1.5
+	values, names, err := bulkLoadRowValues(&bulkLoadTestRow{ID: 1, Name: "a", Score: &score, Skipped: "x", hidden: "y"})
+	assertNilF(t, err)
+	assertDeepEqualE(t, names, []string{"iD", "full_name", "score"})
+	assertEqualF(t, len(values), 3)
+	assertEqualE(t, values[0], 1)
+
+	values, names, err = bulkLoadRowValues([]any{1, "a"})
+	assertNilF(t, err)
+	assertNilE(t, names)
+	assertEqualE(t, len(values), 2)
+
+	_, _, err = bulkLoadRowValues(42)
+	assertNotNilF(t, err)
+	assertEqualE(t, err.(*SnowflakeError).Number, ErrBulkLoad)
+}
+
+func TestBulkLoadCSVRecord(t *testing.T) {
+	var nilScore *float64
+	ts := time.Date(2024, 3, 1, 10, 20, 30, 123456789, time.FixedZone("", 3600))
+	record, err := bulkLoadCSVRecord([]any{
+		int32(7), uint8(8), 0.1, true, "a,b", "", nil, nilScore, []byte{0xca, 0xfe}, ts, map[string]int{"k": 1},
+		sql.NullString{String: "valuer", Valid: true}, sql.NullInt64{},
+	})
+	assertNilF(t, err)
+	assertEqualE(t, string(record),
+		`7,8,0.1,true,"a,b","",,,cafe,2024-03-01 10:20:30.123456789 +01:00,"{""k"":1}",valuer,`+"\n")
+}
+
+func TestBulkLoaderValidatesRows(t *testing.T) {
+	sc := &snowflakeConn{cfg: &Config{}}
+	_, err := sc.NewBulkLoader(context.Background(), "", nil)
+	assertNotNilF(t, err)
+
+	bl, err := sc.NewBulkLoader(context.Background(), "t", nil)
+	assertNilF(t, err)
+	assertNilF(t, bl.Append([]any{1, "a"}))
+	err = bl.Append([]any{1})
+	assertNotNilF(t, err)
+	assertEqualE(t, err.(*SnowflakeError).Number, ErrBulkLoad)
+	assertEqualE(t, bl.csv.String(), "1,a\n")
+
+	bl, err = sc.NewBulkLoader(context.Background(), "t", &BulkLoaderOptions{Format: BulkLoadFormatParquet})
+	assertNilF(t, err)
+	assertNotNilE(t, bl.Append([]any{1, "a"}), "parquet without column names should be rejected")
+}
+
+func TestBulkLoaderCopyStatement(t *testing.T) {
+	sc := &snowflakeConn{cfg: &Config{}}
+	bl, err := sc.NewBulkLoader(context.Background(), "db.s.t", &BulkLoaderOptions{Columns: []string{"a", "b"}, OnError: "CONTINUE"})
+	assertNilF(t, err)
+	assertEqualE(t, bl.copyStatement(),
+		"COPY INTO db.s.t (a, b) FROM '"+bl.stagePath+"/' "+bulkLoadCSVFileFormat+" ON_ERROR=CONTINUE PURGE=TRUE")
+
+	bl, err = sc.NewBulkLoader(context.Background(), "t", &BulkLoaderOptions{Format: BulkLoadFormatParquet})
+	assertNilF(t, err)
+	assertNilF(t, bl.Append(bulkLoadTestRow{ID: 1}))
+	assertEqualE(t, bl.copyStatement(),
+		"COPY INTO t FROM '"+bl.stagePath+"/' "+bulkLoadParquetFileFormat+" ON_ERROR=ABORT_STATEMENT PURGE=TRUE")
+}
+
+func TestWriteBulkLoadParquet(t *testing.T) {
+	ts := time.Date(2024, 3, 1, 10, 20, 30, 123456000, time.UTC)
+	rows := [][]any{
+		{nil, "a", 1.5, true, ts, []byte{1}},
+		{int64(2), nil, 2.5, false, ts, nil},
+	}
+	var buf bytes.Buffer
+	assertNilF(t, writeBulkLoadParquet(&buf, memory.DefaultAllocator, []string{"ID", "NAME", "SCORE", "FLAG", "TS", "BIN"}, rows))
+
+	table, err := pqarrow.ReadTable(context.Background(), bytes.NewReader(buf.Bytes()), nil, pqarrow.ArrowReadProperties{}, memory.DefaultAllocator)
+	assertNilF(t, err)
+	defer table.Release()
+	assertEqualE(t, table.NumRows(), int64(2))
+	assertEqualF(t, table.NumCols(), int64(6))
+	assertEqualE(t, table.Schema().Field(0).Type.ID(), arrow.INT64)
+	assertEqualE(t, table.Schema().Field(1).Type.ID(), arrow.STRING)
+	assertEqualE(t, table.Schema().Field(4).Type.ID(), arrow.TIMESTAMP)
+	ids := table.Column(0).Data().Chunk(0).(*array.Int64)
+	assertTrueE(t, ids.IsNull(0))
+	assertEqualE(t, ids.Value(1), int64(2))
+
+	err = writeBulkLoadParquet(&buf, memory.DefaultAllocator, []string{"ID"}, [][]any{{1}, {"a"}})
+	assertNotNilE(t, err, "mixed column types should be rejected")
+}
+
+type fakeCopyRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *fakeCopyRows) Columns() []string { return r.columns }
+func (r *fakeCopyRows) Close() error      { return nil }
+func (r *fakeCopyRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+func TestReadBulkLoadResults(t *testing.T) {
+	results, err := readBulkLoadResults(&fakeCopyRows{
+		columns: []string{"file", "status", "rows_parsed", "rows_loaded", "error_limit", "errors_seen", "first_error", "first_error_line", "first_error_character", "first_error_column_name"},
+		rows: [][]driver.Value{
+			{"stage/1.csv.gz", "LOADED", "10", "10", "1", "0", nil, nil, nil, nil},
+			{"stage/2.csv.gz", "PARTIALLY_LOADED", "10", "9", "10", "1", "bad value", "3", "5", `"T"["A":1]`},
+		},
+	})
+	assertNilF(t, err)
+	assertEqualF(t, len(results), 2)
+	assertEqualE(t, results[0], BulkLoadFileResult{File: "stage/1.csv.gz", Status: "LOADED", RowsParsed: 10, RowsLoaded: 10})
+	assertEqualE(t, results[1], BulkLoadFileResult{File: "stage/2.csv.gz", Status: "PARTIALLY_LOADED", RowsParsed: 10, RowsLoaded: 9,
+		ErrorsSeen: 1, FirstError: "bad value", FirstErrorLine: 3, FirstErrorCharacter: 5, FirstErrorColumn: `"T"["A":1]`})
+
+	results, err = readBulkLoadResults(&fakeCopyRows{
+		columns: []string{"status"},
+		rows:    [][]driver.Value{{"Copy executed with 0 files processed."}},
+	})
+	assertNilF(t, err)
+	assertEqualE(t, len(results), 0)
+}
+
+func TestBulkLoader(t *testing.T) {
+	for _, format := range []BulkLoadFormat{BulkLoadFormatCSV, BulkLoadFormatParquet} {
+		t.Run(format.String(), func(t *testing.T) {
+			tableName := "test_bulk_loader_" + randomString(5)
+			runDBTest(t, func(dbt *DBTest) {
+				dbt.mustExec(fmt.Sprintf("CREATE OR REPLACE TABLE %v (ID INTEGER, FULL_NAME VARCHAR, SCORE DOUBLE)", tableName))
+				defer dbt.mustExec("DROP TABLE IF EXISTS " + tableName)
+
+				var results []BulkLoadFileResult
+				err := dbt.conn.Raw(func(x any) error {
+					bl, err := x.(BulkLoadConnection).NewBulkLoader(context.Background(), tableName,
+						&BulkLoaderOptions{Format: format, FileSize: 1024})
+					if err != nil {
+						return err
+					}
+					for i := 0; i < 1000; i++ {
+						score := float64(i) / 2
+						if err = bl.Append(bulkLoadTestRow{ID: i, Name: strings.Repeat("x", i%10), Score: &score}); err != nil {
+							return err
+						}
+					}
+					results, err = bl.Flush()
+					return err
+				})
+				assertNilF(t, err)
+				assertTrueE(t, len(results) > 1, "rows should be split into multiple files")
+				var loaded int64
+				for _, result := range results {
+					assertEqualE(t, result.Status, "LOADED")
+					loaded += result.RowsLoaded
+				}
+				assertEqualE(t, loaded, int64(1000))
+				dbt.mustQueryAssertCount("SELECT * FROM "+tableName, 1000)
+			})
+		})
+	}
+}
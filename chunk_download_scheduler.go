@@ -0,0 +1,146 @@
+package gosnowflake
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	// a change of throughput smaller than this ratio does not change the number of workers
+	chunkDownloadThroughputTolerance = 0.1
+)
+
+type chunkDownloadConcurrency struct {
+	min int
+	max int
+}
+
+// WithChunkDownloadConcurrency returns a context that lets the chunk downloader adjust the number of chunks
+// downloaded ahead of the reader between minWorkers and maxWorkers, based on the measured download throughput.
+// By default, MaxChunkDownloadWorkers chunks are always downloaded ahead.
+func WithChunkDownloadConcurrency(ctx context.Context, minWorkers, maxWorkers int) context.Context {
+	return context.WithValue(ctx, chunkDownloadConcurrencyKey, chunkDownloadConcurrency{min: minWorkers, max: maxWorkers})
+}
+
+// WithResultMemoryLimit returns a context that limits the uncompressed size of the result chunks that are
+// downloaded or waiting to be read. At least one chunk is always downloaded, even if it exceeds the limit.
+func WithResultMemoryLimit(ctx context.Context, bytes int64) context.Context {
+	return context.WithValue(ctx, resultMemoryLimitKey, bytes)
+}
+
+func getChunkDownloadConcurrency(ctx context.Context) (minWorkers int, maxWorkers int) {
+	minWorkers, maxWorkers = MaxChunkDownloadWorkers, MaxChunkDownloadWorkers
+	if c, ok := ctx.Value(chunkDownloadConcurrencyKey).(chunkDownloadConcurrency); ok {
+		minWorkers, maxWorkers = c.min, c.max
+	}
+	minWorkers = intMax(minWorkers, 1)
+	return minWorkers, intMax(maxWorkers, minWorkers)
+}
+
+func getResultMemoryLimit(ctx context.Context) int64 {
+	limit, _ := ctx.Value(resultMemoryLimitKey).(int64)
+	return limit
+}
+
+// chunkDownloadScheduler decides how many chunks are downloaded ahead of the reader.
+// A chunk is in flight from the moment it is scheduled until the reader picks it up.
+type chunkDownloadScheduler struct {
+	mu          sync.Mutex
+	minWorkers  int
+	maxWorkers  int
+	workers     int
+	memoryLimit int64
+	// chunk taken from the queue, but not scheduled yet. -1 if none.
+	held          int
+	inFlight      int
+	bytesInFlight int64
+
+	windowStart    time.Time
+	windowBytes    int64
+	windowChunks   int
+	lastThroughput float64
+}
+
+func newChunkDownloadScheduler(ctx context.Context) *chunkDownloadScheduler {
+	minWorkers, maxWorkers := getChunkDownloadConcurrency(ctx)
+	return &chunkDownloadScheduler{
+		minWorkers:  minWorkers,
+		maxWorkers:  maxWorkers,
+		workers:     minWorkers,
+		memoryLimit: getResultMemoryLimit(ctx),
+		held:        -1,
+		windowStart: time.Now(),
+	}
+}
+
+// acquire returns the next chunk to download from the queue if the concurrency and memory limits allow it.
+func (s *chunkDownloadScheduler) acquire(queue <-chan int, metas []execResponseChunk) (int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.held < 0 {
+		select {
+		case idx, ok := <-queue:
+			if !ok {
+				return 0, false
+			}
+			s.held = idx
+		default:
+			return 0, false
+		}
+	}
+	size := metas[s.held].UncompressedSize
+	if s.inFlight > 0 && (s.inFlight >= s.workers || s.memoryLimit > 0 && s.bytesInFlight+size > s.memoryLimit) {
+		return 0, false
+	}
+	idx := s.held
+	s.held = -1
+	s.inFlight++
+	s.bytesInFlight += size
+	return idx, true
+}
+
+// release frees the slot of a chunk picked up by the reader.
+func (s *chunkDownloadScheduler) release(size int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.inFlight--
+	s.bytesInFlight -= size
+}
+
+// downloaded records a finished download. After every window of as many downloads as there are workers,
+// the throughput of the window is compared with the previous one and the number of workers is raised while
+// it improves and lowered when it drops. A slow reader stretches the window, so it also lowers the number of workers.
+// It returns true if the number of workers was raised.
+func (s *chunkDownloadScheduler) downloaded(size int64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.windowBytes += size
+	s.windowChunks++
+	if s.windowChunks < s.workers {
+		return false
+	}
+	elapsed := time.Since(s.windowStart).Seconds()
+	throughput := float64(s.windowBytes)
+	if elapsed > 0 {
+		throughput /= elapsed
+	}
+	raised := false
+	switch {
+	case throughput >= s.lastThroughput*(1+chunkDownloadThroughputTolerance):
+		if s.workers < s.maxWorkers {
+			s.workers++
+			raised = true
+		}
+	case throughput < s.lastThroughput*(1-chunkDownloadThroughputTolerance):
+		if s.workers > s.minWorkers {
+			s.workers--
+		}
+	}
+	logger.Debugf("chunk download throughput: %.0f B/s, workers: %v", throughput, s.workers)
+	s.lastThroughput = throughput
+	s.windowStart = time.Now()
+	s.windowBytes = 0
+	s.windowChunks = 0
+	return raised
+}
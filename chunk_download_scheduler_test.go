@@ -0,0 +1,86 @@
+package gosnowflake
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func newSchedulerTestQueue(sizes ...int64) (chan int, []execResponseChunk) {
+	queue := make(chan int, len(sizes))
+	metas := make([]execResponseChunk, len(sizes))
+	for i, size := range sizes {
+		metas[i] = execResponseChunk{UncompressedSize: size}
+		queue <- i
+	}
+	return queue, metas
+}
+
+func TestChunkDownloadSchedulerDefaults(t *testing.T) {
+	s := newChunkDownloadScheduler(context.Background())
+	assertEqualE(t, s.minWorkers, MaxChunkDownloadWorkers)
+	assertEqualE(t, s.maxWorkers, MaxChunkDownloadWorkers)
+	assertEqualE(t, s.memoryLimit, int64(0))
+
+	s = newChunkDownloadScheduler(WithResultMemoryLimit(WithChunkDownloadConcurrency(context.Background(), 0, -1), 100))
+	assertEqualE(t, s.minWorkers, 1)
+	assertEqualE(t, s.maxWorkers, 1)
+	assertEqualE(t, s.memoryLimit, int64(100))
+}
+
+func TestChunkDownloadSchedulerLimits(t *testing.T) {
+	queue, metas := newSchedulerTestQueue(60, 30, 200, 10, 10)
+	s := newChunkDownloadScheduler(WithResultMemoryLimit(WithChunkDownloadConcurrency(context.Background(), 3, 3), 100))
+
+	idx, ok := s.acquire(queue, metas)
+	assertTrueF(t, ok)
+	assertEqualE(t, idx, 0)
+	idx, ok = s.acquire(queue, metas)
+	assertTrueF(t, ok)
+	assertEqualE(t, idx, 1)
+	_, ok = s.acquire(queue, metas)
+	assertFalseE(t, ok, "chunk 2 exceeds the memory limit")
+
+	s.release(60)
+	s.release(30)
+	idx, ok = s.acquire(queue, metas)
+	assertTrueE(t, ok, "a chunk larger than the limit is downloaded when nothing else is in flight")
+	assertEqualE(t, idx, 2)
+	s.release(200)
+
+	for i := 3; i < 5; i++ {
+		idx, ok = s.acquire(queue, metas)
+		assertTrueF(t, ok)
+		assertEqualE(t, idx, i)
+	}
+	_, ok = s.acquire(queue, metas)
+	assertFalseE(t, ok, "queue is empty")
+	close(queue)
+	_, ok = s.acquire(queue, metas)
+	assertFalseE(t, ok, "queue is closed")
+}
+
+func TestChunkDownloadSchedulerAdjustsWorkers(t *testing.T) {
+	s := newChunkDownloadScheduler(WithChunkDownloadConcurrency(context.Background(), 1, 3))
+	assertEqualE(t, s.workers, 1)
+	assertTrueE(t, s.downloaded(1000), "the first window raises the number of workers")
+	assertEqualE(t, s.workers, 2)
+
+	assertFalseE(t, s.downloaded(1000), "the window is not complete yet")
+	s.windowStart = time.Now().Add(-time.Hour)
+	assertFalseE(t, s.downloaded(1000), "a throughput drop lowers the number of workers")
+	assertEqualE(t, s.workers, 1)
+
+	s.lastThroughput = 1
+	assertTrueE(t, s.downloaded(1000))
+	assertEqualE(t, s.workers, 2)
+	s.lastThroughput = 1
+	s.downloaded(1000)
+	assertTrueE(t, s.downloaded(1000))
+	assertEqualE(t, s.workers, 3)
+	s.lastThroughput = 1
+	s.downloaded(1000)
+	s.downloaded(1000)
+	assertFalseE(t, s.downloaded(1000), "the number of workers does not exceed the maximum")
+	assertEqualE(t, s.workers, 3)
+}
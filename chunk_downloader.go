@@ -30,6 +30,7 @@ type chunkDownloader interface {
 	start() error
 	next() (chunkRowType, error)
 	reset()
+	close() error
 	getChunkMetas() []execResponseChunk
 	getQueryResultFormat() resultFormat
 	getRowType() []execResponseRowType
@@ -67,6 +68,17 @@ type snowflakeChunkDownloader struct {
 	FuncDownload       func(context.Context, *snowflakeChunkDownloader, int)
 	FuncDownloadHelper func(context.Context, *snowflakeChunkDownloader, int) error
 	FuncGet            func(context.Context, *snowflakeConn, string, map[string]string, time.Duration) (*http.Response, error)
+
+	scheduler *chunkDownloadScheduler
+	spill     *chunkSpill
+
+	// position of the first row to return, set when resuming from a cursor
+	resumeChunkIndex int
+	resumeRowOffset  int
+	skipRows         int
+	// position after the last returned row
+	cursorChunkIndex int
+	cursorRowOffset  int
 }
 
 func (scd *snowflakeChunkDownloader) totalUncompressedSize() (acc int64) {
@@ -106,33 +118,75 @@ func (scd *snowflakeChunkDownloader) start() error {
 		}
 	}
 
+	if err := scd.skipToResumePosition(); err != nil {
+		return err
+	}
+
 	// start downloading chunks if exists
 	chunkMetaLen := len(scd.ChunkMetas)
 	if chunkMetaLen > 0 {
-		logger.WithContext(scd.ctx).Debugf("MaxChunkDownloadWorkers: %v", MaxChunkDownloadWorkers)
+		scd.scheduler = newChunkDownloadScheduler(scd.ctx)
+		scd.spill = newChunkSpill(scd.ctx, scd.sc)
+		logger.WithContext(scd.ctx).Debugf("chunk download workers: %v-%v, memory limit: %v",
+			scd.scheduler.minWorkers, scd.scheduler.maxWorkers, scd.scheduler.memoryLimit)
 		logger.WithContext(scd.ctx).Debugf("chunks: %v, total bytes: %d", chunkMetaLen, scd.totalUncompressedSize())
 		scd.ChunksMutex = &sync.Mutex{}
 		scd.DoneDownloadCond = sync.NewCond(scd.ChunksMutex)
 		scd.Chunks = make(map[int][]chunkRowType)
 		scd.ChunksChan = make(chan int, chunkMetaLen)
-		scd.ChunksError = make(chan *chunkError, MaxChunkDownloadWorkers)
-		for i := 0; i < chunkMetaLen; i++ {
+		scd.ChunksError = make(chan *chunkError, scd.scheduler.maxWorkers)
+		for i := intMax(scd.resumeChunkIndex-1, 0); i < chunkMetaLen; i++ {
 			chunk := scd.ChunkMetas[i]
 			logger.WithContext(scd.ctx).Debugf("Result Format: %v, add chunk to channel ChunksChan: %v, URL: %v, RowCount: %v, UncompressedSize: %v, ChunkResultFormat: %v",
 				scd.getQueryResultFormat(), i+1, chunk.URL, chunk.RowCount, chunk.UncompressedSize, scd.QueryResultFormat)
 			scd.ChunksChan <- i
 		}
-		for i := 0; i < intMin(MaxChunkDownloadWorkers, chunkMetaLen); i++ {
-			scd.schedule()
+		scd.schedule()
+	}
+	return nil
+}
+
+// resumeAt sets the position of the first row to return. It must be called before start.
+func (scd *snowflakeChunkDownloader) resumeAt(chunkIndex, rowOffset int) error {
+	if usesArrowBatches(scd.ctx) {
+		return errInvalidResultCursor("", "cursors are not supported for arrow batches")
+	}
+	if chunkIndex < 0 || chunkIndex > len(scd.ChunkMetas) || rowOffset < 0 {
+		return errInvalidResultCursor("", fmt.Sprintf("position out of range. chunk: %v, row: %v", chunkIndex, rowOffset))
+	}
+	if chunkIndex > 0 && rowOffset > scd.ChunkMetas[chunkIndex-1].RowCount {
+		return errInvalidResultCursor("", fmt.Sprintf("chunk %v has %v rows, row: %v", chunkIndex, scd.ChunkMetas[chunkIndex-1].RowCount, rowOffset))
+	}
+	scd.resumeChunkIndex = chunkIndex
+	scd.resumeRowOffset = rowOffset
+	return nil
+}
+
+// skipToResumePosition skips the rows before the resume position. Chunks before it are never downloaded.
+func (scd *snowflakeChunkDownloader) skipToResumePosition() error {
+	scd.cursorChunkIndex = scd.resumeChunkIndex
+	scd.cursorRowOffset = scd.resumeRowOffset
+	if scd.resumeChunkIndex == 0 {
+		if scd.resumeRowOffset > scd.CurrentChunkSize {
+			return errInvalidResultCursor("", fmt.Sprintf("the first rowset has %v rows, row: %v", scd.CurrentChunkSize, scd.resumeRowOffset))
 		}
+		scd.CurrentIndex = scd.resumeRowOffset - 1
+		return nil
 	}
+	scd.CurrentChunk = nil
+	scd.CurrentChunkSize = 0
+	scd.CurrentChunkIndex = scd.resumeChunkIndex - 2 // next moves on to the chunk at resumeChunkIndex-1
+	scd.skipRows = scd.resumeRowOffset
 	return nil
 }
 
+// schedule starts downloading as many queued chunks as the scheduler allows.
 func (scd *snowflakeChunkDownloader) schedule() {
-	timer := time.Now()
-	select {
-	case nextIdx := <-scd.ChunksChan:
+	for {
+		nextIdx, ok := scd.scheduler.acquire(scd.ChunksChan, scd.ChunkMetas)
+		if !ok {
+			return
+		}
 		logger.WithContext(scd.ctx).Infof("schedule chunk: %v", nextIdx+1)
 		go GoroutineWrapper(
 			scd.ctx,
@@ -140,14 +194,6 @@ func (scd *snowflakeChunkDownloader) schedule() {
 				scd.FuncDownload(scd.ctx, scd, nextIdx)
 			},
 		)
-	default:
-		// no more download
-		chunkCount := len(scd.ChunkMetas)
-		avgTime := 0.0
-		if chunkCount > 0 {
-			avgTime = float64(time.Since(timer)) / float64(chunkCount)
-		}
-		logger.WithContext(scd.ctx).Infof("Processed %v chunks. It took %v ms, average chunk processing time: %v ms", len(scd.ChunkMetas), time.Since(timer).String(), avgTime)
 	}
 }
 
@@ -184,6 +230,8 @@ func (scd *snowflakeChunkDownloader) next() (chunkRowType, error) {
 	for {
 		scd.CurrentIndex++
 		if scd.CurrentIndex < scd.CurrentChunkSize {
+			scd.cursorChunkIndex = scd.CurrentChunkIndex + 1
+			scd.cursorRowOffset = scd.CurrentIndex + 1
 			return scd.CurrentChunk[scd.CurrentIndex], nil
 		}
 		scd.CurrentChunkIndex++ // next chunk
@@ -198,6 +246,13 @@ func (scd *snowflakeChunkDownloader) next() (chunkRowType, error) {
 		}
 
 		for scd.Chunks[scd.CurrentChunkIndex] == nil {
+			if scd.isSpilled(scd.CurrentChunkIndex) {
+				if err := scd.spill.load(scd, scd.CurrentChunkIndex); err != nil {
+					scd.ChunksMutex.Unlock()
+					return chunkRowType{}, fmt.Errorf("loading spilled chunk: %w", err)
+				}
+				continue
+			}
 			logger.WithContext(scd.ctx).Debugf("waiting for chunk idx: %v/%v",
 				scd.CurrentChunkIndex+1, len(scd.ChunkMetas))
 
@@ -214,8 +269,11 @@ func (scd *snowflakeChunkDownloader) next() (chunkRowType, error) {
 		scd.CurrentChunk = scd.Chunks[scd.CurrentChunkIndex]
 		scd.ChunksMutex.Unlock()
 		scd.CurrentChunkSize = len(scd.CurrentChunk)
+		scd.CurrentIndex += scd.skipRows
+		scd.skipRows = 0
 
 		// kick off the next download
+		scd.scheduler.release(scd.ChunkMetas[scd.CurrentChunkIndex].UncompressedSize)
 		scd.schedule()
 	}
 
@@ -231,6 +289,22 @@ func (scd *snowflakeChunkDownloader) reset() {
 	scd.Chunks = nil // detach all chunks. No way to go backward without reinitialize it.
 }
 
+func (scd *snowflakeChunkDownloader) close() error {
+	if scd.spill == nil {
+		return nil
+	}
+	return scd.spill.close()
+}
+
+// isSpilled returns true if the chunk is kept on disk. The caller must hold ChunksMutex.
+func (scd *snowflakeChunkDownloader) isSpilled(idx int) bool {
+	if scd.spill == nil {
+		return false
+	}
+	_, ok := scd.spill.files[idx]
+	return ok
+}
+
 func (scd *snowflakeChunkDownloader) getChunkMetas() []execResponseChunk {
 	return scd.ChunkMetas
 }
@@ -277,7 +351,9 @@ func getChunk(
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse URL: %w", err)
 	}
-	return newRetryHTTP(ctx, sc.rest.Client, http.NewRequest, u, headers, timeout, sc.rest.MaxRetryCount, sc.currentTimeProvider, sc.cfg).execute()
+	return newRetryHTTP(ctx, sc.rest.Client, http.NewRequest, u, headers, timeout, sc.rest.MaxRetryCount, sc.currentTimeProvider, sc.cfg).
+		setRequestKind(RetryRequestKindChunk).
+		execute()
 }
 
 func (scd *snowflakeChunkDownloader) startArrowBatches() error {
@@ -364,12 +440,23 @@ func downloadChunk(ctx context.Context, scd *snowflakeChunkDownloader, idx int)
 		scd.ChunksError <- &chunkError{Index: idx, Error: err}
 	} else if errors.Is(scd.ctx.Err(), context.Canceled) || errors.Is(scd.ctx.Err(), context.DeadlineExceeded) {
 		scd.ChunksError <- &chunkError{Index: idx, Error: scd.ctx.Err()}
+	} else if scd.scheduler != nil && scd.scheduler.downloaded(scd.ChunkMetas[idx].UncompressedSize) {
+		scd.schedule()
 	}
 	elapsedTime := time.Since(timer).String()
 	logger.Debugf("“Processed %v chunk %v out of %v. It took %v ms. Chunk size: %v, rows: %v”.", scd.getQueryResultFormat(), idx+1, len(scd.ChunkMetas), elapsedTime, scd.ChunkMetas[idx].UncompressedSize, scd.ChunkMetas[idx].RowCount)
 }
 
-func downloadChunkHelper(ctx context.Context, scd *snowflakeChunkDownloader, idx int) error {
+func downloadChunkHelper(ctx context.Context, scd *snowflakeChunkDownloader, idx int) (err error) {
+	ctx, op := scd.sc.instrumentation().startOperation(ctx, spanNameChunkDownload, "chunk_download",
+		attrChunkIndex.Int(idx), attrRowCount.Int(scd.ChunkMetas[idx].RowCount))
+	var body *countingReader
+	defer func() {
+		if body != nil {
+			op.recordBytes(ctx, body.n)
+		}
+		op.end(ctx, err)
+	}()
 	headers := make(map[string]string)
 	if len(scd.ChunkHeader) > 0 {
 		logger.WithContext(ctx).Debug("chunk header is provided.")
@@ -388,8 +475,8 @@ func downloadChunkHelper(ctx context.Context, scd *snowflakeChunkDownloader, idx
 		return fmt.Errorf("getting chunk: %w", err)
 	}
 	defer func() {
-		if err = resp.Body.Close(); err != nil {
-			logger.Warnf("downloadChunkHelper: closing response body %v: %v", scd.ChunkMetas[idx].URL, err)
+		if closeErr := resp.Body.Close(); closeErr != nil {
+			logger.Warnf("downloadChunkHelper: closing response body %v: %v", scd.ChunkMetas[idx].URL, closeErr)
 		}
 	}()
 	logger.WithContext(ctx).Debugf("response returned chunk: %v for URL: %v", idx+1, scd.ChunkMetas[idx].URL)
@@ -407,7 +494,11 @@ func downloadChunkHelper(ctx context.Context, scd *snowflakeChunkDownloader, idx
 		}
 	}
 
-	bufStream := bufio.NewReader(resp.Body)
+	body = &countingReader{r: resp.Body}
+	if scd.spill != nil && scd.spill.shouldSpill(scd, idx) {
+		return scd.spill.write(scd, idx, body)
+	}
+	bufStream := bufio.NewReader(body)
 	return decodeChunk(ctx, scd, idx, bufStream)
 }
 
@@ -591,6 +682,8 @@ func (scd *streamChunkDownloader) next() (chunkRowType, error) {
 
 func (scd *streamChunkDownloader) reset() {}
 
+func (scd *streamChunkDownloader) close() error { return nil }
+
 func (scd *streamChunkDownloader) getChunkMetas() []execResponseChunk {
 	return scd.ChunkMetas
 }
@@ -670,7 +763,9 @@ func (f *httpStreamChunkFetcher) fetch(URL string, rows chan<- []*string) error
 	if err != nil {
 		return fmt.Errorf("parsing URL: %w", err)
 	}
-	res, err := newRetryHTTP(f.ctx, f.client, http.NewRequest, fullURL, f.headers, f.timeout, f.maxRetryCount, defaultTimeProvider, nil).execute()
+	res, err := newRetryHTTP(f.ctx, f.client, http.NewRequest, fullURL, f.headers, f.timeout, f.maxRetryCount, defaultTimeProvider, nil).
+		setRequestKind(RetryRequestKindChunk).
+		execute()
 	if err != nil {
 		return fmt.Errorf("executing HTTP request: %w", err)
 	}
@@ -0,0 +1,143 @@
+package gosnowflake
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+)
+
+const (
+	chunkSpillKeySize = 32
+	chunkSpillIVSize  = 12
+)
+
+// WithResultSpill returns a context that spills downloaded result chunks to files in Config.TmpDirPath once
+// the uncompressed size of the chunks kept in memory reaches memoryWatermark. The files are encrypted with
+// a key that exists only in memory, read back when the rows reach them and removed when the rows are closed.
+// It has no effect on Arrow batches.
+func WithResultSpill(ctx context.Context, memoryWatermark int64) context.Context {
+	return context.WithValue(ctx, resultSpillKey, memoryWatermark)
+}
+
+func getResultSpillWatermark(ctx context.Context) int64 {
+	watermark, _ := ctx.Value(resultSpillKey).(int64)
+	return watermark
+}
+
+// chunkSpill keeps raw chunks on disk until they are read. The files map is guarded by the downloader's ChunksMutex.
+type chunkSpill struct {
+	watermark int64
+	tmpDir    string
+	key       []byte
+
+	mu     sync.Mutex
+	dir    string
+	closed bool
+	files  map[int]string
+}
+
+func newChunkSpill(ctx context.Context, sc *snowflakeConn) *chunkSpill {
+	watermark := getResultSpillWatermark(ctx)
+	if watermark <= 0 {
+		return nil
+	}
+	var tmpDir string
+	if sc != nil && sc.cfg != nil {
+		tmpDir = sc.cfg.TmpDirPath
+	}
+	return &chunkSpill{
+		watermark: watermark,
+		tmpDir:    tmpDir,
+		key:       getSecureRandom(chunkSpillKeySize),
+		files:     make(map[int]string),
+	}
+}
+
+// shouldSpill returns true if the chunks kept in memory, together with the chunk idx, exceed the watermark.
+func (cs *chunkSpill) shouldSpill(scd *snowflakeChunkDownloader, idx int) bool {
+	scd.ChunksMutex.Lock()
+	defer scd.ChunksMutex.Unlock()
+	inMemory := scd.ChunkMetas[idx].UncompressedSize
+	for i, chunk := range scd.Chunks {
+		if chunk != nil {
+			inMemory += scd.ChunkMetas[i].UncompressedSize
+		}
+	}
+	return inMemory > cs.watermark
+}
+
+// write encrypts the raw chunk and stores it in the spill directory.
+func (cs *chunkSpill) write(scd *snowflakeChunkDownloader, idx int, r io.Reader) error {
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("reading chunk: %w", err)
+	}
+	iv := getSecureRandom(chunkSpillIVSize)
+	encrypted, err := encryptGCM(iv, raw, cs.key, []byte(strconv.Itoa(idx)))
+	if err != nil {
+		return fmt.Errorf("encrypting chunk: %w", err)
+	}
+
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	if cs.closed {
+		return nil
+	}
+	if cs.dir == "" {
+		if cs.dir, err = os.MkdirTemp(cs.tmpDir, "snowflake-result-"); err != nil {
+			return fmt.Errorf("creating spill directory: %w", err)
+		}
+	}
+	path := filepath.Join(cs.dir, fmt.Sprintf("chunk-%d", idx))
+	if err = os.WriteFile(path, append(iv, encrypted...), 0600); err != nil {
+		return fmt.Errorf("writing spilled chunk: %w", err)
+	}
+	logger.WithContext(scd.ctx).Debugf("spilled chunk %v (%v bytes) to disk", idx+1, len(raw))
+
+	scd.ChunksMutex.Lock()
+	defer scd.ChunksMutex.Unlock()
+	cs.files[idx] = path
+	return nil
+}
+
+// load reads the spilled chunk back, decodes it into scd.Chunks and removes its file.
+// The caller must hold ChunksMutex, which is released while the chunk is decoded.
+func (cs *chunkSpill) load(scd *snowflakeChunkDownloader, idx int) error {
+	path := cs.files[idx]
+	delete(cs.files, idx)
+	scd.ChunksMutex.Unlock()
+	defer scd.ChunksMutex.Lock()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading spilled chunk: %w", err)
+	}
+	if err = os.Remove(path); err != nil {
+		logger.WithContext(scd.ctx).Warnf("failed to remove spilled chunk %v: %v", path, err)
+	}
+	if len(data) < chunkSpillIVSize {
+		return fmt.Errorf("spilled chunk %v is truncated", idx+1)
+	}
+	raw, err := decryptGCM(data[:chunkSpillIVSize], data[chunkSpillIVSize:], cs.key, []byte(strconv.Itoa(idx)))
+	if err != nil {
+		return fmt.Errorf("decrypting spilled chunk: %w", err)
+	}
+	return decodeChunk(scd.ctx, scd, idx, bufio.NewReader(bytes.NewReader(raw)))
+}
+
+// close removes all spilled chunks. Chunks downloaded afterwards are dropped.
+func (cs *chunkSpill) close() error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cs.closed = true
+	if cs.dir == "" {
+		return nil
+	}
+	return os.RemoveAll(cs.dir)
+}
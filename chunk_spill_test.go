@@ -0,0 +1,79 @@
+package gosnowflake
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestResultSpill(t *testing.T) {
+	numChunks := 5
+	tmpDir := t.TempDir()
+	cm := make([]execResponseChunk, numChunks)
+	for i := range cm {
+		cm[i] = execResponseChunk{URL: fmt.Sprintf("chunk%v", i), RowCount: 2, UncompressedSize: 100}
+	}
+	var othersDownloaded sync.WaitGroup
+	othersDownloaded.Add(numChunks - 1)
+	sc := &snowflakeConn{
+		cfg:  &Config{Params: make(map[string]*string), TmpDirPath: tmpDir},
+		rest: &snowflakeRestful{RequestTimeout: defaultRequestTimeout},
+	}
+	ctx := WithResultSpill(WithChunkDownloadConcurrency(context.Background(), numChunks, numChunks), 150)
+	scd := &snowflakeChunkDownloader{
+		sc:            sc,
+		ctx:           ctx,
+		Total:         int64(numChunks * 2),
+		ChunkMetas:    cm,
+		TotalRowIndex: int64(-1),
+		Qrmk:          "HOHOHO",
+		FuncDownload: func(ctx context.Context, scd *snowflakeChunkDownloader, idx int) {
+			downloadChunk(ctx, scd, idx)
+			if idx != 0 {
+				othersDownloaded.Done()
+			}
+		},
+		FuncDownloadHelper: downloadChunkHelper,
+		FuncGet: func(_ context.Context, _ *snowflakeConn, url string, _ map[string]string, _ time.Duration) (*http.Response, error) {
+			// the first chunk arrives last, so the chunks after it pile up
+			if url == "chunk0" {
+				othersDownloaded.Wait()
+			}
+			body := fmt.Sprintf(`["%v-0"],["%v-1"]`, url, url)
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body))}, nil
+		},
+		RowSet: rowSetType{RowType: []execResponseRowType{{Name: "c1", Type: "TEXT"}}},
+	}
+	rows := &snowflakeRows{sc: sc, ctx: ctx, ChunkDownloader: scd}
+	assertNilF(t, scd.start())
+
+	dest := make([]driver.Value, 1)
+	assertNilF(t, rows.Next(dest))
+	assertEqualE(t, dest[0], "chunk0-0")
+	scd.ChunksMutex.Lock()
+	assertEqualE(t, len(scd.spill.files), numChunks-2, "all chunks above the watermark should be spilled")
+	for _, path := range scd.spill.files {
+		data, err := os.ReadFile(path)
+		assertNilF(t, err)
+		assertFalseE(t, bytes.Contains(data, []byte("chunk")), "spilled chunk should be encrypted")
+	}
+	scd.ChunksMutex.Unlock()
+
+	for i := 1; i < numChunks*2; i++ {
+		assertNilF(t, rows.Next(dest))
+		assertEqualE(t, dest[0], fmt.Sprintf("chunk%v-%v", i/2, i%2))
+	}
+	assertEqualE(t, rows.Next(dest), io.EOF)
+	assertNilF(t, rows.Close())
+	entries, err := os.ReadDir(tmpDir)
+	assertNilF(t, err)
+	assertEqualE(t, len(entries), 0, "spill directory should be removed")
+}
@@ -0,0 +1,292 @@
+package gosnowflake
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultCircuitBreakerFailureThreshold = 5
+	defaultCircuitBreakerOpenDuration     = 30 * time.Second
+	defaultCircuitBreakerHalfOpenProbes   = 1
+)
+
+// CircuitBreakerEndpoint is the class of endpoints a circuit breaker guards.
+type CircuitBreakerEndpoint int
+
+const (
+	// CircuitBreakerEndpointLogin guards login, token and authenticator requests.
+	CircuitBreakerEndpointLogin CircuitBreakerEndpoint = iota
+	// CircuitBreakerEndpointQuery guards query requests.
+	CircuitBreakerEndpointQuery
+	// CircuitBreakerEndpointQueryMonitoring guards query status requests.
+	CircuitBreakerEndpointQueryMonitoring
+	// CircuitBreakerEndpointChunkStorage guards result chunk downloads from cloud storage.
+	CircuitBreakerEndpointChunkStorage
+)
+
+func (e CircuitBreakerEndpoint) String() string {
+	switch e {
+	case CircuitBreakerEndpointLogin:
+		return "login"
+	case CircuitBreakerEndpointQuery:
+		return "query"
+	case CircuitBreakerEndpointQueryMonitoring:
+		return "query monitoring"
+	case CircuitBreakerEndpointChunkStorage:
+		return "chunk storage"
+	default:
+		return "unknown"
+	}
+}
+
+// CircuitBreakerState is the state of a circuit breaker.
+type CircuitBreakerState int
+
+const (
+	// CircuitBreakerClosed lets all requests through.
+	CircuitBreakerClosed CircuitBreakerState = iota
+	// CircuitBreakerOpen rejects all requests.
+	CircuitBreakerOpen
+	// CircuitBreakerHalfOpen lets a limited number of probe requests through.
+	CircuitBreakerHalfOpen
+)
+
+func (s CircuitBreakerState) String() string {
+	switch s {
+	case CircuitBreakerClosed:
+		return "closed"
+	case CircuitBreakerOpen:
+		return "open"
+	case CircuitBreakerHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// CircuitBreakerConfig configures client-side circuit breaking.
+type CircuitBreakerConfig struct {
+	FailureThreshold int           // consecutive failures which open the breaker. Default is 5.
+	OpenDuration     time.Duration // how long the breaker stays open before probing. Default is 30s.
+	HalfOpenProbes   int           // number of concurrent probe requests allowed when half-open. Default is 1.
+}
+
+// CircuitBreakerStat is a snapshot of a single circuit breaker.
+type CircuitBreakerStat struct {
+	Host                string
+	Endpoint            CircuitBreakerEndpoint
+	State               CircuitBreakerState
+	ConsecutiveFailures int
+	Rejections          int64     // requests rejected since the breaker was created
+	OpenedAt            time.Time // last time the breaker opened, zero if it never opened
+}
+
+type circuitBreakerKey struct {
+	host     string
+	endpoint CircuitBreakerEndpoint
+}
+
+type circuitBreaker struct {
+	mu                  sync.Mutex
+	key                 circuitBreakerKey
+	cfg                 CircuitBreakerConfig
+	state               CircuitBreakerState
+	consecutiveFailures int
+	probesInFlight      int
+	rejections          int64
+	openedAt            time.Time
+	now                 func() time.Time
+}
+
+type circuitBreakerRegistry struct {
+	mu       sync.Mutex
+	enabled  bool
+	cfg      CircuitBreakerConfig
+	breakers map[circuitBreakerKey]*circuitBreaker
+	now      func() time.Time
+}
+
+var circuitBreakers = newCircuitBreakerRegistry()
+
+func newCircuitBreakerRegistry() *circuitBreakerRegistry {
+	return &circuitBreakerRegistry{
+		breakers: make(map[circuitBreakerKey]*circuitBreaker),
+		now:      time.Now,
+	}
+}
+
+// EnableCircuitBreaker enables client-side circuit breaking for all connections.
+// Breakers are kept per host and per endpoint class. A breaker opens after consecutive failures,
+// rejects requests with ErrCircuitBreakerOpen while open and lets probe requests through once
+// the open duration has passed. Calling it again resets all breakers.
+func EnableCircuitBreaker(cfg CircuitBreakerConfig) {
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = defaultCircuitBreakerFailureThreshold
+	}
+	if cfg.OpenDuration <= 0 {
+		cfg.OpenDuration = defaultCircuitBreakerOpenDuration
+	}
+	if cfg.HalfOpenProbes <= 0 {
+		cfg.HalfOpenProbes = defaultCircuitBreakerHalfOpenProbes
+	}
+	circuitBreakers.mu.Lock()
+	defer circuitBreakers.mu.Unlock()
+	circuitBreakers.enabled = true
+	circuitBreakers.cfg = cfg
+	circuitBreakers.breakers = make(map[circuitBreakerKey]*circuitBreaker)
+}
+
+// DisableCircuitBreaker disables client-side circuit breaking and drops all breakers.
+func DisableCircuitBreaker() {
+	circuitBreakers.mu.Lock()
+	defer circuitBreakers.mu.Unlock()
+	circuitBreakers.enabled = false
+	circuitBreakers.breakers = make(map[circuitBreakerKey]*circuitBreaker)
+}
+
+// GetCircuitBreakerStats returns a snapshot of all circuit breakers, sorted by host and endpoint.
+func GetCircuitBreakerStats() []CircuitBreakerStat {
+	circuitBreakers.mu.Lock()
+	breakers := make([]*circuitBreaker, 0, len(circuitBreakers.breakers))
+	for _, cb := range circuitBreakers.breakers {
+		breakers = append(breakers, cb)
+	}
+	circuitBreakers.mu.Unlock()
+
+	stats := make([]CircuitBreakerStat, 0, len(breakers))
+	for _, cb := range breakers {
+		stats = append(stats, cb.stat())
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Host != stats[j].Host {
+			return stats[i].Host < stats[j].Host
+		}
+		return stats[i].Endpoint < stats[j].Endpoint
+	})
+	return stats
+}
+
+// get returns the breaker for the host and endpoint, or nil if circuit breaking is disabled.
+func (reg *circuitBreakerRegistry) get(host string, endpoint CircuitBreakerEndpoint) *circuitBreaker {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	if !reg.enabled {
+		return nil
+	}
+	key := circuitBreakerKey{host: strings.ToLower(host), endpoint: endpoint}
+	cb, ok := reg.breakers[key]
+	if !ok {
+		cb = &circuitBreaker{key: key, cfg: reg.cfg, now: reg.now}
+		reg.breakers[key] = cb
+	}
+	return cb
+}
+
+// allow reports whether a request may be sent. Each allowed request must be followed by a call to record.
+func (cb *circuitBreaker) allow() error {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if cb.state == CircuitBreakerOpen && cb.now().Sub(cb.openedAt) >= cb.cfg.OpenDuration {
+		cb.state = CircuitBreakerHalfOpen
+		cb.probesInFlight = 0
+	}
+	switch cb.state {
+	case CircuitBreakerOpen:
+		cb.rejections++
+		return errCircuitBreakerOpen(cb.key)
+	case CircuitBreakerHalfOpen:
+		if cb.probesInFlight >= cb.cfg.HalfOpenProbes {
+			cb.rejections++
+			return errCircuitBreakerOpen(cb.key)
+		}
+		cb.probesInFlight++
+	}
+	return nil
+}
+
+// cancel releases an allowed request that was cancelled by the caller. As its outcome says nothing about the
+// endpoint, the state and the failure counter are left unchanged.
+func (cb *circuitBreaker) cancel() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if cb.state == CircuitBreakerHalfOpen && cb.probesInFlight > 0 {
+		cb.probesInFlight--
+	}
+}
+
+// record updates the breaker with the outcome of an allowed request.
+func (cb *circuitBreaker) record(failed bool) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if cb.state == CircuitBreakerHalfOpen && cb.probesInFlight > 0 {
+		cb.probesInFlight--
+	}
+	if !failed {
+		if cb.state != CircuitBreakerClosed {
+			logger.Infof("circuit breaker for %v requests to %v closed", cb.key.endpoint, cb.key.host)
+		}
+		cb.state = CircuitBreakerClosed
+		cb.consecutiveFailures = 0
+		return
+	}
+	cb.consecutiveFailures++
+	if cb.state == CircuitBreakerHalfOpen || (cb.state == CircuitBreakerClosed && cb.consecutiveFailures >= cb.cfg.FailureThreshold) {
+		logger.Warnf("circuit breaker for %v requests to %v opened after %v consecutive failures", cb.key.endpoint, cb.key.host, cb.consecutiveFailures)
+		cb.state = CircuitBreakerOpen
+		cb.openedAt = cb.now()
+	}
+}
+
+func (cb *circuitBreaker) stat() CircuitBreakerStat {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	state := cb.state
+	if state == CircuitBreakerOpen && cb.now().Sub(cb.openedAt) >= cb.cfg.OpenDuration {
+		state = CircuitBreakerHalfOpen
+	}
+	return CircuitBreakerStat{
+		Host:                cb.key.host,
+		Endpoint:            cb.key.endpoint,
+		State:               state,
+		ConsecutiveFailures: cb.consecutiveFailures,
+		Rejections:          cb.rejections,
+		OpenedAt:            cb.openedAt,
+	}
+}
+
+// circuitBreakerEndpointFor returns the endpoint class guarded for the request, if any.
+func circuitBreakerEndpointFor(kind RetryRequestKind, path string) (CircuitBreakerEndpoint, bool) {
+	switch {
+	case kind == RetryRequestKindLogin:
+		return CircuitBreakerEndpointLogin, true
+	case kind == RetryRequestKindQuery:
+		return CircuitBreakerEndpointQuery, true
+	case kind == RetryRequestKindChunk:
+		return CircuitBreakerEndpointChunkStorage, true
+	case strings.HasPrefix(path, monitoringQueriesPath):
+		return CircuitBreakerEndpointQueryMonitoring, true
+	default:
+		return 0, false
+	}
+}
+
+// isCircuitBreakerFailure reports whether the outcome of a request counts as an endpoint failure.
+func isCircuitBreakerFailure(res *http.Response, err error) bool {
+	if res == nil {
+		return err != nil
+	}
+	return isRetryableStatus(res.StatusCode)
+}
+
+func errCircuitBreakerOpen(key circuitBreakerKey) *SnowflakeError {
+	return &SnowflakeError{
+		Number:      ErrCircuitBreakerOpen,
+		SQLState:    SQLStateConnectionFailure,
+		Message:     errMsgCircuitBreakerOpen,
+		MessageArgs: []interface{}{key.endpoint, key.host},
+	}
+}
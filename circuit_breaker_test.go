@@ -0,0 +1,154 @@
+package gosnowflake
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerOpensAfterConsecutiveFailures(t *testing.T) {
+	now := time.Now()
+	cb := &circuitBreaker{
+		key: circuitBreakerKey{host: "test.snowflakecomputing.com", endpoint: CircuitBreakerEndpointQuery},
+		cfg: CircuitBreakerConfig{FailureThreshold: 3, OpenDuration: time.Minute, HalfOpenProbes: 1},
+		now: func() time.Time { return now },
+	}
+	for i := 0; i < 2; i++ {
+		assertNilF(t, cb.allow())
+		cb.record(true)
+	}
+	assertNilF(t, cb.allow())
+	cb.record(false)
+	assertEqualE(t, cb.stat().ConsecutiveFailures, 0, "success should reset the failure counter")
+
+	for i := 0; i < 3; i++ {
+		assertNilF(t, cb.allow())
+		cb.record(true)
+	}
+	assertEqualE(t, cb.stat().State, CircuitBreakerOpen)
+
+	err := cb.allow()
+	var se *SnowflakeError
+	assertErrorsAsF(t, err, &se)
+	assertEqualE(t, se.Number, ErrCircuitBreakerOpen)
+	assertEqualE(t, cb.stat().Rejections, int64(1))
+}
+
+func TestCircuitBreakerHalfOpenProbes(t *testing.T) {
+	now := time.Now()
+	cb := &circuitBreaker{
+		key: circuitBreakerKey{host: "test.snowflakecomputing.com", endpoint: CircuitBreakerEndpointLogin},
+		cfg: CircuitBreakerConfig{FailureThreshold: 1, OpenDuration: time.Minute, HalfOpenProbes: 1},
+		now: func() time.Time { return now },
+	}
+	assertNilF(t, cb.allow())
+	cb.record(true)
+	assertNotNilF(t, cb.allow())
+
+	now = now.Add(time.Minute)
+	assertEqualE(t, cb.stat().State, CircuitBreakerHalfOpen)
+	assertNilF(t, cb.allow(), "first probe should be allowed")
+	assertNotNilF(t, cb.allow(), "only one probe should be allowed at a time")
+	cb.record(true)
+	assertEqualE(t, cb.stat().State, CircuitBreakerOpen, "failed probe should open the breaker again")
+
+	now = now.Add(time.Minute)
+	assertNilF(t, cb.allow())
+	cb.record(false)
+	assertEqualE(t, cb.stat().State, CircuitBreakerClosed, "successful probe should close the breaker")
+	assertNilF(t, cb.allow())
+}
+
+func TestCircuitBreakerCancelledRequestKeepsState(t *testing.T) {
+	now := time.Now()
+	cb := &circuitBreaker{
+		key: circuitBreakerKey{host: "test.snowflakecomputing.com", endpoint: CircuitBreakerEndpointQuery},
+		cfg: CircuitBreakerConfig{FailureThreshold: 2, OpenDuration: time.Minute, HalfOpenProbes: 1},
+		now: func() time.Time { return now },
+	}
+	assertNilF(t, cb.allow())
+	cb.record(true)
+	assertNilF(t, cb.allow())
+	cb.cancel()
+	assertEqualE(t, cb.stat().ConsecutiveFailures, 1, "a cancelled request should not reset the failure counter")
+
+	assertNilF(t, cb.allow())
+	cb.record(true)
+	assertEqualF(t, cb.stat().State, CircuitBreakerOpen)
+
+	now = now.Add(time.Minute)
+	assertNilF(t, cb.allow())
+	cb.cancel()
+	assertEqualE(t, cb.stat().State, CircuitBreakerHalfOpen, "a cancelled probe should not close the breaker")
+	assertEqualE(t, cb.stat().ConsecutiveFailures, 2)
+	assertNilE(t, cb.allow(), "a cancelled probe should release its slot")
+}
+
+func TestCircuitBreakerCancelledRequestInRetryLoop(t *testing.T) {
+	EnableCircuitBreaker(CircuitBreakerConfig{FailureThreshold: 1, OpenDuration: time.Hour})
+	t.Cleanup(DisableCircuitBreaker)
+	cb := circuitBreakers.get("fakeaccountcircuitbreaker.snowflakecomputing.com:443", CircuitBreakerEndpointQuery)
+	cb.mu.Lock()
+	cb.state = CircuitBreakerHalfOpen
+	cb.consecutiveFailures = 3
+	cb.mu.Unlock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	client := &cancellingHTTPClient{cancel: cancel}
+	urlPtr, err := url.Parse("https://fakeaccountcircuitbreaker.snowflakecomputing.com:443/queries/v1/query-request?" + requestIDKey)
+	assertNilF(t, err, "failed to parse the test URL")
+	_, err = newRetryHTTP(ctx,
+		client,
+		emptyRequest, urlPtr, make(map[string]string), 60*time.Second, 10, defaultTimeProvider, nil).doPost().setBody([]byte{0}).execute()
+	assertNotNilF(t, err)
+
+	stats := GetCircuitBreakerStats()
+	assertEqualF(t, len(stats), 1)
+	assertEqualE(t, stats[0].State, CircuitBreakerHalfOpen)
+	assertEqualE(t, stats[0].ConsecutiveFailures, 3)
+}
+
+// cancellingHTTPClient cancels the context of the request it is sent, as a caller giving up would.
+type cancellingHTTPClient struct {
+	cancel context.CancelFunc
+}
+
+func (c *cancellingHTTPClient) Do(*http.Request) (*http.Response, error) {
+	c.cancel()
+	return nil, context.Canceled
+}
+
+func TestCircuitBreakerFailsFastInRetryLoop(t *testing.T) {
+	EnableCircuitBreaker(CircuitBreakerConfig{FailureThreshold: 2, OpenDuration: time.Hour})
+	t.Cleanup(DisableCircuitBreaker)
+
+	client := &fakeHTTPClient{
+		statusCode: http.StatusServiceUnavailable,
+		t:          t,
+	}
+	urlPtr, err := url.Parse("https://fakeaccountcircuitbreaker.snowflakecomputing.com:443/queries/v1/query-request?" + requestIDKey)
+	assertNilF(t, err, "failed to parse the test URL")
+	ctx := WithRetryPolicy(context.Background(), &recordingRetryPolicy{retry: true, wait: time.Millisecond})
+	_, err = newRetryHTTP(ctx,
+		client,
+		emptyRequest, urlPtr, make(map[string]string), 60*time.Second, 10, defaultTimeProvider, nil).doPost().setBody([]byte{0}).execute()
+	var se *SnowflakeError
+	assertTrueF(t, errors.As(err, &se), "expected a SnowflakeError")
+	assertEqualE(t, se.Number, ErrCircuitBreakerOpen)
+	assertEqualE(t, client.retryNumber, 2)
+
+	stats := GetCircuitBreakerStats()
+	assertEqualF(t, len(stats), 1)
+	assertEqualE(t, stats[0].Host, "fakeaccountcircuitbreaker.snowflakecomputing.com:443")
+	assertEqualE(t, stats[0].Endpoint, CircuitBreakerEndpointQuery)
+	assertEqualE(t, stats[0].State, CircuitBreakerOpen)
+	assertEqualE(t, stats[0].Rejections, int64(1))
+}
+
+func TestCircuitBreakerDisabledByDefault(t *testing.T) {
+	assertNilE(t, circuitBreakers.get("test.snowflakecomputing.com", CircuitBreakerEndpointQuery))
+	assertEqualE(t, len(GetCircuitBreakerStats()), 0)
+}
@@ -9,6 +9,7 @@ import (
 	"database/sql/driver"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -42,6 +43,7 @@ const (
 	statementTypeIDSelect           = int64(0x1000)
 	statementTypeIDDml              = int64(0x3000)
 	statementTypeIDMultiTableInsert = statementTypeIDDml + int64(0x500)
+	statementTypeIDTcl              = int64(0x5000)
 	statementTypeIDMultistatement   = int64(0xA000)
 )
 
@@ -59,6 +61,7 @@ const (
 	snowflakeResultType contextKey = "snowflakeResultType"
 	execResultType      resultType = "exec"
 	queryResultType     resultType = "query"
+	submitResultType    resultType = "submit" // async queries whose result is fetched later by query ID
 )
 
 type execKey string
@@ -81,6 +84,8 @@ type snowflakeConn struct {
 	internal            InternalClient
 	queryContextCache   *queryContextCache
 	currentTimeProvider currentTimeProvider
+	instr               *instrumentation
+	sessionState        sessionStateTracker
 }
 
 var (
@@ -95,8 +100,17 @@ func (sc *snowflakeConn) exec(
 	isInternal bool,
 	describeOnly bool,
 	bindings []driver.NamedValue) (
-	*execResponse, error) {
+	data *execResponse, err error) {
+	callerCtx := ctx
+	ctx, op := sc.instrumentation().startOperation(ctx, spanNameExec, "query")
+	defer func() {
+		if data != nil {
+			op.setAttributes(attrQueryID.String(data.Data.QueryID))
+		}
+		op.end(ctx, err)
+	}()
 	if sc.cfg.LogQueryText || isLogQueryTextEnabled(ctx) {
+		op.setAttributes(attrDBQuery.String(query))
 		if len(bindings) > 0 && (sc.cfg.LogQueryParameters || isLogQueryParametersEnabled(ctx)) {
 			logger.WithContext(ctx).Infof("Executing query: %v with bindings: %v", query, bindings)
 		} else {
@@ -106,7 +120,6 @@ func (sc *snowflakeConn) exec(
 		logger.WithContext(ctx).Infof("Executing query")
 	}
 
-	var err error
 	counter := atomic.AddUint64(&sc.SequenceCounter, 1) // query sequence counter
 	_, _, sessionID := safeGetTokens(sc.rest)
 	ctx = context.WithValue(ctx, SFSessionIDKey, sessionID)
@@ -133,6 +146,7 @@ func (sc *snowflakeConn) exec(
 
 	// handle bindings, if required
 	requestID := getOrGenerateRequestIDFromContext(ctx)
+	op.setAttributes(attrRequestID.String(requestID.String()), attrSessionID.Int64(sessionID))
 	if len(bindings) > 0 {
 		if err = sc.processBindings(ctx, bindings, describeOnly, requestID, &req); err != nil {
 			return nil, err
@@ -146,9 +160,9 @@ func (sc *snowflakeConn) exec(
 		headers[httpHeaderAccept] = headerContentTypeApplicationJSON
 	}
 
-	// propagate traceID and spanID via traceparent header. this is a no-op if invalid IDs
+	// propagate traceID and spanID of the caller via traceparent header. this is a no-op if invalid IDs
 	propagator := propagation.TraceContext{}
-	propagator.Inject(ctx, propagation.MapCarrier(headers))
+	propagator.Inject(callerCtx, propagation.MapCarrier(headers))
 
 	paramsMutex.Lock()
 	if serviceName, ok := sc.cfg.Params[serviceName]; ok {
@@ -161,8 +175,13 @@ func (sc *snowflakeConn) exec(
 		return nil, err
 	}
 
-	data, err := sc.rest.FuncPostQuery(ctx, sc.rest, &url.Values{}, headers,
+	release, err := sc.admit(ctx, op, isInternal)
+	if err != nil {
+		return nil, err
+	}
+	data, err = sc.rest.FuncPostQuery(ctx, sc.rest, &url.Values{}, headers,
 		jsonBody, sc.rest.RequestTimeout, requestID, sc.cfg)
+	release()
 	if err != nil {
 		return data, err
 	}
@@ -208,19 +227,10 @@ func (sc *snowflakeConn) exec(
 	}
 
 	logger.WithContext(ctx).Debugf("Exec/Query: queryId=%v SUCCESS with total=%v, returned=%v ", data.Data.QueryID, data.Data.Total, data.Data.Returned)
-	if data.Data.FinalDatabaseName != "" {
-		sc.cfg.Database = data.Data.FinalDatabaseName
-	}
-	if data.Data.FinalSchemaName != "" {
-		sc.cfg.Schema = data.Data.FinalSchemaName
-	}
-	if data.Data.FinalWarehouseName != "" {
-		sc.cfg.Warehouse = data.Data.FinalWarehouseName
-	}
-	if data.Data.FinalRoleName != "" {
-		sc.cfg.Role = data.Data.FinalRoleName
-	}
+	sc.updateSessionNames(&data.Data)
+	sc.trackSessionParameters(data.Data.Parameters)
 	sc.populateSessionParameters(data.Data.Parameters)
+	sc.trackTransaction(query, data.Data.StatementTypeID)
 	return data, err
 }
 
@@ -232,6 +242,11 @@ func extractQueryContext(data *execResponse) (queryContext, error) {
 
 func buildQueryContext(qcc *queryContextCache) (requestQueryContext, error) {
 	rqc := requestQueryContext{}
+	if qcc != nil && qcc.mutex != nil {
+		// queries can run concurrently on a session, see SessionMultiplexer
+		qcc.mutex.Lock()
+		defer qcc.mutex.Unlock()
+	}
 	if qcc == nil || len(qcc.entries) == 0 {
 		logger.Debugf("empty qcc")
 		return rqc, nil
@@ -295,6 +310,11 @@ func (sc *snowflakeConn) cleanup() {
 }
 
 func (sc *snowflakeConn) Close() (err error) {
+	sc.runOnClose()
+	return sc.close()
+}
+
+func (sc *snowflakeConn) close() (err error) {
 	logger.WithContext(sc.ctx).Infoln("Close")
 	if err := sc.telemetry.sendBatch(); err != nil {
 		logger.WithContext(sc.ctx).Warnf("error while sending telemetry. %v", err)
@@ -428,6 +448,17 @@ func (sc *snowflakeConn) queryContextInternal(
 	_, _, sessionID := safeGetTokens(sc.rest)
 	ctx = context.WithValue(setResultType(ctx, queryResultType), SFSessionIDKey, sessionID)
 	logger.WithContext(ctx).Debug("QueryContextInternal")
+	var cacheKey string
+	resultCache := sc.resultCacheFor(ctx, query)
+	if resultCache != nil {
+		cacheKey = sc.resultCacheKey(query, args)
+		if getResultCacheMode(ctx) != resultCacheRefresh {
+			if cached, ok := resultCache.get(ctx, cacheKey); ok {
+				logger.WithContext(ctx).Debugf("result of query %v served from the result cache", cached.QueryID)
+				return sc.rowsForResponse(ctx, *cached)
+			}
+		}
+	}
 	noResult := isAsyncMode(ctx)
 	isDesc := isDescribeOnly(ctx)
 	isInternal := isInternal(ctx)
@@ -453,22 +484,30 @@ func (sc *snowflakeConn) queryContextInternal(
 	if noResult {
 		return data.Data.AsyncRows, nil
 	}
+	if resultCache != nil && isCacheableResult(&data.Data) {
+		resultCache.put(ctx, cacheKey, &data.Data)
+	}
+	return sc.rowsForResponse(ctx, data.Data)
+}
+
+// rowsForResponse returns the rows of a query response.
+func (sc *snowflakeConn) rowsForResponse(ctx context.Context, data execResponseData) (driver.Rows, error) {
 
 	rows := new(snowflakeRows)
 	rows.sc = sc
-	rows.queryID = data.Data.QueryID
+	rows.queryID = data.QueryID
 	rows.ctx = ctx
 
-	if isMultiStmt(&data.Data) {
+	if isMultiStmt(&data) {
 		// handleMultiQuery is responsible to fill rows with childResults
-		if err = sc.handleMultiQuery(ctx, data.Data, rows); err != nil {
+		if err := sc.handleMultiQuery(ctx, data, rows); err != nil {
 			return nil, err
 		}
 	} else {
-		rows.addDownloader(populateChunkDownloader(ctx, sc, data.Data))
+		rows.addDownloader(populateChunkDownloader(ctx, sc, data))
 	}
 
-	err = rows.ChunkDownloader.start()
+	err := rows.ChunkDownloader.start()
 	return rows, err
 }
 
@@ -507,7 +546,7 @@ func (sc *snowflakeConn) Ping(ctx context.Context) error {
 // CheckNamedValue determines which types are handled by this driver aside from
 // the instances captured by driver.Value
 func (sc *snowflakeConn) CheckNamedValue(nv *driver.NamedValue) error {
-	if supportedNullBind(nv) || supportedDecfloatBind(nv) || supportedArrayBind(nv) || supportedStructuredObjectWriterBind(nv) || supportedStructuredArrayBind(nv) || supportedStructuredMapBind(nv) {
+	if supportedNullBind(nv) || supportedDecfloatBind(nv) || supportedArrayBind(nv) || supportedArrowBind(nv) || supportedStructuredObjectWriterBind(nv) || supportedStructuredArrayBind(nv) || supportedStructuredMapBind(nv) {
 		return nil
 	}
 	return driver.ErrSkip
@@ -532,6 +571,58 @@ func (sc *snowflakeConn) GetQueryStatus(
 	}, nil
 }
 
+// CancelQueryByID cancels a query of any session of the user with SYSTEM$CANCEL_QUERY, unlike the
+// cancellation of a context, which only aborts the requests of this connection.
+func (sc *snowflakeConn) CancelQueryByID(ctx context.Context, queryID string) (CancelQueryResult, error) {
+	if sc.rest == nil {
+		return "", driver.ErrBadConn
+	}
+	if sc.isQueryFinished(ctx, queryID) {
+		return QueryAlreadyFinished, nil
+	}
+	// the query to cancel may hold the admission slot the cancellation would wait for
+	bypassCtx := context.WithValue(ctx, admissionBypassKey, true)
+	if _, err := sc.exec(bypassCtx, "SELECT SYSTEM$CANCEL_QUERY(?)", false, false, false,
+		[]driver.NamedValue{{Ordinal: 1, Value: queryID}}); err != nil {
+		// the query may have finished in the meantime
+		if sc.isQueryFinished(ctx, queryID) {
+			return QueryAlreadyFinished, nil
+		}
+		if isQueryNotFoundError(err) {
+			return QueryNotFound, nil
+		}
+		return "", err
+	}
+	logger.WithContext(ctx).Infof("cancelled query %v", queryID)
+	return QueryCancelled, nil
+}
+
+// isQueryFinished returns true if the status of the query says it finished. A query without a status, e.g. because
+// it was just submitted or the status request failed, is not known to be finished.
+func (sc *snowflakeConn) isQueryFinished(ctx context.Context, queryID string) bool {
+	ret, err := sc.checkQueryStatus(ctx, queryID)
+	if ret == nil {
+		logger.WithContext(ctx).Debugf("failed to get the status of query %v: %v", queryID, err)
+		return false
+	}
+	return newAsyncQueryStatus(queryID, ret).Done
+}
+
+// isQueryNotFoundError returns true if SYSTEM$CANCEL_QUERY failed because the query does not exist or the user
+// is not allowed to see it.
+func isQueryNotFoundError(err error) bool {
+	var se *SnowflakeError
+	if !errors.As(err, &se) {
+		return false
+	}
+	if se.Number == ErrStatementNotFound || se.Number == ErrObjectNotExistOrAuthorized {
+		return true
+	}
+	msg := strings.ToLower(se.Message)
+	return strings.Contains(msg, "not found") || strings.Contains(msg, "does not exist") ||
+		strings.Contains(msg, "not authorized")
+}
+
 func (sc *snowflakeConn) AddTelemetryData(_ context.Context, eventDate time.Time, data map[string]string) error {
 	td := &telemetryData{
 		Timestamp: eventDate.UnixMilli(),
@@ -603,6 +694,7 @@ type ArrowStreamBatch struct {
 	scd     *snowflakeArrowStreamChunkDownloader
 	Loc     *time.Location
 	rr      io.ReadCloser
+	inline  []byte // first batch returned with the query response, nil for downloaded chunks
 }
 
 // NumRows returns the total number of rows that the metadata stated should
@@ -807,9 +899,10 @@ func (scd *snowflakeArrowStreamChunkDownloader) GetBatches() (out []ArrowStreamB
 	if len(rowSetBytes) > 0 {
 		out = out[:chunkMetaLen+1]
 		out[0] = ArrowStreamBatch{
-			scd: scd,
-			Loc: loc,
-			rr:  io.NopCloser(bytes.NewReader(rowSetBytes)),
+			scd:    scd,
+			Loc:    loc,
+			rr:     io.NopCloser(bytes.NewReader(rowSetBytes)),
+			inline: rowSetBytes,
 		}
 		toFill = out[1:]
 	}
@@ -885,6 +978,7 @@ func buildSnowflakeConn(ctx context.Context, config Config) (*snowflakeConn, err
 		cfg:                 &config,
 		queryContextCache:   (&queryContextCache{}).init(),
 		currentTimeProvider: defaultTimeProvider,
+		instr:               instrumentationFor(&config),
 	}
 	initPlatformDetection()
 	err := initEasyLogging(config.ClientConfigFile)
@@ -934,6 +1028,7 @@ func buildSnowflakeConn(ctx context.Context, config Config) (*snowflakeConn, err
 		LoginTimeout:        sc.cfg.LoginTimeout,
 		RequestTimeout:      sc.cfg.RequestTimeout,
 		MaxRetryCount:       sc.cfg.MaxRetryCount,
+		RetryPolicy:         sc.cfg.RetryPolicy,
 		FuncPost:            postRestful,
 		FuncGet:             getRestful,
 		FuncAuthPost:        postAuthRestful,
@@ -182,6 +182,8 @@ func handleSingleParam(cfg *Config, key string, value interface{}) error {
 		cfg.TmpDirPath, err = parseString(value)
 	case "disablequerycontextcache":
 		cfg.DisableQueryContextCache, err = parseBool(value)
+	case "restoresessionstate":
+		cfg.RestoreSessionState, err = parseBool(value)
 	case "includeretryreason":
 		cfg.IncludeRetryReason, err = parseConfigBool(value)
 	case "clientconfigfile":
@@ -330,7 +330,7 @@ func TestParseToml(t *testing.T) {
 		},
 		{
 			testParams: []string{"ocspFailOpen", "ocsp_fail_open", "insecureMode", "insecure_mode", "PasscodeInPassword", "passcode_in_password", "validateDEFAULTParameters", "validate_default_parameters",
-				"clientRequestMFAtoken", "client_request_mfa_token", "clientStoreTemporaryCredential", "client_store_temporary_credential", "disableQueryContextCache", "disable_query_context_cache", "disable_ocsp_checks",
+				"clientRequestMFAtoken", "client_request_mfa_token", "clientStoreTemporaryCredential", "client_store_temporary_credential", "disableQueryContextCache", "disable_query_context_cache", "restoreSessionState", "restore_session_state", "disable_ocsp_checks",
 				"includeRetryReason", "include_retry_reason", "disableConsoleLogin", "disable_console_login", "disableSamlUrlCheck", "disable_saml_url_check"},
 			values: []interface{}{true, "true", false, "false"},
 		},
@@ -374,7 +374,7 @@ func TestParseTomlWithWrongValue(t *testing.T) {
 		},
 		{
 			testParams: []string{"ocspFailOpen", "insecureMode", "PasscodeInPassword", "validateDEFAULTParameters", "clientRequestMFAtoken",
-				"clientStoreTemporaryCredential", "disableQueryContextCache", "includeRetryReason", "disableConsoleLogin", "disableSamlUrlCheck"},
+				"clientStoreTemporaryCredential", "disableQueryContextCache", "restoreSessionState", "includeRetryReason", "disableConsoleLogin", "disableSamlUrlCheck"},
 			values: []interface{}{"wrong_value", 1},
 		},
 	}
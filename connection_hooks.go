@@ -0,0 +1,51 @@
+package gosnowflake
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+)
+
+// ConnectionHook is called with a connection of the driver, set with Config.OnConnect and Config.OnClose.
+// The connection implements driver.ExecerContext, driver.QueryerContext and SnowflakeConnection, e.g.:
+//
+//	cfg.OnConnect = func(ctx context.Context, conn driver.Conn) error {
+//		_, err := conn.(driver.ExecerContext).ExecContext(ctx, "USE SECONDARY ROLES ALL", nil)
+//		return err
+//	}
+type ConnectionHook func(ctx context.Context, conn driver.Conn) error
+
+// initConnection runs the init statements and the OnConnect hook of the config on a new connection.
+// The state of the session afterwards is the one ResetSession restores.
+func (sc *snowflakeConn) initConnection(ctx context.Context) error {
+	if len(sc.cfg.InitStatements) == 0 && sc.cfg.OnConnect == nil {
+		return nil
+	}
+	_, _, sessionID := safeGetTokens(sc.rest)
+	ctx = context.WithValue(ctx, SFSessionIDKey, sessionID)
+	for _, stmt := range sc.cfg.InitStatements {
+		if _, err := sc.exec(ctx, stmt, false, false, false, nil); err != nil {
+			return fmt.Errorf("running init statement %q: %w", stmt, err)
+		}
+	}
+	if sc.cfg.OnConnect != nil {
+		if err := sc.cfg.OnConnect(ctx, sc); err != nil {
+			return fmt.Errorf("OnConnect hook: %w", err)
+		}
+	}
+	sc.updateInitialSessionState()
+	return nil
+}
+
+// runOnClose calls the OnClose hook of the config before the connection is closed. Its error is only logged,
+// as the connection is closed anyway.
+func (sc *snowflakeConn) runOnClose() {
+	if sc.cfg == nil || sc.cfg.OnClose == nil || sc.rest == nil {
+		return
+	}
+	_, _, sessionID := safeGetTokens(sc.rest)
+	ctx := context.WithValue(context.Background(), SFSessionIDKey, sessionID)
+	if err := sc.cfg.OnClose(ctx, sc); err != nil {
+		logger.WithContext(ctx).Warnf("OnClose hook failed. err: %v", err)
+	}
+}
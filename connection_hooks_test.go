@@ -0,0 +1,88 @@
+package gosnowflake
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestUnitInitConnection(t *testing.T) {
+	var queries []string
+	postQueryMock := func(_ context.Context, _ *snowflakeRestful, _ *url.Values, _ map[string]string, body []byte,
+		_ time.Duration, _ UUID, _ *Config) (*execResponse, error) {
+		var req execRequest
+		assertNilF(t, json.Unmarshal(body, &req))
+		queries = append(queries, req.SQLText)
+		data := execResponseData{QueryID: NewUUID().String()}
+		switch req.SQLText {
+		case "ALTER SESSION SET TIMEZONE = 'UTC'":
+			data.Parameters = []nameValueParameter{{Name: "TIMEZONE", Value: "UTC"}}
+		case "USE ROLE MISSING":
+			return &execResponse{Data: data, Code: "2043", Message: "Role 'MISSING' does not exist", Success: false}, nil
+		}
+		return &execResponse{Data: data, Success: true}, nil
+	}
+	timezone := "America/Los_Angeles"
+	var closedSessions int
+	newConn := func(cfg *Config) *snowflakeConn {
+		cfg.Params = map[string]*string{"timezone": &timezone}
+		cfg.Role = "SYSADMIN"
+		sc := &snowflakeConn{
+			cfg: cfg,
+			rest: &snowflakeRestful{
+				FuncPostQuery: postQueryMock,
+				FuncCloseSession: func(context.Context, *snowflakeRestful, time.Duration) error {
+					closedSessions++
+					return nil
+				},
+				TokenAccessor: getSimpleTokenAccessor(),
+			},
+			telemetry:           &snowflakeTelemetry{enabled: false},
+			queryContextCache:   (&queryContextCache{}).init(),
+			currentTimeProvider: defaultTimeProvider,
+		}
+		sc.setInitialSessionState(authResponseSessionInfo{RoleName: "SYSADMIN"})
+		return sc
+	}
+	ctx := context.Background()
+
+	var hookCalls []string
+	hook := func(name string) ConnectionHook {
+		return func(ctx context.Context, conn driver.Conn) error {
+			hookCalls = append(hookCalls, name)
+			_, err := conn.(driver.ExecerContext).ExecContext(ctx, "SET tag = '"+name+"'", nil)
+			return err
+		}
+	}
+	sc := newConn(&Config{
+		InitStatements: []string{"ALTER SESSION SET TIMEZONE = 'UTC'", "USE SECONDARY ROLES ALL"},
+		OnConnect:      hook("connect"),
+		OnClose:        hook("close"),
+	})
+	assertNilF(t, sc.initConnection(ctx))
+	assertDeepEqualE(t, queries, []string{"ALTER SESSION SET TIMEZONE = 'UTC'", "USE SECONDARY ROLES ALL", "SET tag = 'connect'"})
+	assertDeepEqualE(t, sc.resetSessionStatements(), []string(nil), "the init statements should not be reset")
+	assertDeepEqualE(t, sc.SessionState().Parameters, map[string]string{"TIMEZONE": "UTC"})
+
+	queries = nil
+	assertNilF(t, sc.Close())
+	assertDeepEqualE(t, hookCalls, []string{"connect", "close"})
+	assertDeepEqualE(t, queries, []string{"SET tag = 'close'"})
+	assertEqualE(t, closedSessions, 1)
+
+	queries = nil
+	sc = newConn(&Config{InitStatements: []string{"USE ROLE MISSING", "SELECT 1"}})
+	err := sc.initConnection(ctx)
+	var se *SnowflakeError
+	assertTrueF(t, errors.As(err, &se))
+	assertEqualE(t, se.Number, 2043)
+	assertDeepEqualE(t, queries, []string{"USE ROLE MISSING"})
+
+	hookErr := errors.New("hook failed")
+	sc = newConn(&Config{OnConnect: func(context.Context, driver.Conn) error { return hookErr }})
+	assertErrIsE(t, sc.initConnection(ctx), hookErr)
+}
@@ -1,12 +1,14 @@
 package gosnowflake
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
 	"database/sql/driver"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"math/big"
 
 	"net/http"
@@ -770,6 +772,86 @@ func TestGetInvalidQueryStatus(t *testing.T) {
 	})
 }
 
+func TestUnitCancelQueryByID(t *testing.T) {
+	var statuses []retStatus
+	var cancelled []string
+	var cancelErr *execResponse
+	postQueryMock := func(_ context.Context, _ *snowflakeRestful, _ *url.Values, _ map[string]string, body []byte,
+		_ time.Duration, _ UUID, _ *Config) (*execResponse, error) {
+		var req execRequest
+		assertNilF(t, json.Unmarshal(body, &req))
+		assertEqualE(t, req.SQLText, "SELECT SYSTEM$CANCEL_QUERY(?)")
+		cancelled = append(cancelled, req.Bindings["1"].Value.(string))
+		if cancelErr != nil {
+			return cancelErr, nil
+		}
+		msg := "query cancelled"
+		return &execResponse{Data: execResponseData{
+			QueryID:         "cancel",
+			StatementTypeID: statementTypeIDSelect,
+			RowType:         []execResponseRowType{{Name: "STATUS", Type: "text"}},
+			RowSet:          [][]*string{{&msg}},
+		}, Success: true}, nil
+	}
+	getMock := func(_ context.Context, _ *snowflakeRestful, _ *url.URL, _ map[string]string, _ time.Duration) (*http.Response, error) {
+		status := &statusResponse{Success: len(statuses) > 0}
+		if len(statuses) > 0 {
+			status.Data.Queries = statuses[:1]
+			statuses = statuses[1:]
+		}
+		b, err := json.Marshal(status)
+		assertNilF(t, err)
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewReader(b))}, nil
+	}
+	sc := &snowflakeConn{
+		cfg:                 &Config{Params: map[string]*string{}},
+		rest:                &snowflakeRestful{FuncPostQuery: postQueryMock, FuncGet: getMock, TokenAccessor: getSimpleTokenAccessor()},
+		telemetry:           &snowflakeTelemetry{enabled: false},
+		queryContextCache:   (&queryContextCache{}).init(),
+		currentTimeProvider: defaultTimeProvider,
+	}
+	var conn SnowflakeConnection = sc
+	ctx := context.Background()
+	cannotCancel := &execResponse{Data: execResponseData{QueryID: "cancel"},
+		Code: "90236", Message: "Cannot cancel query", Success: false}
+	notFound := &execResponse{Data: execResponseData{QueryID: "cancel"},
+		Code: "709", Message: "Statement query-1 not found", Success: false}
+	testcases := []struct {
+		name      string
+		statuses  []retStatus
+		cancelErr *execResponse
+		result    CancelQueryResult
+		cancelled bool
+	}{
+		{"running", []retStatus{{Status: "RUNNING"}}, nil, QueryCancelled, true},
+		{"succeeded", []retStatus{{Status: "SUCCESS"}}, nil, QueryAlreadyFinished, false},
+		{"failed", []retStatus{{Status: "FAILED_WITH_ERROR", ErrorCode: "100038"}}, nil, QueryAlreadyFinished, false},
+		{"just submitted without status", nil, nil, QueryCancelled, true},
+		{"not found", nil, notFound, QueryNotFound, true},
+		{"finished while cancelling", []retStatus{{Status: "RUNNING"}, {Status: "SUCCESS"}}, cannotCancel, QueryAlreadyFinished, true},
+	}
+	for _, test := range testcases {
+		t.Run(test.name, func(t *testing.T) {
+			statuses, cancelled, cancelErr = test.statuses, nil, test.cancelErr
+			result, err := conn.CancelQueryByID(ctx, "query-1")
+			assertNilF(t, err)
+			assertEqualE(t, result, test.result)
+			assertEqualE(t, len(cancelled) > 0, test.cancelled)
+		})
+	}
+
+	statuses, cancelErr = []retStatus{{Status: "RUNNING"}, {Status: "RUNNING"}}, cannotCancel
+	result, err := conn.CancelQueryByID(ctx, "query-1")
+	var se *SnowflakeError
+	assertTrueF(t, errors.As(err, &se))
+	assertEqualE(t, se.Number, 90236)
+	assertEqualE(t, result, CancelQueryResult(""))
+
+	statuses, cancelErr = nil, cannotCancel
+	_, err = conn.CancelQueryByID(ctx, "query-1")
+	assertNotNilE(t, err, "a failed cancellation of a query without status should not be reported as not found")
+}
+
 func TestExecWithServerSideError(t *testing.T) {
 	postQueryMock := func(_ context.Context, _ *snowflakeRestful,
 		_ *url.Values, _ map[string]string, _ []byte, _ time.Duration,
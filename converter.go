@@ -26,6 +26,7 @@ import (
 )
 
 const format = "2006-01-02 15:04:05.999999999"
+const formatWithOffset = format + " -07:00"
 const numberDefaultPrecision = 38
 const jsonFormatStr = "json"
 
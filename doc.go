@@ -173,6 +173,10 @@ The following connection parameters are supported:
   - disableQueryContextCache: disables parsing of query context returned from server and resending it to server as well.
     Default value is false.
 
+  - restoreSessionState: when set to true, the driver logs in again when the session is gone or its master token
+    expired, and replays the current role, warehouse, database, schema and altered session parameters on the new
+    session. Otherwise such a connection is discarded. Default value is false.
+
   - clientConfigFile: specifies the location of the client configuration json file.
     In this file you can configure Easy Logging feature.
 
@@ -269,6 +273,111 @@ It you wish to specify a custom transporter (e.g. to provide a custom TLS config
 
 As an alternative, you can use the `RegisterTLSConfig` / `DeregisterTLSConfig` functions as seen in the unit tests: https://github.com/snowflakedb/gosnowflake/blob/v1.16.0/transport_test.go#L127
 
+# Retry policy
+
+Failed HTTP requests are retried with jittered exponential backoff on connection errors
+and on HTTP 5xx, 408 and 429 responses. A custom RetryPolicy decides whether a failed
+attempt is retried and how long to wait before the retry. It receives the kind of request
+(login, query, result chunk, cloud storage or other), the attempt number, the HTTP status
+and the error. The policy can be set for all connections in Config.RetryPolicy or for a
+single call with WithRetryPolicy:
+
+	ctx := sf.WithRetryPolicy(context.Background(), failFastPolicy)
+	rows, err := db.QueryContext(ctx, "SELECT 1")
+
+MaxRetryCount, LoginTimeout and RequestTimeout are enforced regardless of the policy.
+DefaultRetryPolicy returns the default implementation, which custom policies can delegate to.
+
+# Circuit breaker
+
+A client-side circuit breaker can stop retry storms against an unhealthy account or endpoint.
+It is disabled by default and enabled for all connections with EnableCircuitBreaker:
+
+	sf.EnableCircuitBreaker(sf.CircuitBreakerConfig{
+		FailureThreshold: 5,
+		OpenDuration:     30 * time.Second,
+	})
+
+Breakers are kept per host and per endpoint class: login, query, query monitoring and
+result chunk storage. After FailureThreshold consecutive failures (connection errors and
+retryable HTTP statuses) the breaker opens and requests fail fast with ErrCircuitBreakerOpen.
+Once OpenDuration has passed, HalfOpenProbes requests are let through. A successful probe
+closes the breaker, a failed one opens it again. GetCircuitBreakerStats returns the current
+state of all breakers.
+
+# Failover
+
+For accounts using connection replication, NewFailoverConnector creates a connector trying an ordered list of
+configs, e.g. for the primary and secondary accounts or connection URLs:
+
+	connector, err := sf.NewFailoverConnector(sf.SnowflakeDriver{}, []sf.Config{primary, secondary}, sf.FailoverOptions{
+		ProbeInterval: time.Minute,
+		OnEvent: func(event sf.FailoverEvent) {
+			log.Printf("%v: endpoint %v (%v)", event.Type, event.Endpoint, event.Host)
+		},
+	})
+	db := sql.OpenDB(connector)
+
+New connections go to the first available endpoint. An endpoint whose login fails with an endpoint-level error
+(service unavailable, a 5xx response, a DNS, TLS or network error, or an open login circuit breaker) is skipped
+until ProbeInterval has passed, then the next new connection tries it again, so that connections fail back to the
+primary endpoint when it recovers. There is no background health check: an unavailable endpoint is only tried again
+by a new connection. Other login errors are returned without failing over. Open connections are not moved.
+A failed login is retried MaxLoginRetries times (2 by default) on an endpoint before the next one is tried.
+
+# Connection pool
+
+Connections implement driver.Validator and driver.SessionResetter, so that database/sql does not hand out connections
+whose session is gone. A connection is discarded instead of being returned to the pool when it is closed, its master
+token expired, or the heartbeat or a session renewal found its session gone. These checks do not make a round trip to
+Snowflake.
+
+Before a pooled connection is reused, a transaction left open by its previous user is rolled back, and the role,
+warehouse, database, schema and session parameters set at login are restored if they were changed, e.g. with USE or
+ALTER SESSION. To keep such changes across queries, run them on a *sql.Conn obtained with DB.Conn.
+
+Statements to run on every new connection, e.g. to set the time zone or the secondary roles, are set with
+Config.InitStatements. Config.OnConnect is called after them, and Config.OnClose before a connection is closed.
+The session state after the init statements and OnConnect is the one restored before a connection is reused.
+If an init statement or OnConnect fails, the connection is closed and the error is returned:
+
+	cfg.InitStatements = []string{"ALTER SESSION SET TIMEZONE = 'UTC'", "USE SECONDARY ROLES ALL"}
+	cfg.OnConnect = func(ctx context.Context, conn driver.Conn) error {
+		_, err := conn.(driver.ExecerContext).ExecContext(ctx, "ALTER SESSION SET QUERY_TAG = 'etl'", nil)
+		return err
+	}
+	db := sql.OpenDB(sf.NewConnector(sf.SnowflakeDriver{}, *cfg))
+
+# Admission control
+
+Many goroutines submitting queries to a small warehouse make Snowflake queue them, and their requests may time
+out while they are queued. To shape the load on the client instead, limit the number of queries running at once with
+an AdmissionController set on the Config. It can be shared by several connectors:
+
+	ac, err := sf.NewAdmissionController(sf.AdmissionControlConfig{
+		MaxInFlight:       8,
+		MaxInFlightPerKey: map[string]int{"REPORTING_WH": 2},
+		MaxQueued:         100,
+	})
+	...
+	cfg.AdmissionController = ac
+
+Queries are limited per current warehouse of the connection, in upper case, or per key set on the context with
+WithAdmissionKey, e.g. per tenant. Once MaxInFlight queries of a key are running, the next ones wait in a queue
+until a query of the key finishes. Waiting queries are started in arrival order, unless a priority is set with
+WithAdmissionPriority, in which case the ones with the highest priority start first. A query whose context is done
+while waiting returns the error of the context without being sent, and a query arriving when MaxQueued queries are
+waiting is rejected with ErrAdmissionQueueFull:
+
+	ctx, cancel := context.WithTimeout(sf.WithAdmissionPriority(ctx, 10), time.Minute)
+	defer cancel()
+	rows, err := db.QueryContext(sf.WithAdmissionKey(ctx, "tenant-42"), query)
+
+A query counts as running until the server returns its result, or until it is submitted for asynchronous queries.
+Internal queries of the driver and SnowflakeConnection.CancelQueryByID are not limited.
+AdmissionController.Stats returns the running and waiting queries of each key and the time the queries waited,
+which is also recorded in the snowflake.client.admission.wait_time metric.
+
 # Proxy
 
 The Go Snowflake Driver honors the environment variables HTTP_PROXY, HTTPS_PROXY and NO_PROXY for the forward proxy setting.
@@ -394,6 +503,26 @@ The result of your query can be retrieved by setting the query ID in the WithFet
 
 ```
 
+Fetching a large result can also be resumed from a checkpoint. ResumableRows.GetCursor returns
+a JSON serializable ResultCursor with the query ID, the chunk and the row after the last row
+returned by Next. Passing it to WithResumeCursor fetches the result by query ID starting from
+that position, without downloading the chunks before it:
+
+```
+
+	// while iterating, e.g. after each committed batch of rows
+	cursor, err := rows.(sf.ResumableRows).GetCursor()
+	checkpoint, err := json.Marshal(cursor)
+
+	// in another worker, after a failure
+	var cursor sf.ResultCursor
+	err = json.Unmarshal(checkpoint, &cursor)
+	rows, err := db.QueryContext(sf.WithResumeCursor(ctx, cursor), "")
+
+```
+
+Cursors are not supported with arrow batches, the stream downloader and multi-statement queries.
+
 # Canceling Query by CtrlC
 
 From 0.5.0, a signal handling responsibility has moved to the applications. If you want to cancel a
@@ -421,6 +550,19 @@ query/command by Ctrl+C, add a os.Interrupt trap in context to execute methods t
 
 See cmd/selectmany.go for the full example.
 
+Cancelling a context only aborts the queries issued through the connection using it. To cancel a query by its ID,
+e.g. a query submitted by another connection or process of the same user, call CancelQueryByID on the connection.
+The result tells whether the query was cancelled, had already finished, or was not found, which includes the
+queries the user is not permitted to see:
+
+	err := conn.Raw(func(x any) error {
+		result, err := x.(sf.SnowflakeConnection).CancelQueryByID(ctx, queryID)
+		if err == nil && result == sf.QueryNotFound {
+			log.Printf("query %v not found", queryID)
+		}
+		return err
+	})
+
 # OpenTelemetry headers
 
 A context containing OpenTelemetry headers for distributed tracing can be
@@ -436,6 +578,30 @@ For example:
 	defer parent_span.End()
 	rows, err := db.QueryContext(ctx, query)
 
+# OpenTelemetry tracing and metrics
+
+The driver creates client spans for query execution (snowflake.exec), logins
+(snowflake.authenticate), session renewals (snowflake.renew_session), result chunk
+downloads (snowflake.chunk_download) and file uploads (snowflake.file_upload).
+Spans are started from the context passed by the caller, so they are nested under
+the caller's span. They carry the query ID, request ID, session ID, retry count and
+transferred bytes as attributes, and each HTTP retry is recorded as a span event.
+The query text is attached only when query text logging is enabled.
+
+The following metrics are recorded:
+
+  - snowflake.client.operation.duration: histogram of operation latency in seconds, by operation
+  - snowflake.client.retries: counter of retried HTTP requests, by URL path and status code
+  - snowflake.client.transferred_bytes: counter of bytes moved from and to cloud storage
+  - snowflake.client.admission.wait_time: histogram of the time queries waited for Config.AdmissionController in seconds, by key
+
+The global OpenTelemetry tracer and meter providers are used by default. They can be
+overridden per connection with Config.TracerProvider and Config.MeterProvider:
+
+	cfg.TracerProvider = tracerProvider
+	cfg.MeterProvider = meterProvider
+	db := sql.OpenDB(sf.NewConnector(sf.SnowflakeDriver{}, *cfg))
+
 # Supported Data Types
 
 The Go Snowflake Driver now supports the Arrow data format for data transfers
@@ -882,6 +1048,21 @@ All values are returned as strings.
 Alternative approach is to rerun a query, but without enabling Arrow batches and use a general Go SQL API instead of driver API.
 It can be optimized by using `WithRequestID`, so backend returns results from cache.
 
+How to fetch Arrow streams on remote workers:
+
+When a query is run with `WithStreamDownloader`, the returned ArrowStreamLoader can serialize its batches with
+ArrowStreamBatchSerializer.SerializeBatches. Each serialized batch can be sent to another process, restored with DeserializeBatch and
+downloaded there with GetStream, without a Snowflake session:
+
+	batches, err := loader.(sf.ArrowStreamBatchSerializer).SerializeBatches()
+	...
+	// on a worker
+	batch, err := sf.DeserializeBatch(data, nil)
+	stream, err := batch.GetStream(ctx)
+
+A serialized batch contains the presigned chunk URL and the key needed to decrypt it, so it must be treated as a secret.
+It is valid only as long as the presigned URL, which typically expires after a few hours.
+
 # Binding Parameters
 
 Binding allows a SQL statement to use a value that is stored in a Golang variable.
@@ -986,9 +1167,46 @@ the CREATE TEMPORARY STAGE command executed by the driver can fail with the foll
 	CREATE TEMPORARY STAGE SYSTEM$BIND file_format=(type=csv field_optionally_enclosed_by='"')
 	Cannot perform CREATE STAGE. This session does not have a current schema. Call 'USE SCHEMA', or use a qualified name.
 
+Data held in Arrow can be inserted by binding a single arrow.Record or array.RecordReader
+with one column per placeholder. Its rows are written to the temporary stage directly from
+the Arrow arrays, regardless of the threshold:
+
+	_, err = db.ExecContext(ctx, "insert into my_table values (?, ?, ?)", recordReader)
+
 For alternative ways to load data into the Snowflake database (including bulk loading using the COPY command),
 see Loading Data into Snowflake (https://docs.snowflake.com/en/user-guide-data-load.html).
 
+# Bulk loading
+
+A BulkLoader loads rows into a table without going through bind parameters. Rows are written to
+CSV or Parquet files in memory, uploaded to a temporary stage once they reach BulkLoaderOptions.FileSize
+and loaded with COPY INTO when Flush is called. Flush returns the result of COPY INTO for every file.
+A row is either a []any or a struct, whose exported fields are mapped to columns by name or `sf` tag:
+
+	type event struct {
+		ID      int64
+		Payload string `sf:"payload"`
+	}
+
+	err := conn.Raw(func(x any) error {
+		loader, err := x.(sf.BulkLoadConnection).NewBulkLoader(ctx, "events", &sf.BulkLoaderOptions{
+			Format: sf.BulkLoadFormatParquet,
+		})
+		if err != nil {
+			return err
+		}
+		for _, e := range events {
+			if err = loader.Append(e); err != nil {
+				return err
+			}
+		}
+		results, err := loader.Flush()
+		...
+	})
+
+The same CREATE STAGE privilege and current schema as for batch inserts are required.
+A BulkLoader must be used only inside the Raw callback of the connection which created it.
+
 # Binding a Parameter to a Time Type
 
 Go's database/sql package supports the ability to bind a parameter in a SQL statement to a time.Time variable.
@@ -1055,6 +1273,21 @@ memory footprint by itself. Consider Custom JSON Decoder.
 	)
 	sf.MaxChunkDownloadWorkers = 2
 
+The number of chunks downloaded ahead of the application can also be adjusted per query. With
+WithChunkDownloadConcurrency the driver measures the download throughput and moves the number of chunk downloaders
+between the given minimum and maximum, downloading fewer chunks ahead when the application reads slowly.
+WithResultMemoryLimit limits the uncompressed size of chunks being downloaded or waiting to be read:
+
+	ctx := sf.WithResultMemoryLimit(sf.WithChunkDownloadConcurrency(ctx, 1, 16), 512*1024*1024)
+	rows, err := db.QueryContext(ctx, query)
+
+If the application reads results slower than they are downloaded, WithResultSpill makes the driver write chunks
+to the directory set in TmpDirPath once the chunks kept in memory reach the given size. Spilled chunks are encrypted
+with a key that is never written to disk, read back when the application reaches them and removed when the rows
+are closed:
+
+	ctx := sf.WithResultSpill(ctx, 256*1024*1024)
+
 Custom JSON Decoder for Parsing Result Set (Experimental)
 
 The application may have the driver use a custom JSON decoder that incrementally parses the result set as follows.
@@ -1069,6 +1302,36 @@ This option will reduce the memory footprint to half or even quarter, but it can
 performance depending on the environment. The test cases running on Travis Ubuntu box show five times less memory
 footprint while four times slower. Be cautious when using the option.
 
+# Result cache
+
+Applications running the same SELECT queries repeatedly, such as dashboards, may answer them from a client-side
+cache instead of the server. The cache is opt-in and set on the Config; it can be shared by several connectors:
+
+	cache, err := sf.NewResultCache(sf.ResultCacheConfig{
+		MaxEntries: 500,
+		MaxBytes:   128 * 1024 * 1024,
+		TTL:        time.Minute,
+	})
+	...
+	cfg.ResultCache = cache
+
+Results are keyed by the SQL text with white space normalized, the bind values, and the account, user, role,
+warehouse, database, schema and session parameters of the connection, so that a result is never returned to a
+session that could see different data. Only SELECT results returned entirely in the query response are cached;
+results downloaded in chunks, multi-statement and asynchronous queries are always run on the server. The cache
+does not know about changes made to the underlying tables, so pick a TTL the application can tolerate.
+
+By default the results are kept in memory. If ResultCacheConfig.Dir is set, they are written to files in a new
+directory inside Dir, encrypted with a key that is never written to disk. The directory is removed by
+ResultCache.Close.
+
+A query can skip the cache with WithResultCacheBypass, or be run on the server with its result replacing the cached
+one with WithResultCacheRefresh:
+
+	rows, err := db.QueryContext(sf.WithResultCacheRefresh(ctx), query)
+
+ResultCache.Stats returns the hits, misses, evictions and size of the cache, and ResultCache.Clear empties it.
+
 # JWT authentication
 
 The Go Snowflake Driver supports JWT (JSON Web Token) authentication.
@@ -1234,6 +1497,16 @@ If you want to execute a mix of query and non-query statements (e.g. a mix of SE
 multi-statement query, use QueryContext(). You can retrieve the result sets for the queries,
 and you can retrieve or ignore the row counts for the non-query statements.
 
+A stream whose file name does not exist locally is read into memory before it is uploaded. To upload it in parts
+while it is read instead, set the `StreamUpload` file transfer option. Memory is then bounded by the part size
+(`MultiPartThreshold`, at least 5 MB on S3 and 8 MB on Azure) times the PUT parallelism. GCS receives the stream
+in a single chunked request. A failed streamed upload is not retried, because the stream cannot be read again,
+and S3 and GCS files uploaded this way have no digest metadata:
+
+	ctx := WithFileTransferOptions(context.Background(), &SnowflakeFileTransferOptions{StreamUpload: true})
+	ctx = WithFileStream(ctx, reader)
+	db.ExecContext(ctx, "PUT file:///tmp/data.csv @~ auto_compress=true parallel=4")
+
 Note: PUT statements are not supported for multi-statement queries.
 
 If a SQL statement passed to ExecQuery() or QueryContext() fails to compile or execute, that statement is
@@ -1259,6 +1532,55 @@ example:
 
 Preparing statements and using bind variables are also not supported for multi-statement queries.
 
+Results of each statement:
+
+QueryContext() and ExecContext() combine the results of the statements. To get the query ID, statement type,
+rows affected or rows, start and end time, and error of each statement, call ExecMultiStatement on the connection.
+The number of statements is taken from WithMultiStatement and defaults to any number. The rows of all statements
+must be closed with Close:
+
+	err := conn.Raw(func(x any) error {
+		res, err := x.(sf.MultiStatementConnection).ExecMultiStatement(ctx, script, nil, nil)
+		if err != nil {
+			return err
+		}
+		defer res.Close()
+		for _, stmt := range res.Statements {
+			fmt.Printf("%v: %v rows affected in %v\n", stmt.QueryID, stmt.RowsAffected, stmt.Elapsed())
+		}
+		return nil
+	})
+
+A multi-statement query stops at the first failed statement. Its error is returned together with the result, which
+holds the failed statement and, when the server reports them, the statements that ran before it. With MultiStatementOptions.ContinueOnError, the driver
+splits the query at the semicolons outside of literals, comments, $$ blocks and Snowflake Scripting BEGIN ... END
+blocks, and runs the statements one at a time in the session, so that every statement runs even if some fail. The error of each failed statement is in
+StatementResult.Err, and MultiStatementResult.Err returns the first one. In this mode, positional ? bind variables
+are supported and are passed to the statements in order:
+
+	res, err := x.(sf.MultiStatementConnection).ExecMultiStatement(ctx, script, nil,
+		&sf.MultiStatementOptions{ContinueOnError: true})
+
+Running SQL scripts:
+
+ExecScript runs a SQL script, such as a schema migration, one statement at a time in a single session of a
+sql.DB. The script is split like with ContinueOnError, so the number of statements does not need to be known.
+By default the script stops at the first failed statement, and the results of the statements that ran are
+returned along with its error. Like in SnowSQL, &name and &{name} are replaced by the values of variables, if
+ScriptOptions.Variables is set:
+
+	f, err := os.Open("V42__add_orders.sql")
+	...
+	res, err := sf.ExecScript(ctx, db, f, &sf.ScriptOptions{
+		Variables: map[string]string{"schema": "analytics"},
+	})
+	if err != nil {
+		log.Fatalf("migration failed: %v", err)
+	}
+	for _, stmt := range res.Statements {
+		log.Printf("%v: %v", stmt.QueryID, stmt.SQLText)
+	}
+
 # Asynchronous Queries
 
 The Go Snowflake Driver supports asynchronous execution of SQL statements.
@@ -1375,6 +1697,54 @@ The behaviour is also dependent on ABORT_DETACHED_QUERY parameter, please see th
 
 As a consequence, best practice would be to isolate all long-running async tasks (especially ones supposed to be continued after the connection is closed) into a separate connection.
 
+==> Following asynchronous queries from another process
+
+WithAsyncMode ties the query to the rows or result returned by the connection. To follow a long-running query
+from another connection or process, for instance in a workflow engine, submit it with SubmitQuery. The returned
+AsyncQuery can be marshaled to JSON and stored, and used later with any connection of the same user to Poll the
+status of the query, Wait for it with backoff, Cancel it or fetch its Results:
+
+	conn, err := db.Conn(ctx)
+	...
+	q, err := sf.SubmitQuery(ctx, conn, "INSERT INTO sales SELECT * FROM staged_sales WHERE day = ?", day)
+	handle, err := json.Marshal(q)
+
+	// hours later, in another process
+	var q sf.AsyncQuery
+	err = json.Unmarshal(handle, &q)
+	status, err := q.Wait(ctx, conn)
+	if err != nil {
+		// the query failed, or its status could not be retrieved
+	}
+	rows, err := q.Results(ctx, conn)
+
+Set Config.KeepSessionAlive on the connection submitting the queries, so that they keep running after it is closed.
+
+# Concurrent queries on one session
+
+A Snowflake session can run several queries at once, while database/sql runs one query at a time per connection.
+To run concurrent queries without the login and heartbeat of a connection per query, use a SessionMultiplexer
+inside sql.Conn.Raw. Each query has its own request ID and result downloader, and at most maxConcurrent queries
+run at once:
+
+	err := conn.Raw(func(x any) error {
+		mux := x.(sf.SessionMultiplexerConnection).SessionMultiplexer(4)
+		var wg sync.WaitGroup
+		for _, query := range queries {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				res, err := mux.ExecContext(ctx, query)
+				...
+			}()
+		}
+		wg.Wait()
+		return nil
+	})
+
+The multiplexer must not be used after the function passed to Raw returns. As the queries share the session,
+transactions and USE or ALTER SESSION statements apply to all of them.
+
 # Support For PUT and GET
 
 The Go Snowflake Driver supports the PUT and GET commands.
@@ -1424,6 +1794,14 @@ To send information from a stream (rather than a file) use code similar to the c
 
 Note: PUT statements are not supported for multi-statement queries.
 
+With auto_compress=true, files and streams are compressed with gzip by default. Zstandard or Brotli can be chosen
+with the `AutoCompression` file transfer option, which is usually much faster than gzip:
+
+	ctx := WithFileTransferOptions(context.Background(), &SnowflakeFileTransferOptions{AutoCompression: "ZSTD"})
+	db.ExecContext(ctx, "PUT file:///tmp/my_data_file.csv @~ auto_compress=true")
+
+Brotli files are not detected by COMPRESSION=AUTO, so COPY INTO needs COMPRESSION=BROTLI to load them.
+
 Using GET:
 
 The following example shows how to run a GET command by passing a string to the
@@ -1474,6 +1852,47 @@ If you wish to ignore those errors instead, you can set `RaisePutGetError: false
 	ctx := WithFileTransferOptions(context.Background(), &SnowflakeFileTransferOptions{RaisePutGetError: false})
 	db.ExecContext(ctx, "PUT ...")
 
+Progress and cancellation:
+
+To follow the progress of every file of a PUT or GET, set a `TransferProgressListener` in the `ProgressListener`
+file transfer option. It is notified when a file starts, when bytes are sent or received, when the file is retried,
+skipped, finished or failed. Files are transferred in parallel, so the listener is called from several goroutines:
+
+	ctx := WithFileTransferOptions(context.Background(), &SnowflakeFileTransferOptions{ProgressListener: listener})
+	db.ExecContext(ctx, "PUT file:///tmp/data/*.csv @~ parallel=8")
+
+Cancelling the context of the PUT or GET aborts the cloud storage requests in flight, and no new files are started.
+An S3 multipart upload that was cancelled is aborted, so its uploaded parts are not kept. Azure discards uncommitted
+blocks by itself.
+
+Testing without a stage:
+
+The fakestage package starts a stub server which answers PUT and GET with a stage in a local directory, so tests
+can run file transfers end-to-end, with compression and encryption, without a Snowflake account or cloud storage:
+
+	srv, err := fakestage.New(t.TempDir())
+	...
+	defer srv.Close()
+	db := sql.OpenDB(gosnowflake.NewConnector(gosnowflake.SnowflakeDriver{}, *srv.Config()))
+	db.Exec("PUT file:///tmp/data/*.csv @mystage")
+
+The driver uses the local stage of the server only when it is connected to a loopback address.
+
+# Unit testing
+
+The sftest package starts an in-process stand-in for Snowflake, to unit test code using the driver through
+database/sql without an account. Register a canned response for each statement pattern, with JSON or Arrow rows,
+a number of affected rows, an error, or a number of polls during which the query is still running:
+
+	srv := sftest.NewServer()
+	defer srv.Close()
+	srv.Handle(`^INSERT INTO users`, sftest.Response{RowsAffected: 1}).ExpectBindings(1, "alice")
+	db := sql.OpenDB(gosnowflake.NewConnector(gosnowflake.SnowflakeDriver{}, *srv.Config()))
+
+Statements bound to other values than expected fail. All statements received, with their bindings, are
+available with `Server.Queries`. `Server.HandleFunc` computes the response from the statement instead; the
+fakestage package is built this way, answering PUT and GET with a `Response.Transfer`.
+
 # Minicore (Native Library)
 
 The Go Snowflake Driver includes an embedded native library called "minicore" that verifies loading of native Rust extensions on various platforms. By default, minicore is enabled and loaded dynamically at runtime.
@@ -92,6 +92,13 @@ func (d SnowflakeDriver) OpenWithConfig(ctx context.Context, config Config) (dri
 
 	sc.startHeartBeat()
 	sc.internal = &httpClient{sr: sc.rest}
+	if err = sc.initConnection(ctx); err != nil {
+		logger.WithContext(ctx).Errorf("Failed to initialize the connection, closing it. err: %v", err)
+		if closeErr := sc.close(); closeErr != nil {
+			logger.WithContext(ctx).Warnf("failed to close the connection. err: %v", closeErr)
+		}
+		return nil, err
+	}
 	// Check context before returning since connectionTelemetry doesn't handle cancellation
 	if ctx.Err() != nil {
 		return nil, ctx.Err()
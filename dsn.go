@@ -16,6 +16,9 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
@@ -108,6 +111,11 @@ type Config struct {
 	// Deprecated: timeouts may be reorganized in a future release.
 	CloudStorageTimeout time.Duration // Timeout for a single call to a cloud storage provider
 	MaxRetryCount       int           // Specifies how many times non-periodic HTTP request can be retried
+	RetryPolicy         RetryPolicy   // Decides which failed requests are retried and how long to wait. The default policy is used if not set.
+
+	ResultCache *ResultCache // Opt-in client-side cache of query results. Results are not cached if not set.
+
+	AdmissionController *AdmissionController // Opt-in client-side limit of the queries running at once per warehouse. Queries are not limited if not set.
 
 	Application       string // application name.
 	DisableOCSPChecks bool   // driver doesn't check certificate revocation status
@@ -125,6 +133,9 @@ type Config struct {
 
 	Transporter http.RoundTripper // RoundTripper to intercept HTTP requests and responses
 
+	TracerProvider trace.TracerProvider // OpenTelemetry tracer provider. The global provider is used if not set.
+	MeterProvider  metric.MeterProvider // OpenTelemetry meter provider. The global provider is used if not set.
+
 	tlsConfig     *tls.Config // Custom TLS configuration
 	TLSConfigName string      // Name of the TLS config to use
 
@@ -149,6 +160,12 @@ type Config struct {
 
 	DisableQueryContextCache bool // Should HTAP query context cache be disabled
 
+	RestoreSessionState bool // Should the driver log in again when the session is gone or its master token expired, and replay the session state
+
+	InitStatements []string       // Statements run on every new connection after login, e.g. ALTER SESSION SET TIMEZONE = 'UTC'
+	OnConnect      ConnectionHook // Called on every new connection after the init statements. The connection is closed if it fails.
+	OnClose        ConnectionHook // Called before a connection is closed
+
 	IncludeRetryReason ConfigBool // Should retried request contain retry reason
 
 	ClientConfigFile string // File path to the client configuration json file
@@ -423,6 +440,9 @@ func DSN(cfg *Config) (dsn string, err error) {
 	if cfg.DisableQueryContextCache {
 		params.Add("disableQueryContextCache", "true")
 	}
+	if cfg.RestoreSessionState {
+		params.Add("restoreSessionState", "true")
+	}
 	if cfg.IncludeRetryReason == ConfigBoolFalse {
 		params.Add("includeRetryReason", "false")
 	}
@@ -1127,6 +1147,13 @@ func parseDSNParams(cfg *Config, params string) (err error) {
 				return
 			}
 			cfg.DisableQueryContextCache = b
+		case "restoreSessionState":
+			var b bool
+			b, err = strconv.ParseBool(value)
+			if err != nil {
+				return
+			}
+			cfg.RestoreSessionState = b
 		case "includeRetryReason":
 			var vv bool
 			vv, err = strconv.ParseBool(value)
@@ -92,10 +92,17 @@ func encryptStreamCBC(
 		}
 	}
 
+	return wrapFileKey(sfe, kek, fileKey, dataIv)
+}
+
+// wrapFileKey encrypts the file key with the stage master key and returns
+// the metadata stored along with the encrypted file.
+func wrapFileKey(sfe *snowflakeFileEncryption, kek []byte, fileKey []byte, dataIv []byte) (*encryptMetadata, error) {
 	// encrypt key with ECB
-	fileKey = padBytesLength(fileKey, block.BlockSize())
+	keySize := len(kek)
+	fileKey = padBytesLength(fileKey, aes.BlockSize)
 	encryptedFileKey := make([]byte, len(fileKey))
-	if err = encryptECB(encryptedFileKey, fileKey, kek); err != nil {
+	if err := encryptECB(encryptedFileKey, fileKey, kek); err != nil {
 		return nil, err
 	}
 
@@ -116,6 +123,62 @@ func encryptStreamCBC(
 	}, nil
 }
 
+// cbcEncryptWriter encrypts what is written to it the same way as
+// encryptStreamCBC. Close writes the final padded block.
+type cbcEncryptWriter struct {
+	out     io.Writer
+	mode    cipher.BlockMode
+	pending []byte
+}
+
+// newCBCEncryptWriter returns a writer encrypting to out with a new file key.
+// Unlike encryptStreamCBC, the metadata is known before any data is written.
+func newCBCEncryptWriter(sfe *snowflakeFileEncryption, out io.Writer) (*cbcEncryptWriter, *encryptMetadata, error) {
+	kek, err := base64.StdEncoding.DecodeString(sfe.QueryStageMasterKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	fileKey := getSecureRandom(len(kek))
+	block, err := aes.NewCipher(fileKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	dataIv := getSecureRandom(block.BlockSize())
+	encryptMeta, err := wrapFileKey(sfe, kek, fileKey, dataIv)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &cbcEncryptWriter{
+		out:  out,
+		mode: cipher.NewCBCEncrypter(block, dataIv),
+	}, encryptMeta, nil
+}
+
+func (w *cbcEncryptWriter) Write(p []byte) (int, error) {
+	w.pending = append(w.pending, p...)
+	full := len(w.pending) - len(w.pending)%aes.BlockSize
+	if full == 0 {
+		return len(p), nil
+	}
+	cipherText := make([]byte, full)
+	w.mode.CryptBlocks(cipherText, w.pending[:full])
+	w.pending = append(w.pending[:0], w.pending[full:]...)
+	if _, err := w.out.Write(cipherText); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close encrypts the remaining bytes with PKCS5 padding. It does not close
+// the underlying writer.
+func (w *cbcEncryptWriter) Close() error {
+	last := padBytesLength(w.pending, aes.BlockSize)
+	w.mode.CryptBlocks(last, last)
+	w.pending = nil
+	_, err := w.out.Write(last)
+	return err
+}
+
 func encryptECB(encrypted []byte, fileKey []byte, decodedKey []byte) error {
 	block, err := aes.NewCipher(decodedKey)
 	if err != nil {
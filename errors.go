@@ -147,6 +147,10 @@ const (
 	ErrMissingAccessATokenButRefreshTokenPresent = 260018
 	// ErrCodeMissingTLSConfig is an error code for the case where the TLS config is missing.
 	ErrCodeMissingTLSConfig = 260019
+	// ErrCodeEmptyFailoverConfigs is an error code for the case where a failover connector is created without configs.
+	ErrCodeEmptyFailoverConfigs = 260020
+	// ErrCodeInvalidAdmissionLimit is an error code for the case where an admission controller is created without a positive limit.
+	ErrCodeInvalidAdmissionLimit = 260021
 
 	/* network */
 
@@ -172,6 +176,12 @@ const (
 	ErrFailedToGetExternalBrowserResponse = 261009
 	// ErrFailedToHeartbeat is an error code when a heartbeat fails.
 	ErrFailedToHeartbeat = 261010
+	// ErrCircuitBreakerOpen is an error code for the case where a request was rejected by an open circuit breaker.
+	ErrCircuitBreakerOpen = 261011
+	// ErrAdmissionQueueFull is an error code for the case where a query was rejected because the admission queue of its key was full.
+	ErrAdmissionQueueFull = 261012
+	// ErrRetryTimeout is an error code for the case where an HTTP request still failed with an error status when its retries ran out.
+	ErrRetryTimeout = 261013
 
 	/* rows */
 
@@ -179,6 +189,8 @@ const (
 	ErrFailedToGetChunk = 262000
 	// ErrNonArrowResponseInArrowBatches is an error code for case where ArrowBatches mode is enabled, but response is not Arrow-based
 	ErrNonArrowResponseInArrowBatches = 262001
+	// ErrInvalidResultCursor is an error code for the case where a result cursor cannot be used to resume fetching a result
+	ErrInvalidResultCursor = 262002
 
 	/* transaction*/
 
@@ -220,6 +232,8 @@ const (
 	ErrBindSerialization = 265001
 	// ErrBindUpload is an error code for the uploading process of bind elements to the stage
 	ErrBindUpload = 265002
+	// ErrBulkLoad is an error code for an invalid row or option passed to a BulkLoader
+	ErrBulkLoad = 265003
 
 	/* async */
 
@@ -230,6 +244,10 @@ const (
 
 	// ErrNoResultIDs is an error code for empty result IDs for multi statement queries
 	ErrNoResultIDs = 267001
+	// ErrMultiStatementBindings is an error code for bind variables that can not be split across the statements of a query
+	ErrMultiStatementBindings = 267002
+	// ErrScriptVariable is an error code for a reference to an undefined variable in a script
+	ErrScriptVariable = 267003
 
 	/* converter */
 
@@ -277,6 +295,8 @@ const (
 	ErrRoleNotExist = 390189
 	// ErrObjectNotExistOrAuthorized is a GS error code for the case that the server-side object specified does not exist
 	ErrObjectNotExistOrAuthorized = 390201
+	// ErrStatementNotFound is a GS error code for the case that the query specified does not exist or is not visible to the user
+	ErrStatementNotFound = 709
 )
 
 const (
@@ -317,6 +337,8 @@ const (
 	errMsgInternalNotMatchEncryptMaterial    = "number of downloading files doesn't match the encryption materials. files=%v, encmat=%v"
 	errMsgFailedToConvertToS3Client          = "failed to convert interface to s3 client"
 	errMsgNoResultIDs                        = "no result IDs returned with the multi-statement query"
+	errMsgScriptVariable                     = "variable %v is not defined"
+	errMsgMultiStatementBindings             = "one positional bind value is required per ? bind variable, got %v values for %v variables"
 	errMsgQueryStatus                        = "server ErrorCode=%s, ErrorMessage=%s"
 	errMsgInvalidPadding                     = "invalid padding on input"
 	errMsgClientConfigFailed                 = "client configuration failed: %v"
@@ -328,6 +350,13 @@ const (
 	errMsgInvalidExecutablePermissionToFile  = "file '%v' is executable — this poses a security risk because the file could be misused as a script or executed unintentionally. Your Permission: %v"
 	errMsgNonArrowResponseInArrowBatches     = "arrow batches enabled, but the response is not Arrow based"
 	errMsgMissingTLSConfig                   = "TLS config not found: %v"
+	errMsgCircuitBreakerOpen                 = "circuit breaker is open for %v requests to %v"
+	errMsgEmptyFailoverConfigs               = "failover connector requires at least one config"
+	errMsgInvalidAdmissionLimit              = "admission controller requires a positive MaxInFlight, got %v"
+	errMsgAdmissionQueueFull                 = "admission queue of %q is full with %v queries"
+	errMsgRetryTimeout                       = "timeout after %[2]v and %[3]v attempts. HTTP Status: %[1]v. Hanging?"
+	errMsgBulkLoad                           = "bulk load failed: %v"
+	errMsgInvalidResultCursor                = "invalid result cursor: %v"
 )
 
 // Returned if a DNS doesn't include account parameter.
@@ -409,6 +438,15 @@ func errNullValueInMap() *SnowflakeError {
 	}
 }
 
+func errInvalidResultCursor(queryID string, reason string) *SnowflakeError {
+	return &SnowflakeError{
+		QueryID:     queryID,
+		Number:      ErrInvalidResultCursor,
+		Message:     errMsgInvalidResultCursor,
+		MessageArgs: []interface{}{reason},
+	}
+}
+
 func errNonArrowResponseForArrowBatches(queryID string) *SnowflakeError {
 	return &SnowflakeError{
 		QueryID: queryID,
@@ -0,0 +1,258 @@
+package gosnowflake
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"database/sql/driver"
+	"errors"
+	"net"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	defaultFailoverProbeInterval   = 30 * time.Second
+	defaultFailoverMaxLoginRetries = 2
+)
+
+// FailoverOptions configures a FailoverConnector.
+type FailoverOptions struct {
+	// ProbeInterval is how long an unavailable endpoint is skipped before a new connection is tried on it again.
+	// Default is 30s.
+	ProbeInterval time.Duration
+	// MaxLoginRetries is how many times a failed login is retried on an endpoint before the next endpoint is tried,
+	// instead of retrying until LoginTimeout. Default is 2.
+	MaxLoginRetries int
+	// OnEvent is called when an endpoint becomes unavailable or available again, and when new connections move
+	// to another endpoint. It is called synchronously from Connect and should return quickly.
+	OnEvent func(FailoverEvent)
+}
+
+// FailoverEventType is the type of a FailoverEvent.
+type FailoverEventType int
+
+const (
+	// FailoverEndpointUnavailable is emitted when connecting to an endpoint failed with an endpoint-level error.
+	FailoverEndpointUnavailable FailoverEventType = iota
+	// FailoverEndpointAvailable is emitted when connecting to an unavailable endpoint succeeded again.
+	FailoverEndpointAvailable
+	// FailoverEndpointSwitched is emitted when new connections move to another endpoint, on failover or failback.
+	FailoverEndpointSwitched
+)
+
+func (t FailoverEventType) String() string {
+	switch t {
+	case FailoverEndpointUnavailable:
+		return "endpoint unavailable"
+	case FailoverEndpointAvailable:
+		return "endpoint available"
+	case FailoverEndpointSwitched:
+		return "endpoint switched"
+	default:
+		return "unknown"
+	}
+}
+
+// FailoverEvent is emitted by a FailoverConnector.
+type FailoverEvent struct {
+	Type FailoverEventType
+	// Endpoint is the index of the config of the endpoint in the list given to NewFailoverConnector,
+	// and Host its host. For FailoverEndpointSwitched, they are the endpoint new connections moved to.
+	Endpoint int
+	Host     string
+	// PreviousEndpoint is the index of the endpoint new connections moved from, for FailoverEndpointSwitched.
+	PreviousEndpoint int
+	// Err is the error of the failed connection, for FailoverEndpointUnavailable.
+	Err  error
+	Time time.Time
+}
+
+type failoverEndpoint struct {
+	cfg         Config
+	unavailable bool
+	nextProbe   time.Time // when the unavailable endpoint is tried again
+}
+
+// FailoverConnector is a driver.Connector connecting to the first available of an ordered list of endpoints, e.g.
+// the primary and secondary accounts of a replicated connection, or connection URLs. Endpoints whose login failed
+// with an endpoint-level error, i.e. service unavailable, a 5xx response, a DNS, TLS or network error, or an open
+// circuit breaker, are skipped for new connections until their probe interval has passed. Then a new connection is
+// tried on them again, in the order of the list, so that connections fail back to the primary endpoint once it is
+// available again. There is no background health check: failback is only checked lazily, by the first Connect after
+// the probe interval. Other errors, e.g. wrong credentials, are returned without trying the next endpoint.
+// Connections already open are not moved.
+type FailoverConnector struct {
+	driver    InternalSnowflakeDriver
+	options   FailoverOptions
+	mu        sync.Mutex
+	endpoints []*failoverEndpoint
+	active    int
+	now       func() time.Time
+}
+
+// NewFailoverConnector creates a connector failing over between the endpoints of the configs, in order of preference.
+func NewFailoverConnector(driver InternalSnowflakeDriver, configs []Config, options FailoverOptions) (*FailoverConnector, error) {
+	if len(configs) == 0 {
+		return nil, &SnowflakeError{
+			Number:  ErrCodeEmptyFailoverConfigs,
+			Message: errMsgEmptyFailoverConfigs,
+		}
+	}
+	if options.ProbeInterval <= 0 {
+		options.ProbeInterval = defaultFailoverProbeInterval
+	}
+	if options.MaxLoginRetries <= 0 {
+		options.MaxLoginRetries = defaultFailoverMaxLoginRetries
+	}
+	c := &FailoverConnector{driver: driver, options: options, now: time.Now}
+	for _, cfg := range configs {
+		if err := fillMissingConfigParameters(&cfg); err != nil {
+			return nil, err
+		}
+		c.endpoints = append(c.endpoints, &failoverEndpoint{cfg: cfg})
+	}
+	return c, nil
+}
+
+// Connect creates a new connection to the first available endpoint.
+func (c *FailoverConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	var errs []error
+	for _, i := range c.candidates() {
+		c.mu.Lock()
+		cfg := c.endpoints[i].cfg
+		c.mu.Unlock()
+		loginCtx := WithRetryPolicy(ctx, &failoverLoginRetryPolicy{
+			RetryPolicy: retryPolicyFor(ctx, cfg.RetryPolicy),
+			maxRetries:  c.options.MaxLoginRetries,
+		})
+		conn, err := c.driver.OpenWithConfig(loginCtx, cfg)
+		if err == nil {
+			c.connected(i)
+			return conn, nil
+		}
+		if ctx.Err() != nil || !isEndpointFailure(err) {
+			return nil, err
+		}
+		logger.WithContext(ctx).Warnf("failed to connect to endpoint %v (%v), failing over. err: %v", i, cfg.Host, err)
+		c.failed(i, err)
+		errs = append(errs, err)
+	}
+	return nil, errors.Join(errs...)
+}
+
+// Driver returns the driver of the connector.
+func (c *FailoverConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// ActiveEndpoint returns the index of the endpoint of the last new connection.
+func (c *FailoverConnector) ActiveEndpoint() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.active
+}
+
+// candidates returns the endpoints to try in order: the available ones and the ones due for a probe in the order
+// of the list, then the other unavailable ones, the earliest to be probed first.
+func (c *FailoverConnector) candidates() []int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	var candidates, skipped []int
+	for i, ep := range c.endpoints {
+		if ep.unavailable && now.Before(ep.nextProbe) {
+			skipped = append(skipped, i)
+		} else {
+			candidates = append(candidates, i)
+		}
+	}
+	sort.SliceStable(skipped, func(a, b int) bool {
+		return c.endpoints[skipped[a]].nextProbe.Before(c.endpoints[skipped[b]].nextProbe)
+	})
+	return append(candidates, skipped...)
+}
+
+func (c *FailoverConnector) connected(i int) {
+	c.mu.Lock()
+	ep := c.endpoints[i]
+	now := c.now()
+	var events []FailoverEvent
+	if ep.unavailable {
+		ep.unavailable = false
+		events = append(events, FailoverEvent{Type: FailoverEndpointAvailable, Endpoint: i, Host: ep.cfg.Host, Time: now})
+	}
+	if c.active != i {
+		events = append(events, FailoverEvent{Type: FailoverEndpointSwitched, Endpoint: i, Host: ep.cfg.Host, PreviousEndpoint: c.active, Time: now})
+		c.active = i
+	}
+	c.mu.Unlock()
+	c.emit(events...)
+}
+
+func (c *FailoverConnector) failed(i int, err error) {
+	c.mu.Lock()
+	ep := c.endpoints[i]
+	now := c.now()
+	ep.nextProbe = now.Add(c.options.ProbeInterval)
+	var events []FailoverEvent
+	if !ep.unavailable {
+		ep.unavailable = true
+		events = append(events, FailoverEvent{Type: FailoverEndpointUnavailable, Endpoint: i, Host: ep.cfg.Host, Err: err, Time: now})
+	}
+	c.mu.Unlock()
+	c.emit(events...)
+}
+
+func (c *FailoverConnector) emit(events ...FailoverEvent) {
+	if c.options.OnEvent == nil {
+		return
+	}
+	for _, event := range events {
+		c.options.OnEvent(event)
+	}
+}
+
+// failoverLoginRetryPolicy stops retrying logins after maxRetries, so that an unavailable endpoint is given up on
+// before LoginTimeout.
+type failoverLoginRetryPolicy struct {
+	RetryPolicy
+	maxRetries int
+}
+
+func (p *failoverLoginRetryPolicy) ShouldRetry(attempt RetryAttempt) bool {
+	if attempt.Kind == RetryRequestKindLogin && attempt.Attempt > p.maxRetries {
+		return false
+	}
+	return p.RetryPolicy.ShouldRetry(attempt)
+}
+
+// isEndpointFailure returns true if a login failed because the endpoint is not reachable or not available,
+// rather than because of the credentials or the request.
+func isEndpointFailure(err error) bool {
+	var se *SnowflakeError
+	if errors.As(err, &se) {
+		switch se.Number {
+		case ErrCodeServiceUnavailable, ErrCircuitBreakerOpen:
+			return true
+		case ErrFailedToAuth, ErrRetryTimeout:
+			// the HTTP status code of the response is the first message argument
+			if len(se.MessageArgs) > 0 {
+				status, ok := se.MessageArgs[0].(int)
+				return ok && status >= 500
+			}
+		}
+		return false
+	}
+	var dnsErr *net.DNSError
+	var opErr *net.OpError
+	var certErr *tls.CertificateVerificationError
+	var recordErr tls.RecordHeaderError
+	var authorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+	return errors.As(err, &dnsErr) || errors.As(err, &opErr) || errors.As(err, &certErr) ||
+		errors.As(err, &recordErr) || errors.As(err, &authorityErr) || errors.As(err, &hostnameErr) ||
+		errors.As(err, &invalidErr) || errors.Is(err, context.DeadlineExceeded)
+}
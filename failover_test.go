@@ -0,0 +1,210 @@
+package gosnowflake
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type failoverTestDriver struct {
+	SnowflakeDriver
+	errs  map[string]error
+	hosts []string
+}
+
+func (d *failoverTestDriver) OpenWithConfig(_ context.Context, cfg Config) (driver.Conn, error) {
+	d.hosts = append(d.hosts, cfg.Host)
+	if err := d.errs[cfg.Host]; err != nil {
+		return nil, err
+	}
+	return &snowflakeConn{cfg: &cfg}, nil
+}
+
+func TestUnitFailoverConnector(t *testing.T) {
+	unavailable := &SnowflakeError{Number: ErrCodeServiceUnavailable, Message: errMsgServiceUnavailable}
+	dnsErr := &url.Error{Op: "Post", URL: "https://secondary", Err: &net.DNSError{Err: "no such host", Name: "secondary"}}
+	drv := &failoverTestDriver{errs: map[string]error{}}
+	var events []FailoverEvent
+	configs := []Config{
+		{Account: "a", User: "u", Password: "p", Host: "primary.snowflakecomputing.com"},
+		{Account: "a", User: "u", Password: "p", Host: "secondary.snowflakecomputing.com"},
+	}
+	c, err := NewFailoverConnector(drv, configs, FailoverOptions{
+		ProbeInterval: time.Minute,
+		OnEvent:       func(event FailoverEvent) { events = append(events, event) },
+	})
+	assertNilF(t, err)
+	now := time.Now()
+	c.now = func() time.Time { return now }
+	ctx := context.Background()
+	connect := func() string {
+		drv.hosts = nil
+		conn, err := c.Connect(ctx)
+		assertNilF(t, err)
+		return conn.(*snowflakeConn).cfg.Host
+	}
+
+	assertEqualE(t, connect(), "primary.snowflakecomputing.com")
+	assertEqualE(t, len(events), 0)
+
+	drv.errs["primary.snowflakecomputing.com"] = unavailable
+	assertEqualE(t, connect(), "secondary.snowflakecomputing.com")
+	assertEqualE(t, c.ActiveEndpoint(), 1)
+	assertEqualF(t, len(events), 2)
+	assertEqualE(t, events[0].Type, FailoverEndpointUnavailable)
+	assertEqualE(t, events[0].Endpoint, 0)
+	assertErrIsE(t, events[0].Err, unavailable)
+	assertEqualE(t, events[1].Type, FailoverEndpointSwitched)
+	assertEqualE(t, events[1].PreviousEndpoint, 0)
+	assertEqualE(t, events[1].Host, "secondary.snowflakecomputing.com")
+
+	assertEqualE(t, connect(), "secondary.snowflakecomputing.com")
+	assertDeepEqualE(t, drv.hosts, []string{"secondary.snowflakecomputing.com"}, "the unavailable primary should be skipped")
+
+	now = now.Add(2 * time.Minute)
+	assertEqualE(t, connect(), "secondary.snowflakecomputing.com")
+	assertDeepEqualE(t, drv.hosts, []string{"primary.snowflakecomputing.com", "secondary.snowflakecomputing.com"}, "the primary should be probed")
+	assertEqualE(t, len(events), 2, "an endpoint still unavailable should not emit events again")
+
+	now = now.Add(2 * time.Minute)
+	delete(drv.errs, "primary.snowflakecomputing.com")
+	events = nil
+	assertEqualE(t, connect(), "primary.snowflakecomputing.com")
+	assertEqualF(t, len(events), 2)
+	assertEqualE(t, events[0].Type, FailoverEndpointAvailable)
+	assertEqualE(t, events[1].Type, FailoverEndpointSwitched)
+	assertEqualE(t, events[1].Endpoint, 0)
+
+	drv.errs["primary.snowflakecomputing.com"] = unavailable
+	drv.errs["secondary.snowflakecomputing.com"] = dnsErr
+	drv.hosts = nil
+	_, err = c.Connect(ctx)
+	assertErrIsE(t, err, unavailable)
+	var se *net.DNSError
+	assertTrueE(t, errors.As(err, &se))
+	_, err = c.Connect(ctx)
+	assertNotNilE(t, err)
+	assertEqualE(t, len(drv.hosts), 4, "unavailable endpoints should be tried when all are unavailable")
+
+	authErr := &SnowflakeError{Number: 390100, Message: "Incorrect username or password was specified."}
+	now = now.Add(2 * time.Minute)
+	drv.errs["primary.snowflakecomputing.com"] = authErr
+	drv.hosts = nil
+	_, err = c.Connect(ctx)
+	assertErrIsE(t, err, authErr)
+	assertDeepEqualE(t, drv.hosts, []string{"primary.snowflakecomputing.com"}, "login errors should not fail over")
+
+	_, err = NewFailoverConnector(drv, nil, FailoverOptions{})
+	assertEqualE(t, err.(*SnowflakeError).Number, ErrCodeEmptyFailoverConfigs)
+}
+
+func TestUnitFailoverConnectorOnServiceUnavailableLogin(t *testing.T) {
+	var primaryLogins atomic.Int32
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == loginRequestPath {
+			primaryLogins.Add(1)
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer primary.Close()
+	secondary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == loginRequestPath {
+			_, _ = w.Write([]byte(`{"data": {"token": "t", "masterToken": "m", "sessionId": 1, "parameters": []}, "success": true}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"success": true}`))
+	}))
+	defer secondary.Close()
+
+	configFor := func(srv *httptest.Server, maxRetryCount int) Config {
+		u, err := url.Parse(srv.URL)
+		assertNilF(t, err)
+		port, err := strconv.Atoi(u.Port())
+		assertNilF(t, err)
+		return Config{
+			Account:          "a",
+			User:             "u",
+			Password:         "p",
+			Host:             u.Hostname(),
+			Port:             port,
+			Protocol:         "http",
+			LoginTimeout:     time.Minute,
+			MaxRetryCount:    maxRetryCount,
+			RetryPolicy:      &recordingRetryPolicy{retry: true, wait: time.Millisecond},
+			DisableTelemetry: true,
+		}
+	}
+
+	t.Run("retries capped by the connector", func(t *testing.T) {
+		primaryLogins.Store(0)
+		var events []FailoverEvent
+		c, err := NewFailoverConnector(SnowflakeDriver{}, []Config{configFor(primary, 0), configFor(secondary, 0)}, FailoverOptions{
+			MaxLoginRetries: 1,
+			OnEvent:         func(event FailoverEvent) { events = append(events, event) },
+		})
+		assertNilF(t, err)
+		start := time.Now()
+		conn, err := c.Connect(context.Background())
+		assertNilF(t, err)
+		defer conn.Close()
+		assertTrueE(t, time.Since(start) < 10*time.Second, "failover should not wait for LoginTimeout")
+		assertEqualE(t, primaryLogins.Load(), int32(2), "the login should be retried once on the primary")
+		assertEqualE(t, c.ActiveEndpoint(), 1)
+		assertEqualF(t, len(events), 2)
+		var se *SnowflakeError
+		assertErrorsAsF(t, events[0].Err, &se)
+		assertEqualE(t, se.Number, ErrCodeServiceUnavailable)
+	})
+
+	t.Run("retries exhausted by MaxRetryCount", func(t *testing.T) {
+		primaryLogins.Store(0)
+		var events []FailoverEvent
+		c, err := NewFailoverConnector(SnowflakeDriver{}, []Config{configFor(primary, 1), configFor(secondary, 1)}, FailoverOptions{
+			MaxLoginRetries: 5,
+			OnEvent:         func(event FailoverEvent) { events = append(events, event) },
+		})
+		assertNilF(t, err)
+		conn, err := c.Connect(context.Background())
+		assertNilF(t, err)
+		defer conn.Close()
+		assertEqualE(t, primaryLogins.Load(), int32(2))
+		assertEqualE(t, c.ActiveEndpoint(), 1)
+		assertEqualF(t, len(events), 2)
+		var se *SnowflakeError
+		assertErrorsAsF(t, events[0].Err, &se)
+		assertEqualE(t, se.Number, ErrRetryTimeout)
+		assertEqualE(t, se.MessageArgs[0], http.StatusServiceUnavailable)
+	})
+}
+
+func TestUnitIsEndpointFailure(t *testing.T) {
+	testcases := []struct {
+		err     error
+		failure bool
+	}{
+		{&SnowflakeError{Number: ErrCodeServiceUnavailable}, true},
+		{&SnowflakeError{Number: ErrCircuitBreakerOpen}, true},
+		{&SnowflakeError{Number: ErrFailedToAuth, MessageArgs: []any{500, "url"}}, true},
+		{&SnowflakeError{Number: ErrFailedToAuth, MessageArgs: []any{400, "url"}}, false},
+		{&SnowflakeError{Number: ErrRetryTimeout, MessageArgs: []any{503, time.Minute, 3}}, true},
+		{&SnowflakeError{Number: ErrRetryTimeout, MessageArgs: []any{429, time.Minute, 3}}, false},
+		{&SnowflakeError{Number: ErrCodeFailedToConnect, MessageArgs: []any{403, "url"}}, false},
+		{&url.Error{Err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}}, true},
+		{&url.Error{Err: context.DeadlineExceeded}, true},
+		{errors.New("invalid private key"), false},
+	}
+	for _, test := range testcases {
+		t.Run(test.err.Error(), func(t *testing.T) {
+			assertEqualE(t, isEndpointFailure(test.err), test.failure)
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package gosnowflake
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// fakeStageMetadataSuffix is appended to the name of a fake stage file to
+// store its digest and encryption metadata, like cloud storage object metadata.
+const fakeStageMetadataSuffix = ".sfcmeta"
+
+// snowflakeFakeStageClient stores stage files in a local directory. It is
+// returned for the FAKESTAGE location type, which only the fakestage test
+// server uses, so PUT and GET go through the same compression, encryption
+// and retry code as cloud stages. The location type is rejected unless the
+// driver is connected to a loopback address.
+type snowflakeFakeStageClient struct {
+	cfg       *Config
+	telemetry *snowflakeTelemetry
+}
+
+type fakeStageMetadata struct {
+	Digest        string `json:"digest"`
+	ContentLength int64  `json:"contentLength"`
+	Key           string `json:"key,omitempty"`
+	Iv            string `json:"iv,omitempty"`
+	Matdesc       string `json:"matdesc,omitempty"`
+}
+
+func (util *snowflakeFakeStageClient) createClient(_ *execResponseStageInfo, _ bool, _ *snowflakeTelemetry) (cloudClient, error) {
+	return nil, nil
+}
+
+func (util *snowflakeFakeStageClient) path(meta *fileMetadata, filename string) (string, error) {
+	location, err := expandUser(meta.stageInfo.Location)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(location, filepath.FromSlash(strings.TrimLeft(filename, "/"))), nil
+}
+
+// cloudUtil implementation
+func (util *snowflakeFakeStageClient) getFileHeader(meta *fileMetadata, filename string) (*fileHeader, error) {
+	path, err := util.path(meta, filename)
+	if err != nil {
+		return nil, err
+	}
+	b, err := os.ReadFile(path + fakeStageMetadataSuffix)
+	if errors.Is(err, os.ErrNotExist) {
+		meta.resStatus = notFoundFile
+		return nil, errors.New("could not find file")
+	} else if err != nil {
+		meta.resStatus = errStatus
+		meta.lastError = err
+		return nil, err
+	}
+	var m fakeStageMetadata
+	if err = json.Unmarshal(b, &m); err != nil {
+		meta.resStatus = errStatus
+		meta.lastError = err
+		return nil, err
+	}
+	meta.resStatus = uploaded
+	return &fileHeader{
+		m.Digest,
+		m.ContentLength,
+		&encryptMetadata{m.Key, m.Iv, m.Matdesc},
+	}, nil
+}
+
+// cloudUtil implementation
+func (util *snowflakeFakeStageClient) uploadFile(
+	dataFile string,
+	meta *fileMetadata,
+	_ int,
+	_ int64) error {
+	var src io.Reader
+	if meta.uploadStream != nil {
+		src = meta.uploadStream
+	} else if meta.srcStream != nil {
+		src = cmp.Or(meta.realSrcStream, meta.srcStream)
+	} else {
+		f, err := os.Open(dataFile)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err = f.Close(); err != nil {
+				logger.Warnf("failed to close %v file: %v", dataFile, err)
+			}
+		}()
+		src = f
+	}
+	path, err := util.path(meta, meta.dstFileName)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	out, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, readWriteFileMode)
+	if err != nil {
+		return err
+	}
+	n, err := io.Copy(out, meta.progressReader(src))
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		meta.lastError = err
+		meta.resStatus = needRetry
+		return err
+	}
+
+	// written last, like object metadata that becomes visible with the object
+	m := fakeStageMetadata{
+		Digest:        meta.sha256Digest,
+		ContentLength: n,
+	}
+	if meta.encryptMeta != nil {
+		m.Key = meta.encryptMeta.key
+		m.Iv = meta.encryptMeta.iv
+		m.Matdesc = meta.encryptMeta.matdesc
+	}
+	b, err := json.Marshal(&m)
+	if err != nil {
+		return err
+	}
+	if err = os.WriteFile(path+fakeStageMetadataSuffix, b, readWriteFileMode); err != nil {
+		return err
+	}
+	meta.dstFileSize = meta.uploadSize
+	meta.resStatus = uploaded
+	return nil
+}
+
+// cloudUtil implementation
+func (util *snowflakeFakeStageClient) nativeDownloadFile(
+	meta *fileMetadata,
+	fullDstFileName string,
+	_ int64,
+	_ int64) error {
+	path, err := util.path(meta, meta.srcFileName)
+	if err != nil {
+		return err
+	}
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err = in.Close(); err != nil {
+			logger.Warnf("failed to close %v file: %v", path, err)
+		}
+	}()
+	if meta.options != nil && meta.options.GetFileToStream {
+		if _, err = meta.dstStream.ReadFrom(meta.progressReader(in)); err != nil {
+			return err
+		}
+	} else {
+		out, err := os.OpenFile(fullDstFileName, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, readWriteFileMode)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(out, meta.progressReader(in))
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return err
+		}
+	}
+	meta.resStatus = downloaded
+	return nil
+}
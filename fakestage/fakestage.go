@@ -0,0 +1,488 @@
+// Package fakestage runs PUT and GET commands of the Snowflake driver against
+// a directory on disk, without a Snowflake account or cloud storage.
+//
+// A Server is an sftest.Server with handlers for the stage commands. It
+// answers PUT and GET with a stage location in its directory, so the driver
+// compresses, encrypts, uploads and downloads files with the same code as
+// for a real stage:
+//
+//	srv, err := fakestage.New(t.TempDir())
+//	if err != nil {
+//		t.Fatal(err)
+//	}
+//	defer srv.Close()
+//	db := sql.OpenDB(gosnowflake.NewConnector(gosnowflake.SnowflakeDriver{}, *srv.Config()))
+//	_, err = db.Exec("PUT file:///tmp/data.csv @mystage/path")
+//
+// Every stage is a subdirectory of the server directory, named after the
+// stage: ~ for the user stage, %TABLE for a table stage and the upper case
+// stage name otherwise. Database and schema qualifiers are ignored.
+// Each file is stored with a .sfcmeta file holding its digest and encryption
+// metadata.
+//
+// PUT, GET, LIST and REMOVE are supported. Other statements succeed without
+// doing anything and are recorded, see Server.Statements.
+package fakestage
+
+import (
+	"cmp"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/snowflakedb/gosnowflake"
+	"github.com/snowflakedb/gosnowflake/sftest"
+)
+
+const (
+	// LocationType is the stage location type the server returns for PUT and GET.
+	LocationType = "FAKESTAGE"
+	// MetadataSuffix is the suffix of the file stored with each stage file.
+	MetadataSuffix = ".sfcmeta"
+
+	sqlStateSyntaxError = "42000"
+	errCodeSyntaxError  = 1003
+	errCodeInternal     = 603
+)
+
+// Server is a stub Snowflake server storing stage files in a directory.
+type Server struct {
+	dir       string
+	encrypt   bool
+	masterKey string
+	srv       *sftest.Server
+	other     *sftest.Handler
+}
+
+// Option configures a Server.
+type Option func(*Server)
+
+// WithoutEncryption stores stage files unencrypted, like a stage created
+// with ENCRYPTION = (TYPE = 'SNOWFLAKE_SSE'). By default files are encrypted
+// on the client with a random master key.
+func WithoutEncryption() Option {
+	return func(s *Server) {
+		s.encrypt = false
+	}
+}
+
+// New starts a Server storing stage files in dir. Close it when done.
+func New(dir string, opts ...Option) (*Server, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	if err = os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	key := make([]byte, 16)
+	if _, err = rand.Read(key); err != nil {
+		return nil, err
+	}
+	s := &Server{
+		dir:       dir,
+		encrypt:   true,
+		masterKey: base64.StdEncoding.EncodeToString(key),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.srv = sftest.NewServer()
+	s.srv.HandleFunc(`^\s*PUT\b`, s.put)
+	s.srv.HandleFunc(`^\s*GET\b`, s.get)
+	s.srv.HandleFunc(`^\s*(LIST|LS)\b`, s.list)
+	s.srv.HandleFunc(`^\s*(REMOVE|RM)\b`, s.remove)
+	s.other = s.srv.Handle(`.*`, sftest.Response{
+		Columns: []sftest.Column{{Name: "status", Type: "text", Length: 16777216}},
+		Rows:    [][]any{{"Statement executed successfully."}},
+	})
+	return s, nil
+}
+
+// Close stops the server. The stage files are kept.
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// Dir returns the directory of the stage files.
+func (s *Server) Dir() string {
+	return s.dir
+}
+
+// StageDir returns the directory of the files of a stage, e.g. @~ or @mystage.
+func (s *Server) StageDir(stage string) string {
+	dir, _ := s.stageLocation(stage)
+	return dir
+}
+
+// Config returns the configuration to connect to the server.
+func (s *Server) Config() *gosnowflake.Config {
+	return s.srv.Config()
+}
+
+// DSN returns the data source name to connect to the server.
+func (s *Server) DSN() (string, error) {
+	return s.srv.DSN()
+}
+
+// Statements returns the statements other than PUT, GET, LIST and REMOVE
+// executed so far, in order.
+func (s *Server) Statements() []string {
+	var statements []string
+	for _, q := range s.other.Queries() {
+		statements = append(statements, q.SQL)
+	}
+	return statements
+}
+
+// queryError is returned to the driver as a failed query.
+type queryError struct {
+	code     int
+	sqlState string
+	message  string
+}
+
+func (e *queryError) Error() string {
+	return e.message
+}
+
+func syntaxError(format string, args ...any) *queryError {
+	return &queryError{errCodeSyntaxError, sqlStateSyntaxError, fmt.Sprintf(format, args...)}
+}
+
+// failed returns the response of a statement failing with err.
+func failed(err error) sftest.Response {
+	qe, ok := err.(*queryError)
+	if !ok {
+		qe = &queryError{errCodeInternal, "", err.Error()}
+	}
+	return sftest.Response{
+		Error: &gosnowflake.SnowflakeError{Number: qe.code, SQLState: qe.sqlState, Message: qe.message},
+	}
+}
+
+func (s *Server) put(q sftest.Query) sftest.Response {
+	tokens := tokenize(q.SQL)
+	if len(tokens) < 3 {
+		return failed(syntaxError("PUT requires a file and a stage"))
+	}
+	src, ok := fileURL(tokens[1])
+	if !ok {
+		return failed(syntaxError("invalid file URL %v", tokens[1]))
+	}
+	stage, prefix := s.stageLocation(unquote(tokens[2]))
+	if stage == "" {
+		return failed(syntaxError("invalid stage %v", tokens[2]))
+	}
+	opts := parseOptions(tokens[3:])
+	transfer := &sftest.FileTransfer{
+		Command:           "UPLOAD",
+		SrcLocations:      []string{src},
+		Parallel:          parseInt(opts["PARALLEL"], 4),
+		AutoCompress:      parseBool(opts["AUTO_COMPRESS"], true),
+		Overwrite:         parseBool(opts["OVERWRITE"], false),
+		SourceCompression: cmp.Or(strings.ToLower(opts["SOURCE_COMPRESSION"]), "auto_detect"),
+		LocationType:      LocationType,
+		Location:          stageLocationURL(filepath.Join(stage, filepath.FromSlash(prefix))),
+	}
+	if s.encrypt {
+		transfer.EncryptionMaterials = []sftest.EncryptionMaterial{s.encryptionMaterial(q.ID)}
+	}
+	return sftest.Response{Transfer: transfer}
+}
+
+func (s *Server) get(q sftest.Query) sftest.Response {
+	tokens := tokenize(q.SQL)
+	if len(tokens) < 3 {
+		return failed(syntaxError("GET requires a stage and a directory"))
+	}
+	stage, prefix := s.stageLocation(unquote(tokens[1]))
+	if stage == "" {
+		return failed(syntaxError("invalid stage %v", tokens[1]))
+	}
+	dst, ok := fileURL(tokens[2])
+	if !ok {
+		return failed(syntaxError("invalid file URL %v", tokens[2]))
+	}
+	opts := parseOptions(tokens[3:])
+	files, err := listFiles(stage, prefix, opts["PATTERN"])
+	if err != nil {
+		return failed(err)
+	}
+	transfer := &sftest.FileTransfer{
+		Command:       "DOWNLOAD",
+		LocalLocation: dst,
+		Parallel:      parseInt(opts["PARALLEL"], 10),
+		LocationType:  LocationType,
+		Location:      stageLocationURL(stage),
+	}
+	for _, f := range files {
+		transfer.SrcLocations = append(transfer.SrcLocations, f.name)
+		if s.encrypt {
+			transfer.EncryptionMaterials = append(transfer.EncryptionMaterials, s.encryptionMaterial(q.ID))
+		}
+	}
+	return sftest.Response{Transfer: transfer}
+}
+
+func (s *Server) list(q sftest.Query) sftest.Response {
+	tokens := tokenize(q.SQL)
+	if len(tokens) < 2 {
+		return failed(syntaxError("LIST requires a stage"))
+	}
+	stage, prefix := s.stageLocation(unquote(tokens[1]))
+	if stage == "" {
+		return failed(syntaxError("invalid stage %v", tokens[1]))
+	}
+	files, err := listFiles(stage, prefix, parseOptions(tokens[2:])["PATTERN"])
+	if err != nil {
+		return failed(err)
+	}
+	resp := sftest.Response{
+		Columns: []sftest.Column{
+			{Name: "name", Type: "text", Length: 16777216},
+			{Name: "size", Type: "fixed", Precision: 38},
+			{Name: "md5", Type: "text", Length: 16777216, Nullable: true},
+			{Name: "last_modified", Type: "text", Length: 16777216},
+		},
+		Rows: make([][]any, 0, len(files)),
+	}
+	stageName := strings.ToLower(filepath.Base(stage))
+	for _, f := range files {
+		resp.Rows = append(resp.Rows, []any{
+			path.Join(stageName, f.name),
+			f.size,
+			f.md5,
+			f.modified.UTC().Format(http.TimeFormat),
+		})
+	}
+	return resp
+}
+
+func (s *Server) remove(q sftest.Query) sftest.Response {
+	tokens := tokenize(q.SQL)
+	if len(tokens) < 2 {
+		return failed(syntaxError("REMOVE requires a stage"))
+	}
+	stage, prefix := s.stageLocation(unquote(tokens[1]))
+	if stage == "" {
+		return failed(syntaxError("invalid stage %v", tokens[1]))
+	}
+	files, err := listFiles(stage, prefix, parseOptions(tokens[2:])["PATTERN"])
+	if err != nil {
+		return failed(err)
+	}
+	stageName := strings.ToLower(filepath.Base(stage))
+	resp := sftest.Response{
+		Columns: []sftest.Column{
+			{Name: "name", Type: "text", Length: 16777216},
+			{Name: "result", Type: "text", Length: 16777216},
+		},
+		Rows: make([][]any, 0, len(files)),
+	}
+	for _, f := range files {
+		p := filepath.Join(stage, filepath.FromSlash(f.name))
+		if err = os.Remove(p); err != nil {
+			return failed(err)
+		}
+		if err = os.Remove(p + MetadataSuffix); err != nil && !os.IsNotExist(err) {
+			return failed(err)
+		}
+		resp.Rows = append(resp.Rows, []any{path.Join(stageName, f.name), "removed"})
+	}
+	return resp
+}
+
+// stageLocationURL returns the stage location of a directory, as the driver
+// expects it.
+func stageLocationURL(dir string) string {
+	return filepath.ToSlash(dir) + "/"
+}
+
+func (s *Server) encryptionMaterial(queryID string) sftest.EncryptionMaterial {
+	return sftest.EncryptionMaterial{
+		QueryStageMasterKey: s.masterKey,
+		QueryID:             queryID,
+		SMKID:               1,
+	}
+}
+
+// stageLocation returns the directory of a stage reference like
+// @db.schema.stage/path and the path within the stage.
+func (s *Server) stageLocation(ref string) (string, string) {
+	ref, ok := strings.CutPrefix(ref, "@")
+	if !ok || ref == "" {
+		return "", ""
+	}
+	name, prefix, _ := strings.Cut(ref, "/")
+	name = lastIdentifier(name)
+	if strings.HasPrefix(name, `"`) && strings.HasSuffix(name, `"`) && len(name) > 1 {
+		name = name[1 : len(name)-1]
+	} else {
+		name = strings.ToUpper(name)
+	}
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\:*?"<>|`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	if name == "" || name == "." || name == ".." {
+		return "", ""
+	}
+	return filepath.Join(s.dir, name), strings.Trim(path.Clean("/"+prefix), "/")
+}
+
+type stageFile struct {
+	name     string
+	size     int64
+	md5      string
+	modified time.Time
+}
+
+// listFiles returns the files of the stage directory starting with prefix
+// and matching pattern, with slash separated names relative to the stage.
+func listFiles(stage, prefix, pattern string) ([]stageFile, error) {
+	var re *regexp.Regexp
+	if pattern != "" {
+		var err error
+		if re, err = regexp.Compile("^(?:" + pattern + ")$"); err != nil {
+			return nil, syntaxError("invalid pattern %v: %v", pattern, err)
+		}
+	}
+	var files []stageFile
+	err := filepath.WalkDir(stage, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || strings.HasSuffix(p, MetadataSuffix) {
+			return nil
+		}
+		rel, err := filepath.Rel(stage, p)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+		if !strings.HasPrefix(name, prefix) || (re != nil && !re.MatchString(name)) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		b, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		sum := md5.Sum(b)
+		files = append(files, stageFile{name, info.Size(), hex.EncodeToString(sum[:]), info.ModTime()})
+		return nil
+	})
+	return files, err
+}
+
+// tokenize splits a statement on white space, keeping quoted strings
+// together and returning = as a token of its own.
+func tokenize(sqlText string) []string {
+	var tokens []string
+	var cur strings.Builder
+	var quote rune
+	flush := func() {
+		if cur.Len() > 0 {
+			tokens = append(tokens, cur.String())
+			cur.Reset()
+		}
+	}
+	for _, r := range strings.TrimRight(strings.TrimSpace(sqlText), ";") {
+		switch {
+		case quote != 0:
+			cur.WriteRune(r)
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			cur.WriteRune(r)
+		case r == '=':
+			flush()
+			tokens = append(tokens, "=")
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+			flush()
+		default:
+			cur.WriteRune(r)
+		}
+	}
+	flush()
+	return tokens
+}
+
+// parseOptions returns the KEY = value options of a statement with upper
+// case keys and unquoted values.
+func parseOptions(tokens []string) map[string]string {
+	opts := make(map[string]string)
+	for i := 0; i+2 < len(tokens); i++ {
+		if tokens[i+1] == "=" {
+			opts[strings.ToUpper(tokens[i])] = unquote(tokens[i+2])
+			i += 2
+		}
+	}
+	return opts
+}
+
+func unquote(s string) string {
+	if len(s) > 1 && s[0] == '\'' && s[len(s)-1] == '\'' {
+		return strings.ReplaceAll(s[1:len(s)-1], `\\`, `\`)
+	}
+	return s
+}
+
+func fileURL(token string) (string, bool) {
+	s := unquote(token)
+	if len(s) < len("file://") || !strings.EqualFold(s[:len("file://")], "file://") {
+		return "", false
+	}
+	return s[len("file://"):], true
+}
+
+func parseBool(s string, def bool) bool {
+	if b, err := strconv.ParseBool(s); err == nil {
+		return b
+	}
+	return def
+}
+
+func parseInt(s string, def int64) int64 {
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return n
+	}
+	return def
+}
+
+// lastIdentifier returns the stage name of a qualified name like db.schema."stage".
+func lastIdentifier(name string) string {
+	inQuotes := false
+	start := 0
+	for i, r := range name {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+		case r == '.' && !inQuotes:
+			start = i + 1
+		}
+	}
+	return name[start:]
+}
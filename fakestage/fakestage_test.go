@@ -0,0 +1,194 @@
+package fakestage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"database/sql"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/snowflakedb/gosnowflake"
+)
+
+var gzipMagic = []byte{0x1f, 0x8b}
+
+func openTestDB(t *testing.T, opts ...Option) (*Server, *sql.DB) {
+	srv, err := New(t.TempDir(), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(srv.Close)
+	db := sql.OpenDB(gosnowflake.NewConnector(gosnowflake.SnowflakeDriver{}, *srv.Config()))
+	t.Cleanup(func() {
+		if err := db.Close(); err != nil {
+			t.Error(err)
+		}
+	})
+	return srv, db
+}
+
+func writeTestFile(t *testing.T, content string) string {
+	src := filepath.Join(t.TempDir(), "data.csv")
+	if err := os.WriteFile(src, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return src
+}
+
+func gunzipFile(t *testing.T, name string) string {
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	r, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestPutGetEncrypted(t *testing.T) {
+	srv, db := openTestDB(t)
+	content := strings.Repeat("1,fake stage\n", 1000)
+	src := writeTestFile(t, content)
+
+	var source, target, status string
+	var sourceSize, targetSize int64
+	var sourceCompression, targetCompression, message string
+	err := db.QueryRow("PUT 'file://"+filepath.ToSlash(src)+"' @mystage/dir").Scan(
+		&source, &target, &sourceSize, &targetSize, &sourceCompression, &targetCompression, &status, &message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != "UPLOADED" || target != "data.csv.gz" {
+		t.Fatalf("unexpected PUT result: %v %v", target, status)
+	}
+
+	staged := filepath.Join(srv.StageDir("@mystage"), "dir", "data.csv.gz")
+	b, err := os.ReadFile(staged)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.HasPrefix(b, gzipMagic) {
+		t.Error("the stage file should be encrypted")
+	}
+	if _, err = os.Stat(staged + MetadataSuffix); err != nil {
+		t.Error(err)
+	}
+
+	err = db.QueryRow("PUT 'file://"+filepath.ToSlash(src)+"' @mystage/dir").Scan(
+		&source, &target, &sourceSize, &targetSize, &sourceCompression, &targetCompression, &status, &message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != "SKIPPED" {
+		t.Errorf("an existing file should be skipped, got %v", status)
+	}
+
+	dst := t.TempDir()
+	var file string
+	var size int64
+	err = db.QueryRow("GET @mystage/dir 'file://"+filepath.ToSlash(dst)+"'").Scan(&file, &size, &status, &message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != "DOWNLOADED" {
+		t.Fatalf("unexpected GET status: %v", status)
+	}
+	if got := gunzipFile(t, filepath.Join(dst, "data.csv.gz")); got != content {
+		t.Errorf("downloaded content differs, got %v bytes, expected %v", len(got), len(content))
+	}
+}
+
+func TestPutWithoutEncryption(t *testing.T) {
+	srv, db := openTestDB(t, WithoutEncryption())
+	content := "1,fake stage\n"
+	src := writeTestFile(t, content)
+
+	if _, err := db.Exec("PUT 'file://" + filepath.ToSlash(src) + "' @~"); err != nil {
+		t.Fatal(err)
+	}
+	if got := gunzipFile(t, filepath.Join(srv.StageDir("@~"), "data.csv.gz")); got != content {
+		t.Errorf("unexpected stage file content: %v", got)
+	}
+}
+
+func TestListAndRemove(t *testing.T) {
+	_, db := openTestDB(t)
+	src := writeTestFile(t, "1,fake stage\n")
+	for _, stage := range []string{"@db.schema.mystage/a", "@MYSTAGE/b"} {
+		if _, err := db.Exec("PUT 'file://" + filepath.ToSlash(src) + "' " + stage + " AUTO_COMPRESS = FALSE"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	names := func() []string {
+		rows, err := db.Query("LIST @mystage PATTERN = '.*[.]csv'")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rows.Close()
+		var names []string
+		for rows.Next() {
+			var name, md5, modified string
+			var size int64
+			if err = rows.Scan(&name, &size, &md5, &modified); err != nil {
+				t.Fatal(err)
+			}
+			names = append(names, name)
+		}
+		if err = rows.Err(); err != nil {
+			t.Fatal(err)
+		}
+		return names
+	}
+	if got := names(); !reflect.DeepEqual(got, []string{"mystage/a/data.csv", "mystage/b/data.csv"}) {
+		t.Fatalf("unexpected files: %v", got)
+	}
+	if _, err := db.Exec("REMOVE @mystage/a"); err != nil {
+		t.Fatal(err)
+	}
+	if got := names(); !reflect.DeepEqual(got, []string{"mystage/b/data.csv"}) {
+		t.Errorf("unexpected files after REMOVE: %v", got)
+	}
+}
+
+func TestOtherStatementsAreRecorded(t *testing.T) {
+	srv, db := openTestDB(t)
+	if _, err := db.Exec("CREATE TEMPORARY STAGE mystage"); err != nil {
+		t.Fatal(err)
+	}
+	if got := srv.Statements(); !reflect.DeepEqual(got, []string{"CREATE TEMPORARY STAGE mystage"}) {
+		t.Errorf("unexpected statements: %v", got)
+	}
+}
+
+func TestStageLocation(t *testing.T) {
+	srv := &Server{dir: "/stages"}
+	for ref, expected := range map[string][2]string{
+		"@~":                         {"~", ""},
+		"@~/a/b/":                    {"~", "a/b"},
+		"@%mytable":                  {"%MYTABLE", ""},
+		"@db.schema.stage/x":         {"STAGE", "x"},
+		`@db."My.Schema"."My Stage"`: {"My Stage", ""},
+		"@stage/../../etc":           {"STAGE", "etc"},
+		"stage":                      {"", ""},
+	} {
+		dir, prefix := srv.stageLocation(ref)
+		if expected[0] != "" {
+			expected[0] = filepath.Join("/stages", expected[0])
+		}
+		if dir != expected[0] || prefix != expected[1] {
+			t.Errorf("%v: got %v %v, expected %v %v", ref, dir, prefix, expected[0], expected[1])
+		}
+	}
+}
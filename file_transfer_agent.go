@@ -3,6 +3,7 @@ package gosnowflake
 //lint:file-ignore U1000 Ignore all unused code
 
 import (
+	"bufio"
 	"bytes"
 	"cmp"
 	"context"
@@ -12,11 +13,13 @@ import (
 	"fmt"
 	"io"
 	"math"
+	"net"
 	"net/url"
 	"os"
 	"path/filepath"
 	"regexp"
 	"runtime"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
@@ -53,6 +56,9 @@ const (
 	azureClient cloudType = "AZURE"
 	gcsClient   cloudType = "GCS"
 	local       cloudType = "LOCAL_FS"
+	// fakeStageClient is only returned by the fakestage test server, and only
+	// accepted from a server on a loopback address.
+	fakeStageClient cloudType = "FAKESTAGE"
 )
 
 type resultStatus int
@@ -86,13 +92,21 @@ type SnowflakeFileTransferOptions struct {
 	// Deprecated: will be removed in the future. The behaviour will be aligned to setting it to true.
 	RaisePutGetError   bool
 	MultiPartThreshold int64
+	// AutoCompression is the compression PUT applies with AUTO_COMPRESS: GZIP (default), ZSTD or BROTLI.
+	AutoCompression string
 
 	/* streaming PUT */
 	compressSourceFromStream bool
+	// StreamUpload uploads a WithFileStream source in parts while it is read
+	// instead of reading it into memory first. Failed uploads are not retried.
+	StreamUpload bool
 
 	/* streaming GET */
 	GetFileToStream bool
 
+	// ProgressListener receives the progress of every file of the PUT or GET.
+	ProgressListener TransferProgressListener
+
 	/* PUT */
 	putCallback             *snowflakeProgressPercentage
 	putAzureCallback        *snowflakeProgressPercentage
@@ -181,7 +195,8 @@ func (sfa *snowflakeFileTransferAgent) execute() error {
 			sizeThreshold := sfa.options.MultiPartThreshold
 			meta.options.MultiPartThreshold = sizeThreshold
 			if sfa.commandType == uploadCommand {
-				if meta.srcFileSize > sizeThreshold {
+				// the size of a streamed source is unknown until it is uploaded
+				if meta.srcFileSize > sizeThreshold || meta.streamUpload {
 					meta.parallel = sfa.parallel
 					largeFileMetas = append(largeFileMetas, meta)
 				} else {
@@ -373,7 +388,16 @@ func (sfa *snowflakeFileTransferAgent) initFileMetadata() error {
 			//Bulk insert case
 			fileName := sfa.srcFiles[0]
 			fileInfo, err := os.Stat(fileName)
-			if err != nil {
+			if err != nil && sfa.options != nil && sfa.options.StreamUpload {
+				sfa.fileMetadata = append(sfa.fileMetadata, &fileMetadata{
+					name:              baseName(fileName),
+					srcFileName:       fileName,
+					fileStream:        sfa.sourceStream,
+					streamUpload:      true,
+					stageLocationType: sfa.stageLocationType,
+					stageInfo:         sfa.stageInfo,
+				})
+			} else if err != nil {
 				buf := new(bytes.Buffer)
 				_, err := buf.ReadFrom(sfa.sourceStream)
 				if err != nil {
@@ -496,7 +520,19 @@ func (sfa *snowflakeFileTransferAgent) processFileCompressionType() error {
 		autoDetect = false
 	}
 
-	gzipCompression := compressionTypes["GZIP"]
+	autoCompression := compressionTypes["GZIP"]
+	if sfa.options != nil && sfa.options.AutoCompression != "" {
+		autoCompression = compressionTypes[strings.ToUpper(sfa.options.AutoCompression)]
+		if autoCompression == nil || !slices.Contains(autoCompressionTypes, autoCompression.name) {
+			return (&SnowflakeError{
+				Number:      ErrCompressionNotSupported,
+				SQLState:    sfa.data.SQLState,
+				QueryID:     sfa.data.QueryID,
+				Message:     errMsgFeatureNotSupported,
+				MessageArgs: []interface{}{sfa.options.AutoCompression},
+			}).exceptionTelemetry(sfa.sc)
+		}
+	}
 	for _, meta := range sfa.fileMetadata {
 		fileName := meta.srcFileName
 		var currentFileCompressionType *compressionType
@@ -514,6 +550,14 @@ func (sfa *snowflakeFileTransferAgent) processFileCompressionType() error {
 					if _, err = io.ReadAll(r); err != nil { // flush out tee buffer
 						return err
 					}
+				} else if meta.streamUpload {
+					br := bufio.NewReaderSize(meta.fileStream, streamDetectionSize)
+					head, err := br.Peek(streamDetectionSize)
+					if err != nil && err != io.EOF {
+						return err
+					}
+					meta.fileStream = br
+					mtype = mimetype.Detect(head)
 				} else {
 					mtype, err = mimetype.DetectFile(fileName)
 					if err != nil {
@@ -555,9 +599,9 @@ func (sfa *snowflakeFileTransferAgent) processFileCompressionType() error {
 			meta.requireCompress = sfa.autoCompress
 			meta.srcCompressionType = nil
 			if sfa.autoCompress {
-				dstFileName := meta.name + compressionTypes["GZIP"].fileExtension
+				dstFileName := meta.name + autoCompression.fileExtension
 				meta.dstFileName = dstFileName
-				meta.dstCompressionType = gzipCompression
+				meta.dstCompressionType = autoCompression
 			} else {
 				meta.dstFileName = meta.name
 				meta.dstCompressionType = nil
@@ -661,7 +705,7 @@ func (sfa *snowflakeFileTransferAgent) transferAccelerateConfigWithUtil(s3Util s
 			Message:  errMsgFailedToConvertToS3Client,
 		}).exceptionTelemetry(sfa.sc)
 	}
-	ret, err := withCloudStorageTimeout(sfa.sc.cfg, func(ctx context.Context) (*s3.GetBucketAccelerateConfigurationOutput, error) {
+	ret, err := withCloudStorageTimeout(sfa.ctx, sfa.sc.cfg, func(ctx context.Context) (*s3.GetBucketAccelerateConfigurationOutput, error) {
 		return client.GetBucketAccelerateConfiguration(ctx, &s3.GetBucketAccelerateConfigurationInput{
 			Bucket: &s3Loc.bucketName,
 		})
@@ -673,13 +717,18 @@ func (sfa *snowflakeFileTransferAgent) transferAccelerateConfigWithUtil(s3Util s
 	return nil
 }
 
-func withCloudStorageTimeout[T any](cfg *Config, f func(ctx context.Context) (T, error)) (T, error) {
+// withCloudStorageTimeout calls f with ctx limited by CloudStorageTimeout.
+// ctx is the context of the PUT or GET, so cancelling it aborts the request.
+func withCloudStorageTimeout[T any](ctx context.Context, cfg *Config, f func(ctx context.Context) (T, error)) (T, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
 	if cfg.CloudStorageTimeout > 0 {
-		ctx, cancelFunc := context.WithTimeout(context.Background(), cfg.CloudStorageTimeout)
+		ctx, cancelFunc := context.WithTimeout(ctx, cfg.CloudStorageTimeout)
 		defer cancelFunc()
 		return f(ctx)
 	}
-	return f(context.Background())
+	return f(ctx)
 }
 
 func (sfa *snowflakeFileTransferAgent) transferAccelerateConfig() error {
@@ -804,6 +853,9 @@ func (sfa *snowflakeFileTransferAgent) uploadFilesParallel(fileMetas []*fileMeta
 				}(i, meta)
 			}
 			wg.Wait()
+			if err = sfa.canceled(); err != nil {
+				return err
+			}
 
 			// append errors with no result associated to separate array
 			var errorMessages []string
@@ -837,6 +889,7 @@ func (sfa *snowflakeFileTransferAgent) uploadFilesParallel(fileMetas []*fileMeta
 
 			needRenewToken := false
 			for _, result := range retryMeta {
+				result.reportRetried(result.lastError)
 				if result.resStatus == renewToken {
 					needRenewToken = true
 				}
@@ -906,10 +959,25 @@ func (sfa *snowflakeFileTransferAgent) uploadFilesSequential(fileMetas []*fileMe
 	return nil
 }
 
-func (sfa *snowflakeFileTransferAgent) uploadOneFile(meta *fileMetadata) (*fileMetadata, error) {
+func (sfa *snowflakeFileTransferAgent) uploadOneFile(meta *fileMetadata) (_ *fileMetadata, err error) {
+	ctx, op := sfa.sc.instrumentation().startOperation(sfa.ctx, spanNameFileUpload, "file_upload",
+		attrFileName.String(meta.name))
+	defer func() {
+		if err == nil {
+			op.recordBytes(ctx, meta.uploadSize)
+		}
+		op.end(ctx, err)
+		meta.reportResult(err)
+	}()
+	if err = meta.transferContext().Err(); err != nil {
+		return meta, err
+	}
+	meta.reportStarted()
 	meta.realSrcFileName = meta.srcFileName
 	tmpDir := ""
-	if meta.fileStream == nil {
+	if meta.srcStream == nil && !meta.streamUpload {
+		// files are compressed and encrypted into a temporary directory,
+		// also when they are read through WithFileStream
 		var err error
 		tmpDir, err = os.MkdirTemp(sfa.sc.cfg.TmpDirPath, "")
 		if err != nil {
@@ -923,9 +991,13 @@ func (sfa *snowflakeFileTransferAgent) uploadOneFile(meta *fileMetadata) (*fileM
 		}
 	}()
 
+	if meta.streamUpload || (meta.srcStream != nil && meta.requireCompress) {
+		return meta, sfa.uploadOneStream(meta)
+	}
+
 	fileUtil := new(snowflakeFileUtil)
 
-	err := compressDataIfRequired(meta, fileUtil, tmpDir)
+	err = compressDataIfRequired(meta, fileUtil, tmpDir)
 	if err != nil {
 		return meta, err
 	}
@@ -947,6 +1019,39 @@ func (sfa *snowflakeFileTransferAgent) uploadOneFile(meta *fileMetadata) (*fileM
 	return meta, nil
 }
 
+// uploadOneStream uploads meta.fileStream, or meta.srcStream when it is set,
+// through a streamUpload. The storage clients cannot retry a stream, so a
+// failed upload of srcStream is started over from the beginning. A
+// streamUpload of fileStream is not retried.
+func (sfa *snowflakeFileTransferAgent) uploadOneStream(meta *fileMetadata) error {
+	err := sfa.uploadOneStreamAttempt(meta)
+	for retry := 1; err != nil && meta.srcStream != nil && retry < defaultMaxRetry; retry++ {
+		if meta.transferContext().Err() != nil {
+			return err
+		}
+		logger.WithContext(sfa.ctx).Warnf("retrying the upload of %v. err: %v", meta.name, err)
+		err = sfa.uploadOneStreamAttempt(meta)
+	}
+	return err
+}
+
+func (sfa *snowflakeFileTransferAgent) uploadOneStreamAttempt(meta *fileMetadata) error {
+	su, err := newStreamUpload(meta, sfa.stageLocationType)
+	if err != nil {
+		return err
+	}
+	meta.uploadStream = su.reader
+	defer func() {
+		meta.uploadStream = nil
+	}()
+	client := sfa.getStorageClient(sfa.stageLocationType)
+	err = client.uploadOneFileWithRetry(meta)
+	if streamErr := su.wait(); streamErr != nil && !errors.Is(streamErr, io.ErrClosedPipe) {
+		return streamErr
+	}
+	return err
+}
+
 func (sfa *snowflakeFileTransferAgent) downloadFilesParallel(fileMetas []*fileMetadata) error {
 	idx := 0
 	fileMetaLen := len(fileMetas)
@@ -972,6 +1077,9 @@ func (sfa *snowflakeFileTransferAgent) downloadFilesParallel(fileMetas []*fileMe
 				}(i, meta)
 			}
 			wg.Wait()
+			if err = sfa.canceled(); err != nil {
+				return err
+			}
 
 			retryMeta := make([]*fileMetadata, 0)
 			for i, result := range results {
@@ -989,6 +1097,7 @@ func (sfa *snowflakeFileTransferAgent) downloadFilesParallel(fileMetas []*fileMe
 
 			needRenewToken := false
 			for _, result := range retryMeta {
+				result.reportRetried(result.lastError)
 				if result.resStatus == renewToken {
 					needRenewToken = true
 				}
@@ -1030,7 +1139,14 @@ func (sfa *snowflakeFileTransferAgent) downloadFilesParallel(fileMetas []*fileMe
 	return err
 }
 
-func (sfa *snowflakeFileTransferAgent) downloadOneFile(meta *fileMetadata) (*fileMetadata, error) {
+func (sfa *snowflakeFileTransferAgent) downloadOneFile(meta *fileMetadata) (_ *fileMetadata, err error) {
+	defer func() {
+		meta.reportResult(err)
+	}()
+	if err = meta.transferContext().Err(); err != nil {
+		return meta, err
+	}
+	meta.reportStarted()
 	if sfa.options != nil && !sfa.options.GetFileToStream {
 		tmpDir, err := os.MkdirTemp(sfa.sc.cfg.TmpDirPath, "")
 		if err != nil {
@@ -1044,7 +1160,7 @@ func (sfa *snowflakeFileTransferAgent) downloadOneFile(meta *fileMetadata) (*fil
 		}()
 	}
 	client := sfa.getStorageClient(sfa.stageLocationType)
-	if err := client.downloadOneFile(meta); err != nil {
+	if err = client.downloadOneFile(meta); err != nil {
 		meta.dstFileSize = -1
 		if !meta.resStatus.isSet() {
 			meta.resStatus = errStatus
@@ -1055,6 +1171,23 @@ func (sfa *snowflakeFileTransferAgent) downloadOneFile(meta *fileMetadata) (*fil
 	return meta, nil
 }
 
+// canceled returns the error of the PUT or GET context once it is done.
+func (sfa *snowflakeFileTransferAgent) canceled() error {
+	if sfa.ctx == nil {
+		return nil
+	}
+	return sfa.ctx.Err()
+}
+
+// retryPolicy returns the policy used to retry cloud storage transfers.
+func (sfa *snowflakeFileTransferAgent) retryPolicy() RetryPolicy {
+	var cfgRetryPolicy RetryPolicy
+	if sfa.sc != nil && sfa.sc.cfg != nil {
+		cfgRetryPolicy = sfa.sc.cfg.RetryPolicy
+	}
+	return retryPolicyFor(sfa.ctx, cfgRetryPolicy)
+}
+
 func (sfa *snowflakeFileTransferAgent) getStorageClient(stageLocationType cloudType) storageUtil {
 	switch stageLocationType {
 	case local:
@@ -1064,11 +1197,27 @@ func (sfa *snowflakeFileTransferAgent) getStorageClient(stageLocationType cloudT
 			cfg:       sfa.sc.cfg,
 			telemetry: sfa.sc.telemetry,
 		}
+	case fakeStageClient:
+		if !isLoopbackHost(sfa.sc.cfg.Host) {
+			return nil
+		}
+		return &remoteStorageUtil{
+			cfg:       sfa.sc.cfg,
+			telemetry: sfa.sc.telemetry,
+		}
 	default:
 		return nil
 	}
 }
 
+func isLoopbackHost(host string) bool {
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
 func (sfa *snowflakeFileTransferAgent) renewExpiredClient() (cloudClient, error) {
 	data, err := sfa.sc.exec(
 		sfa.ctx,
@@ -1293,14 +1442,12 @@ func (spp *snowflakeProgressPercentage) updateProgress(filename string, startTim
 	return progress == 1.0
 }
 
+// compressDataIfRequired compresses a source file. Streams are compressed
+// while they are uploaded, see uploadOneStream.
 func compressDataIfRequired(meta *fileMetadata, fileUtil *snowflakeFileUtil, tmpDir string) error {
 	var err error
-	if meta.requireCompress {
-		if meta.srcStream != nil {
-			meta.realSrcStream, _, err = fileUtil.compressFileWithGzipFromStream(&meta.srcStream)
-		} else {
-			meta.realSrcFileName, _, err = fileUtil.compressFileWithGzip(meta.srcFileName, tmpDir)
-		}
+	if meta.requireCompress && meta.srcStream == nil {
+		meta.realSrcFileName, _, err = fileUtil.compressFile(meta.srcFileName, tmpDir, meta.dstCompressionType)
 	}
 	return err
 }
@@ -290,6 +290,28 @@ func TestUnitProcessFileCompressionType(t *testing.T) {
 	})
 }
 
+func TestProcessFileCompressionTypeWithAutoCompression(t *testing.T) {
+	sfa := &snowflakeFileTransferAgent{
+		ctx:            context.Background(),
+		sc:             &snowflakeConn{cfg: &Config{}},
+		commandType:    uploadCommand,
+		srcCompression: "none",
+		autoCompress:   true,
+		data:           &execResponseData{},
+		options:        &SnowflakeFileTransferOptions{AutoCompression: "zstd"},
+		fileMetadata:   []*fileMetadata{{name: "data.csv", srcFileName: "data.csv"}},
+	}
+	assertNilF(t, sfa.processFileCompressionType())
+	assertEqualE(t, sfa.fileMetadata[0].dstFileName, "data.csv.zst")
+	assertEqualE(t, sfa.fileMetadata[0].dstCompressionType, compressionTypes["ZSTD"])
+	assertTrueE(t, sfa.fileMetadata[0].requireCompress)
+
+	sfa.options.AutoCompression = "bzip2"
+	err := sfa.processFileCompressionType()
+	assertNotNilF(t, err)
+	assertEqualE(t, err.(*SnowflakeError).Number, ErrCompressionNotSupported)
+}
+
 func TestParseCommandWithInvalidStageLocation(t *testing.T) {
 	runSnowflakeConnTest(t, func(sct *SCTest) {
 		sfa := &snowflakeFileTransferAgent{
@@ -585,6 +607,25 @@ func TestUpdateMetadataWithPresignedUrlError(t *testing.T) {
 	})
 }
 
+func TestFakeStageRequiresLoopbackHost(t *testing.T) {
+	for host, allowed := range map[string]bool{
+		"127.0.0.1":                        true,
+		"::1":                              true,
+		"localhost":                        true,
+		"myaccount.snowflakecomputing.com": false,
+		"10.0.0.1":                         false,
+	} {
+		t.Run(host, func(t *testing.T) {
+			sfa := &snowflakeFileTransferAgent{
+				sc: &snowflakeConn{
+					cfg: &Config{Host: host},
+				},
+			}
+			assertEqualE(t, sfa.getStorageClient(fakeStageClient) != nil, allowed)
+		})
+	}
+}
+
 func TestUploadWhenFilesystemReadOnlyError(t *testing.T) {
 	if isWindows {
 		t.Skip("permission model is different")
@@ -912,11 +953,7 @@ func TestUploadDownloadOneFileRequireCompressStream(t *testing.T) {
 }
 
 func testUploadDownloadOneFile(t *testing.T, isStream bool) {
-	tmpDir, err := os.MkdirTemp("", "data")
-	if err != nil {
-		t.Fatalf("cannot create temp directory: %v", err)
-	}
-	defer os.RemoveAll(tmpDir)
+	tmpDir := t.TempDir()
 	uploadFile := filepath.Join(tmpDir, "data.txt")
 	f, err := os.Create(uploadFile)
 	if err != nil {
@@ -2,14 +2,20 @@ package gosnowflake
 
 import (
 	"bytes"
+	"cmp"
 	"compress/gzip"
 	"crypto/sha256"
 	"encoding/base64"
+	"fmt"
 	"io"
 	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync/atomic"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
 )
 
 type snowflakeFileUtil struct {
@@ -20,26 +26,38 @@ const (
 	readWriteFileMode os.FileMode = 0666
 )
 
-func (util *snowflakeFileUtil) compressFileWithGzipFromStream(srcStream **bytes.Buffer) (*bytes.Buffer, int, error) {
-	r := getReaderFromBuffer(srcStream)
-	buf, err := io.ReadAll(r)
-	if err != nil {
-		return nil, -1, err
+// autoCompressionTypes are the compression types AUTO_COMPRESS can produce.
+var autoCompressionTypes = []string{"GZIP", "ZSTD", "BROTLI"}
+
+func newCompressionWriter(w io.Writer, compression *compressionType) (io.WriteCloser, error) {
+	switch compression.name {
+	case "GZIP":
+		return gzip.NewWriter(w), nil
+	case "ZSTD":
+		return zstd.NewWriter(w)
+	case "BROTLI":
+		return brotli.NewWriter(w), nil
 	}
-	var c bytes.Buffer
-	w := gzip.NewWriter(&c)
-	if _, err := w.Write(buf); err != nil { // write buf to gzip writer
-		return nil, -1, err
+	return nil, fmt.Errorf("compression %v is not supported for AUTO_COMPRESS", compression.name)
+}
+
+// compressTo copies src to dst through the compression writer. A nil
+// compression means gzip.
+func compressTo(dst io.Writer, src io.Reader, compression *compressionType) error {
+	w, err := newCompressionWriter(dst, cmp.Or(compression, compressionTypes["GZIP"]))
+	if err != nil {
+		return err
 	}
-	if err := w.Close(); err != nil {
-		return nil, -1, err
+	if _, err = io.Copy(w, src); err != nil {
+		return err
 	}
-	return &c, c.Len(), nil
+	return w.Close()
 }
 
-func (util *snowflakeFileUtil) compressFileWithGzip(fileName string, tmpDir string) (gzipFileName string, size int64, err error) {
+func (util *snowflakeFileUtil) compressFile(fileName string, tmpDir string, compression *compressionType) (compressedFileName string, size int64, err error) {
 	basename := baseName(fileName)
-	gzipFileName = filepath.Join(tmpDir, basename+"_c.gz")
+	compression = cmp.Or(compression, compressionTypes["GZIP"])
+	compressedFileName = filepath.Join(tmpDir, basename+"_c"+compression.fileExtension)
 
 	fr, err := os.Open(fileName)
 	if err != nil {
@@ -50,26 +68,24 @@ func (util *snowflakeFileUtil) compressFileWithGzip(fileName string, tmpDir stri
 			err = tmpErr
 		}
 	}()
-	fw, err := os.OpenFile(gzipFileName, os.O_WRONLY|os.O_CREATE, readWriteFileMode)
+	fw, err := os.OpenFile(compressedFileName, os.O_WRONLY|os.O_CREATE, readWriteFileMode)
 	if err != nil {
 		return "", -1, err
 	}
-	gzw := gzip.NewWriter(fw)
 	defer func() {
-		if tmpErr := gzw.Close(); tmpErr != nil {
+		if tmpErr := fw.Close(); tmpErr != nil {
 			err = tmpErr
 		}
 	}()
-	_, err = io.Copy(gzw, fr)
-	if err != nil {
+	if err = compressTo(fw, fr, compression); err != nil {
 		return "", -1, err
 	}
 
-	stat, err := os.Stat(gzipFileName)
+	stat, err := fw.Stat()
 	if err != nil {
 		return "", -1, err
 	}
-	return gzipFileName, stat.Size(), err
+	return compressedFileName, stat.Size(), err
 }
 
 func (util *snowflakeFileUtil) getDigestAndSizeForStream(stream io.Reader) (string, int64, error) {
@@ -157,10 +173,16 @@ type fileMetadata struct {
 	fileStream    io.Reader
 	srcStream     *bytes.Buffer
 	realSrcStream *bytes.Buffer
+	streamUpload  bool
+	uploadStream  io.Reader // set while a streamUpload is read by the storage client
 
 	/* streaming GET */
 	dstStream *bytes.Buffer
 
+	/* progress */
+	transferStarted bool
+	transferred     atomic.Int64
+
 	/* GCS */
 	presignedURL                *url.URL
 	gcsFileHeaderDigest         string
@@ -169,6 +191,8 @@ type fileMetadata struct {
 
 	/* mock */
 	mockUploader    s3UploadAPI
+	mockAborter     s3AbortMultipartUploadAPI
+	mockCopier      s3CopyObjectAPI
 	mockDownloader  s3DownloadAPI
 	mockHeader      s3HeaderAPI
 	mockGcsClient   gcsAPI
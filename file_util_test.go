@@ -1,9 +1,17 @@
 package gosnowflake
 
 import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
 	"os/user"
 	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
 )
 
 func TestGetDigestAndSizeForInvalidDir(t *testing.T) {
@@ -66,3 +74,51 @@ func TestExpandUser(t *testing.T) {
 		t.Fatalf("failed to expand user, expected: %v, got: %v", expectedPath, user)
 	}
 }
+
+func decompressForTest(t *testing.T, r io.Reader, compression *compressionType) []byte {
+	var dr io.Reader
+	switch compression.name {
+	case "GZIP":
+		gzr, err := gzip.NewReader(r)
+		assertNilF(t, err)
+		dr = gzr
+	case "ZSTD":
+		zr, err := zstd.NewReader(r)
+		assertNilF(t, err)
+		defer zr.Close()
+		dr = zr
+	case "BROTLI":
+		dr = brotli.NewReader(r)
+	}
+	data, err := io.ReadAll(dr)
+	assertNilF(t, err)
+	return data
+}
+
+func TestCompressFileAndStream(t *testing.T) {
+	content := []byte(strings.Repeat("1,abc,2024-01-01\n", 1000))
+	srcFile := filepath.Join(t.TempDir(), "data.csv")
+	assertNilF(t, os.WriteFile(srcFile, content, 0600))
+	fileUtil := new(snowflakeFileUtil)
+
+	for _, name := range autoCompressionTypes {
+		t.Run(name, func(t *testing.T) {
+			compression := compressionTypes[name]
+			compressedFile, size, err := fileUtil.compressFile(srcFile, t.TempDir(), compression)
+			assertNilF(t, err)
+			assertStringContainsE(t, compressedFile, "data.csv_c"+compression.fileExtension)
+			assertTrueE(t, size > 0 && size < int64(len(content)))
+			f, err := os.Open(compressedFile)
+			assertNilF(t, err)
+			defer f.Close()
+			assertDeepEqualE(t, decompressForTest(t, f, compression), content)
+
+			var compressed bytes.Buffer
+			assertNilF(t, compressTo(&compressed, bytes.NewReader(content), compression))
+			assertDeepEqualE(t, decompressForTest(t, &compressed, compression), content)
+		})
+	}
+
+	_, _, err := fileUtil.compressFile(srcFile, t.TempDir(), compressionTypes["BZIP2"])
+	assertNotNilE(t, err)
+}
@@ -1,6 +1,7 @@
 package gosnowflake
 
 import (
+	"bytes"
 	"cmp"
 	"context"
 	"encoding/json"
@@ -66,7 +67,7 @@ func (util *snowflakeGcsClient) getFileHeader(meta *fileMetadata, filename strin
 			"Authorization": "Bearer " + accessToken,
 		}
 
-		resp, err := withCloudStorageTimeout(util.cfg, func(ctx context.Context) (*http.Response, error) {
+		resp, err := withCloudStorageTimeout(meta.transferContext(), util.cfg, func(ctx context.Context) (*http.Response, error) {
 			req, err := http.NewRequestWithContext(ctx, "HEAD", URL.String(), nil)
 			if err != nil {
 				return nil, err
@@ -185,7 +186,10 @@ func (util *snowflakeGcsClient) uploadFile(
 
 	gcsHeaders := make(map[string]string)
 	gcsHeaders[httpHeaderContentEncoding] = contentEncoding
-	gcsHeaders[gcsMetadataSfcDigest] = meta.sha256Digest
+	if meta.uploadStream == nil {
+		// the digest of a streamed source is stored once it is uploaded, see storeStreamedDigest
+		gcsHeaders[gcsMetadataSfcDigest] = meta.sha256Digest
+	}
 	if accessToken != "" {
 		gcsHeaders["Authorization"] = "Bearer " + accessToken
 	}
@@ -216,7 +220,10 @@ func (util *snowflakeGcsClient) uploadFile(
 	}
 
 	var uploadSrc io.Reader
-	if meta.srcStream != nil {
+	if meta.uploadStream != nil {
+		// sent with chunked transfer encoding as it is read
+		uploadSrc = meta.uploadStream
+	} else if meta.srcStream != nil {
 		uploadSrc = meta.srcStream
 		if meta.realSrcStream != nil {
 			uploadSrc = meta.realSrcStream
@@ -234,11 +241,20 @@ func (util *snowflakeGcsClient) uploadFile(
 		}(uploadSrc.(io.Closer))
 	}
 
-	resp, err := withCloudStorageTimeout(util.cfg, func(ctx context.Context) (*http.Response, error) {
+	var contentLength int64
+	if b, ok := uploadSrc.(*bytes.Buffer); ok {
+		contentLength = int64(b.Len())
+	}
+	uploadSrc = meta.progressReader(uploadSrc)
+
+	resp, err := withCloudStorageTimeout(meta.transferContext(), util.cfg, func(ctx context.Context) (*http.Response, error) {
 		req, err := http.NewRequestWithContext(ctx, "PUT", uploadURL.String(), uploadSrc)
 		if err != nil {
 			return nil, err
 		}
+		if contentLength > 0 {
+			req.ContentLength = contentLength
+		}
 		for k, v := range gcsHeaders {
 			req.Header.Add(k, v)
 		}
@@ -278,6 +294,12 @@ func (util *snowflakeGcsClient) uploadFile(
 		}
 		return meta.lastError
 	}
+	if meta.uploadStream != nil {
+		if err = util.storeStreamedDigest(meta, accessToken); err != nil {
+			meta.lastError = err
+			return fmt.Errorf("error while storing the digest of %v. %w", meta.name, err)
+		}
+	}
 
 	if meta.options.putCallback != nil {
 		meta.options.putCallback = &snowflakeProgressPercentage{
@@ -300,6 +322,56 @@ func (util *snowflakeGcsClient) uploadFile(
 	return nil
 }
 
+// storeStreamedDigest adds the digest that was computed while a streamed object
+// was uploaded to its metadata. A presigned URL does not allow it.
+func (util *snowflakeGcsClient) storeStreamedDigest(meta *fileMetadata, accessToken string) error {
+	if accessToken == "" {
+		logger.Warnf("%v was uploaded to a presigned url, it is uploaded without its digest", meta.dstFileName)
+		return nil
+	}
+	metadataURL, err := util.generateMetadataURL(meta.stageInfo, strings.TrimLeft(meta.dstFileName, "/"))
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(map[string]map[string]string{
+		"metadata": {sfcDigest: meta.sha256Digest},
+	})
+	if err != nil {
+		return err
+	}
+	resp, err := withCloudStorageTimeout(meta.transferContext(), util.cfg, func(ctx context.Context) (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, "PATCH", metadataURL.String(), bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+		req.Header.Set(httpHeaderContentType, headerContentTypeApplicationJSON)
+		client, err := newGcsClient(util.cfg, util.telemetry)
+		if err != nil {
+			return nil, err
+		}
+		// for testing only
+		if meta.mockGcsClient != nil {
+			client = meta.mockGcsClient
+		}
+		return client.Do(req)
+	})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if resp.Body != nil {
+			if err := resp.Body.Close(); err != nil {
+				logger.Warnf("failed to close response body: %v", err)
+			}
+		}
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%v", resp.Status)
+	}
+	return nil
+}
+
 // cloudUtil implementation
 func (util *snowflakeGcsClient) nativeDownloadFile(
 	meta *fileMetadata,
@@ -373,7 +445,7 @@ func (util *snowflakeGcsClient) nativeDownloadFile(
 
 // getFileHeaderForDownload gets the file header using a HEAD request
 func (util *snowflakeGcsClient) getFileHeaderForDownload(downloadURL *url.URL, gcsHeaders map[string]string, accessToken string, meta *fileMetadata) (*http.Response, error) {
-	resp, err := withCloudStorageTimeout(util.cfg, func(ctx context.Context) (*http.Response, error) {
+	resp, err := withCloudStorageTimeout(meta.transferContext(), util.cfg, func(ctx context.Context) (*http.Response, error) {
 		req, err := http.NewRequestWithContext(ctx, "HEAD", downloadURL.String(), nil)
 		if err != nil {
 			return nil, err
@@ -639,7 +711,7 @@ func (util *snowflakeGcsClient) downloadRangeStream(
 	client gcsAPI,
 	start, end int64) (io.ReadCloser, error) {
 
-	resp, err := withCloudStorageTimeout(util.cfg, func(ctx context.Context) (*http.Response, error) {
+	resp, err := withCloudStorageTimeout(meta.transferContext(), util.cfg, func(ctx context.Context) (*http.Response, error) {
 		req, err := http.NewRequestWithContext(ctx, "GET", downloadURL.String(), nil)
 		if err != nil {
 			return nil, err
@@ -666,7 +738,10 @@ func (util *snowflakeGcsClient) downloadRangeStream(
 	}
 
 	// Return the response body stream directly - caller is responsible for closing
-	return resp.Body, nil
+	return struct {
+		io.Reader
+		io.Closer
+	}{meta.progressReader(resp.Body), resp.Body}, nil
 }
 
 // downloadRangeBytes downloads a specific byte range and returns the bytes
@@ -705,7 +780,7 @@ func (util *snowflakeGcsClient) downloadFileSinglePart(
 	meta *fileMetadata,
 	fullDstFileName string) error {
 
-	resp, err := withCloudStorageTimeout(util.cfg, func(ctx context.Context) (*http.Response, error) {
+	resp, err := withCloudStorageTimeout(meta.transferContext(), util.cfg, func(ctx context.Context) (*http.Response, error) {
 		req, err := http.NewRequestWithContext(ctx, "GET", downloadURL.String(), nil)
 		if err != nil {
 			return nil, err
@@ -739,8 +814,9 @@ func (util *snowflakeGcsClient) downloadFileSinglePart(
 		return util.handleHTTPError(resp, meta, accessToken)
 	}
 
+	body := meta.progressReader(resp.Body)
 	if meta.options != nil && meta.options.GetFileToStream {
-		if _, err := io.Copy(meta.dstStream, resp.Body); err != nil {
+		if _, err := io.Copy(meta.dstStream, body); err != nil {
 			return err
 		}
 	} else {
@@ -753,7 +829,7 @@ func (util *snowflakeGcsClient) downloadFileSinglePart(
 				logger.Warnf("Failed to close the file: %v", err)
 			}
 		}()
-		if _, err = io.Copy(f, resp.Body); err != nil {
+		if _, err = io.Copy(f, body); err != nil {
 			return err
 		}
 		fi, err := os.Stat(fullDstFileName)
@@ -802,17 +878,7 @@ func (util *snowflakeGcsClient) extractBucketNameAndPath(location string) *gcsLo
 func (util *snowflakeGcsClient) generateFileURL(stageInfo *execResponseStageInfo, filename string) (result *url.URL, err error) {
 	gcsLoc := util.extractBucketNameAndPath(stageInfo.Location)
 	fullFilePath := gcsLoc.path + filename
-	endPoint := "https://storage.googleapis.com"
-
-	// TODO: SNOW-1789759 hardcoded region will be replaced in the future
-	isRegionalURLEnabled := (strings.ToLower(stageInfo.Region) == gcsRegionMeCentral2) || stageInfo.UseRegionalURL
-	if stageInfo.EndPoint != "" {
-		endPoint = fmt.Sprintf("https://%s", stageInfo.EndPoint)
-	} else if stageInfo.UseVirtualURL {
-		endPoint = fmt.Sprintf("https://%s.storage.googleapis.com", gcsLoc.bucketName)
-	} else if stageInfo.Region != "" && isRegionalURLEnabled {
-		endPoint = fmt.Sprintf("https://storage.%s.rep.googleapis.com", strings.ToLower(stageInfo.Region))
-	}
+	endPoint := util.endPoint(stageInfo, gcsLoc, stageInfo.UseVirtualURL)
 
 	if stageInfo.UseVirtualURL {
 		result, err = url.Parse(endPoint + "/" + url.PathEscape(fullFilePath))
@@ -823,6 +889,27 @@ func (util *snowflakeGcsClient) generateFileURL(stageInfo *execResponseStageInfo
 	return result, err
 }
 
+// generateMetadataURL returns the JSON API URL of an object, which is not
+// served from the virtual host of the bucket.
+func (util *snowflakeGcsClient) generateMetadataURL(stageInfo *execResponseStageInfo, filename string) (*url.URL, error) {
+	gcsLoc := util.extractBucketNameAndPath(stageInfo.Location)
+	endPoint := util.endPoint(stageInfo, gcsLoc, false)
+	return url.Parse(endPoint + "/storage/v1/b/" + gcsLoc.bucketName + "/o/" + url.PathEscape(gcsLoc.path+filename))
+}
+
+func (util *snowflakeGcsClient) endPoint(stageInfo *execResponseStageInfo, gcsLoc *gcsLocation, useVirtualURL bool) string {
+	// TODO: SNOW-1789759 hardcoded region will be replaced in the future
+	isRegionalURLEnabled := (strings.ToLower(stageInfo.Region) == gcsRegionMeCentral2) || stageInfo.UseRegionalURL
+	if stageInfo.EndPoint != "" {
+		return fmt.Sprintf("https://%s", stageInfo.EndPoint)
+	} else if useVirtualURL {
+		return fmt.Sprintf("https://%s.storage.googleapis.com", gcsLoc.bucketName)
+	} else if stageInfo.Region != "" && isRegionalURLEnabled {
+		return fmt.Sprintf("https://storage.%s.rep.googleapis.com", strings.ToLower(stageInfo.Region))
+	}
+	return "https://storage.googleapis.com"
+}
+
 func (util *snowflakeGcsClient) isTokenExpired(resp *http.Response) bool {
 	return resp.StatusCode == 401
 }
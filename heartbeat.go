@@ -7,6 +7,7 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
 	"time"
 )
 
@@ -107,6 +108,14 @@ func (hc *heartbeat) heartbeatMain() error {
 			logger.WithContext(ctx).Errorf("failed to decode heartbeat response JSON. err: %v", err)
 			return err
 		}
+		if respd.Code == strconv.Itoa(ErrSessionGone) {
+			hc.restful.session.markLost()
+			return &SnowflakeError{
+				Number:   ErrSessionGone,
+				SQLState: SQLStateConnectionFailure,
+				Message:  respd.Message,
+			}
+		}
 		if respd.Code == sessionExpiredCode {
 			logger.WithContext(ctx).Info("Snowflake returned 'session expired', trying to renew expired token.")
 			err = hc.restful.renewExpiredSessionToken(context.Background(), timeout, token)
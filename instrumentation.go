@@ -0,0 +1,213 @@
+package gosnowflake
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/snowflakedb/gosnowflake"
+
+// OpenTelemetry span names emitted by the driver.
+const (
+	spanNameExec          = "snowflake.exec"
+	spanNameAuthenticate  = "snowflake.authenticate"
+	spanNameRenewSession  = "snowflake.renew_session"
+	spanNameChunkDownload = "snowflake.chunk_download"
+	spanNameFileUpload    = "snowflake.file_upload"
+)
+
+// OpenTelemetry attribute keys attached to driver spans and metrics.
+const (
+	attrOperation  = attribute.Key("snowflake.operation")
+	attrQueryID    = attribute.Key("snowflake.query_id")
+	attrRequestID  = attribute.Key("snowflake.request_id")
+	attrSessionID  = attribute.Key("snowflake.session_id")
+	attrRetryCount = attribute.Key("snowflake.retry_count")
+	attrBytes      = attribute.Key("snowflake.bytes")
+	attrChunkIndex = attribute.Key("snowflake.chunk_index")
+	attrRowCount   = attribute.Key("snowflake.row_count")
+	attrFileName   = attribute.Key("snowflake.file_name")
+	attrAuthType   = attribute.Key("snowflake.authenticator")
+	attrHTTPPath   = attribute.Key("url.path")
+	attrHTTPStatus = attribute.Key("http.response.status_code")
+	attrDBSystem   = attribute.Key("db.system")
+	attrDBQuery    = attribute.Key("db.query.text")
+	attrAdmission  = attribute.Key("snowflake.admission.key")
+)
+
+var (
+	defaultInstrumentation     *instrumentation
+	defaultInstrumentationOnce sync.Once
+)
+
+type instrumentationKey struct{}
+
+// instrumentation bundles the tracer and the metric instruments used by a connection.
+type instrumentation struct {
+	tracer    trace.Tracer
+	duration  metric.Float64Histogram
+	retries   metric.Int64Counter
+	transfers metric.Int64Counter
+	admission metric.Float64Histogram
+}
+
+func newInstrumentation(tp trace.TracerProvider, mp metric.MeterProvider) *instrumentation {
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+	if mp == nil {
+		mp = otel.GetMeterProvider()
+	}
+	meter := mp.Meter(instrumentationName, metric.WithInstrumentationVersion(SnowflakeGoDriverVersion))
+	instr := &instrumentation{
+		tracer: tp.Tracer(instrumentationName, trace.WithInstrumentationVersion(SnowflakeGoDriverVersion)),
+	}
+	var err error
+	if instr.duration, err = meter.Float64Histogram("snowflake.client.operation.duration",
+		metric.WithDescription("Duration of driver operations such as queries, logins and chunk downloads."),
+		metric.WithUnit("s")); err != nil {
+		logger.Warnf("failed to create duration histogram. err: %v", err)
+	}
+	if instr.retries, err = meter.Int64Counter("snowflake.client.retries",
+		metric.WithDescription("Number of HTTP requests retried by the driver."),
+		metric.WithUnit("{retry}")); err != nil {
+		logger.Warnf("failed to create retries counter. err: %v", err)
+	}
+	if instr.transfers, err = meter.Int64Counter("snowflake.client.transferred_bytes",
+		metric.WithDescription("Number of bytes downloaded from or uploaded to cloud storage."),
+		metric.WithUnit("By")); err != nil {
+		logger.Warnf("failed to create transferred bytes counter. err: %v", err)
+	}
+	if instr.admission, err = meter.Float64Histogram("snowflake.client.admission.wait_time",
+		metric.WithDescription("Time queries waited for Config.AdmissionController before being sent."),
+		metric.WithUnit("s")); err != nil {
+		logger.Warnf("failed to create admission wait histogram. err: %v", err)
+	}
+	return instr
+}
+
+// instrumentationFor returns the instrumentation for the given config.
+// The global OpenTelemetry providers are used unless the config overrides them.
+func instrumentationFor(cfg *Config) *instrumentation {
+	if cfg != nil && (cfg.TracerProvider != nil || cfg.MeterProvider != nil) {
+		return newInstrumentation(cfg.TracerProvider, cfg.MeterProvider)
+	}
+	defaultInstrumentationOnce.Do(func() {
+		defaultInstrumentation = newInstrumentation(nil, nil)
+	})
+	return defaultInstrumentation
+}
+
+// instrumentationFromContext returns the instrumentation of the operation started in ctx, if any.
+func instrumentationFromContext(ctx context.Context) *instrumentation {
+	if instr, ok := ctx.Value(instrumentationKey{}).(*instrumentation); ok {
+		return instr
+	}
+	return instrumentationFor(nil)
+}
+
+// instrumentedOperation is a single traced and measured driver operation.
+type instrumentedOperation struct {
+	instr     *instrumentation
+	span      trace.Span
+	operation string
+	start     time.Time
+}
+
+// startOperation starts a span as a child of any span carried by ctx and returns
+// a context which carries both the span and the instrumentation.
+func (instr *instrumentation) startOperation(ctx context.Context, spanName, operation string, attrs ...attribute.KeyValue) (context.Context, *instrumentedOperation) {
+	attrs = append(attrs, attrDBSystem.String("snowflake"), attrOperation.String(operation))
+	ctx, span := instr.tracer.Start(ctx, spanName, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+	ctx = context.WithValue(ctx, instrumentationKey{}, instr)
+	return ctx, &instrumentedOperation{
+		instr:     instr,
+		span:      span,
+		operation: operation,
+		start:     time.Now(),
+	}
+}
+
+func (op *instrumentedOperation) setAttributes(attrs ...attribute.KeyValue) {
+	op.span.SetAttributes(attrs...)
+}
+
+// recordBytes adds the number of transferred bytes to the span and the transfer counter.
+func (op *instrumentedOperation) recordBytes(ctx context.Context, n int64) {
+	op.span.SetAttributes(attrBytes.Int64(n))
+	if op.instr.transfers != nil {
+		op.instr.transfers.Add(ctx, n, metric.WithAttributes(attrOperation.String(op.operation)))
+	}
+}
+
+// recordAdmissionWait adds the time the query waited for admission to the span and the admission histogram.
+// A nil error means the query was admitted.
+func (op *instrumentedOperation) recordAdmissionWait(ctx context.Context, key string, wait time.Duration, err error) {
+	op.span.AddEvent("admission", trace.WithAttributes(
+		attrAdmission.String(key),
+		attribute.Float64("wait_time", wait.Seconds()),
+		attribute.Bool("admitted", err == nil)))
+	if op.instr.admission != nil {
+		op.instr.admission.Record(ctx, wait.Seconds(), metric.WithAttributes(
+			attrAdmission.String(key),
+			attribute.Bool("admitted", err == nil)))
+	}
+}
+
+// end finishes the span and records the operation duration. A nil error marks the span as successful.
+func (op *instrumentedOperation) end(ctx context.Context, err error) {
+	if err != nil {
+		op.span.RecordError(err)
+		op.span.SetStatus(codes.Error, err.Error())
+	}
+	if op.instr.duration != nil {
+		op.instr.duration.Record(ctx, time.Since(op.start).Seconds(), metric.WithAttributes(
+			attrOperation.String(op.operation),
+			attribute.Bool("error", err != nil)))
+	}
+	op.span.End()
+}
+
+// recordRetry adds a retry event to the span in ctx and increments the retry counter.
+func recordRetry(ctx context.Context, path string, retryCount int, statusCode int) {
+	span := trace.SpanFromContext(ctx)
+	span.AddEvent("retry", trace.WithAttributes(
+		attrRetryCount.Int(retryCount),
+		attrHTTPPath.String(path),
+		attrHTTPStatus.Int(statusCode)))
+	span.SetAttributes(attrRetryCount.Int(retryCount))
+	if instr := instrumentationFromContext(ctx); instr.retries != nil {
+		instr.retries.Add(ctx, 1, metric.WithAttributes(attrHTTPPath.String(path), attrHTTPStatus.Int(statusCode)))
+	}
+}
+
+// instrumentation returns the instrumentation of the connection, falling back to the global providers.
+func (sc *snowflakeConn) instrumentation() *instrumentation {
+	if sc == nil {
+		return instrumentationFor(nil)
+	}
+	if sc.instr != nil {
+		return sc.instr
+	}
+	return instrumentationFor(sc.cfg)
+}
+
+// countingReader counts the bytes read from the underlying reader.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,105 @@
+package gosnowflake
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func newRecordingConfig() (*Config, *tracetest.SpanRecorder) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := trace.NewTracerProvider(trace.WithSpanProcessor(recorder))
+	return &Config{Params: map[string]*string{}, TracerProvider: tp}, recorder
+}
+
+func spanAttribute(span trace.ReadOnlySpan, key attribute.Key) (attribute.Value, bool) {
+	for _, attr := range span.Attributes() {
+		if attr.Key == key {
+			return attr.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+func TestExecCreatesSpanAsChildOfCallerSpan(t *testing.T) {
+	cfg, recorder := newRecordingConfig()
+	sc := &snowflakeConn{
+		cfg: cfg,
+		rest: &snowflakeRestful{
+			FuncPostQuery: func(_ context.Context, _ *snowflakeRestful, _ *url.Values, _ map[string]string, _ []byte, _ time.Duration, _ UUID, _ *Config) (*execResponse, error) {
+				return &execResponse{
+					Data:    execResponseData{QueryID: "01b2c3d4-0000-0000-0000-000000000001"},
+					Code:    "0",
+					Success: true,
+				}, nil
+			},
+		},
+		queryContextCache: (&queryContextCache{}).init(),
+		instr:             instrumentationFor(cfg),
+	}
+
+	ctx, parent := cfg.TracerProvider.Tracer("test").Start(context.Background(), "parent")
+	requestID := NewUUID()
+	_, err := sc.exec(WithRequestID(ctx, requestID), "SELECT 1", false, false, false, nil)
+	assertNilF(t, err)
+	parent.End()
+
+	spans := recorder.Ended()
+	assertEqualF(t, len(spans), 2)
+	execSpan := spans[0]
+	assertEqualE(t, execSpan.Name(), spanNameExec)
+	assertEqualE(t, execSpan.Parent().SpanID(), parent.SpanContext().SpanID())
+	assertEqualE(t, execSpan.SpanContext().TraceID(), parent.SpanContext().TraceID())
+	queryID, ok := spanAttribute(execSpan, attrQueryID)
+	assertTrueE(t, ok)
+	assertEqualE(t, queryID.AsString(), "01b2c3d4-0000-0000-0000-000000000001")
+	reqID, ok := spanAttribute(execSpan, attrRequestID)
+	assertTrueE(t, ok)
+	assertEqualE(t, reqID.AsString(), requestID.String())
+	_, ok = spanAttribute(execSpan, attrDBQuery)
+	assertFalseE(t, ok, "query text should not be recorded unless query text logging is enabled")
+}
+
+func TestExecSpanRecordsError(t *testing.T) {
+	cfg, recorder := newRecordingConfig()
+	sc := &snowflakeConn{
+		cfg: cfg,
+		rest: &snowflakeRestful{
+			FuncPostQuery: func(_ context.Context, _ *snowflakeRestful, _ *url.Values, _ map[string]string, _ []byte, _ time.Duration, _ UUID, _ *Config) (*execResponse, error) {
+				return nil, errors.New("connection reset")
+			},
+		},
+		queryContextCache: (&queryContextCache{}).init(),
+		instr:             instrumentationFor(cfg),
+	}
+
+	_, err := sc.exec(context.Background(), "SELECT 1", false, false, false, nil)
+	assertNotNilF(t, err)
+
+	spans := recorder.Ended()
+	assertEqualF(t, len(spans), 1)
+	assertEqualE(t, spans[0].Status().Code, codes.Error)
+	assertEqualE(t, spans[0].Status().Description, "connection reset")
+}
+
+func TestRecordRetryAddsEventToSpan(t *testing.T) {
+	cfg, recorder := newRecordingConfig()
+	ctx, op := instrumentationFor(cfg).startOperation(context.Background(), spanNameRenewSession, "renew_session")
+	recordRetry(ctx, tokenRequestPath, 1, 503)
+	recordRetry(ctx, tokenRequestPath, 2, 503)
+	op.end(ctx, nil)
+
+	spans := recorder.Ended()
+	assertEqualF(t, len(spans), 1)
+	assertEqualE(t, len(spans[0].Events()), 2)
+	retryCount, ok := spanAttribute(spans[0], attrRetryCount)
+	assertTrueE(t, ok)
+	assertEqualE(t, retryCount.AsInt64(), int64(2))
+}
@@ -20,10 +20,10 @@ func (util *localUtil) createClient(_ *execResponseStageInfo, _ bool, _ *Config,
 
 func (util *localUtil) uploadOneFileWithRetry(meta *fileMetadata) error {
 	var frd *bufio.Reader
-	if meta.srcStream != nil {
+	if meta.uploadStream == nil && meta.srcStream != nil {
 		b := cmp.Or(meta.realSrcStream, meta.srcStream)
-		frd = bufio.NewReader(b)
-	} else {
+		frd = bufio.NewReader(meta.progressReader(b))
+	} else if meta.uploadStream == nil {
 		f, err := os.Open(meta.realSrcFileName)
 		if err != nil {
 			return err
@@ -33,7 +33,7 @@ func (util *localUtil) uploadOneFileWithRetry(meta *fileMetadata) error {
 				logger.Warnf("failed to close the file %v: %v", meta.realSrcFileName, err)
 			}
 		}()
-		frd = bufio.NewReader(f)
+		frd = bufio.NewReader(meta.progressReader(f))
 	}
 
 	user, err := expandUser(meta.stageInfo.Location)
@@ -56,6 +56,14 @@ func (util *localUtil) uploadOneFileWithRetry(meta *fileMetadata) error {
 			logger.Warnf("failed to close the file %v: %v", meta.dstFileName, err)
 		}
 	}()
+	if meta.uploadStream != nil {
+		if _, err = io.Copy(output, meta.progressReader(meta.uploadStream)); err != nil {
+			return err
+		}
+		meta.dstFileSize = meta.uploadSize
+		meta.resStatus = uploaded
+		return nil
+	}
 	data := make([]byte, meta.uploadSize)
 	for {
 		n, err := frd.Read(data)
@@ -112,6 +120,7 @@ func (util *localUtil) downloadOneFile(meta *fileMetadata) error {
 		return err
 	}
 	meta.dstFileSize = fi.Size()
+	meta.reportTotalBytes(meta.dstFileSize)
 	meta.resStatus = downloaded
 	return nil
 }
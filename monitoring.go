@@ -128,6 +128,7 @@ type SnowflakeQueryStatus struct {
 type SnowflakeConnection interface {
 	GetQueryStatus(ctx context.Context, queryID string) (*SnowflakeQueryStatus, error)
 	AddTelemetryData(ctx context.Context, eventDate time.Time, data map[string]string) error
+	CancelQueryByID(ctx context.Context, queryID string) (CancelQueryResult, error)
 }
 
 // checkQueryStatus returns the status given the query ID. If successful,
@@ -279,6 +280,15 @@ func (sc *snowflakeConn) buildRowsForRunningQuery(
 	if err := sc.rowsForRunningQuery(ctx, qid, rows); err != nil {
 		return nil, err
 	}
+	if cursor, ok := getResumeCursor(ctx); ok {
+		scd, ok := rows.ChunkDownloader.(*snowflakeChunkDownloader)
+		if !ok {
+			return nil, errInvalidResultCursor(qid, "cursors are not supported for this result")
+		}
+		if err := scd.resumeAt(cursor.ChunkIndex, cursor.RowOffset); err != nil {
+			return nil, err
+		}
+	}
 	err := rows.ChunkDownloader.start()
 	return rows, err
 }
@@ -0,0 +1,93 @@
+package gosnowflake
+
+import (
+	"context"
+	"database/sql/driver"
+)
+
+// SessionMultiplexer runs queries concurrently on the session of one connection, which saves the login and the
+// heartbeat of every connection of a pool. Each query has its own request ID and result downloader, while the
+// session token, its renewal and the query context cache are shared. Get it with
+// SessionMultiplexerConnection.SessionMultiplexer inside sql.Conn.Raw, and only use it until the function passed
+// to Raw returns:
+//
+//	err := conn.Raw(func(x any) error {
+//		mux := x.(sf.SessionMultiplexerConnection).SessionMultiplexer(4)
+//		g, ctx := errgroup.WithContext(ctx)
+//		for _, query := range queries {
+//			g.Go(func() error {
+//				rows, err := mux.QueryContext(ctx, query)
+//				...
+//			})
+//		}
+//		return g.Wait()
+//	})
+//
+// As the queries share the session, a transaction, USE or ALTER SESSION statement run by one of them applies
+// to all of them.
+type SessionMultiplexer struct {
+	sc *snowflakeConn
+	// slots limits the number of queries running at once, nil if unlimited.
+	slots chan struct{}
+}
+
+// SessionMultiplexerConnection is implemented by the connections of the driver, which can be reached with sql.Conn.Raw.
+type SessionMultiplexerConnection interface {
+	SessionMultiplexer(maxConcurrent int) *SessionMultiplexer
+}
+
+// SessionMultiplexer returns a SessionMultiplexer running at most maxConcurrent queries at once on the session
+// of the connection, or any number of queries if maxConcurrent is not positive.
+func (sc *snowflakeConn) SessionMultiplexer(maxConcurrent int) *SessionMultiplexer {
+	mux := &SessionMultiplexer{sc: sc}
+	if maxConcurrent > 0 {
+		mux.slots = make(chan struct{}, maxConcurrent)
+	}
+	return mux
+}
+
+// QueryContext runs a query on the session, waiting for a free slot if maxConcurrent queries are running.
+// The rows can be read after the next queries started.
+func (mux *SessionMultiplexer) QueryContext(ctx context.Context, query string, args ...any) (driver.Rows, error) {
+	bindings, err := mux.acquire(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	defer mux.release()
+	return mux.sc.QueryContext(ctx, query, bindings)
+}
+
+// ExecContext runs a statement on the session, waiting for a free slot if maxConcurrent queries are running.
+func (mux *SessionMultiplexer) ExecContext(ctx context.Context, query string, args ...any) (driver.Result, error) {
+	bindings, err := mux.acquire(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	defer mux.release()
+	return mux.sc.ExecContext(ctx, query, bindings)
+}
+
+func (mux *SessionMultiplexer) acquire(ctx context.Context, args []any) ([]driver.NamedValue, error) {
+	if mux.sc.rest == nil {
+		return nil, driver.ErrBadConn
+	}
+	bindings, err := mux.sc.namedValues(args)
+	if err != nil {
+		return nil, err
+	}
+	if mux.slots == nil {
+		return bindings, nil
+	}
+	select {
+	case mux.slots <- struct{}{}:
+		return bindings, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (mux *SessionMultiplexer) release() {
+	if mux.slots != nil {
+		<-mux.slots
+	}
+}
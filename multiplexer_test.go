@@ -0,0 +1,104 @@
+package gosnowflake
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestUnitSessionMultiplexer(t *testing.T) {
+	var mu sync.Mutex
+	requestIDs := make(map[UUID]bool)
+	var running, maxRunning atomic.Int32
+	postQueryMock := func(_ context.Context, _ *snowflakeRestful, _ *url.Values, _ map[string]string, body []byte,
+		_ time.Duration, requestID UUID, _ *Config) (*execResponse, error) {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			m := maxRunning.Load()
+			if n <= m || maxRunning.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		var req execRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			return nil, err
+		}
+		mu.Lock()
+		requestIDs[requestID] = true
+		mu.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		value := req.Bindings["1"].Value.(string)
+		queryContext, _ := json.Marshal(queryContext{Entries: []queryContextEntry{{ID: 0, Timestamp: time.Now().UnixNano(), Priority: 0}}})
+		return &execResponse{Data: execResponseData{
+			QueryID:           NewUUID().String(),
+			StatementTypeID:   statementTypeIDSelect,
+			QueryResultFormat: "json",
+			RowType:           []execResponseRowType{{Name: "V", Type: "text"}},
+			RowSet:            [][]*string{{&value}},
+			Total:             1,
+			Returned:          1,
+			FinalRoleName:     "R" + value,
+			QueryContext:      queryContext,
+		}, Success: true}, nil
+	}
+	sc := &snowflakeConn{
+		cfg:                 &Config{Params: map[string]*string{}},
+		rest:                &snowflakeRestful{FuncPostQuery: postQueryMock, TokenAccessor: getSimpleTokenAccessor()},
+		telemetry:           &snowflakeTelemetry{enabled: false},
+		queryContextCache:   (&queryContextCache{}).init(),
+		currentTimeProvider: defaultTimeProvider,
+	}
+	var conn SessionMultiplexerConnection = sc
+	mux := conn.SessionMultiplexer(3)
+	ctx := context.Background()
+
+	const queries = 12
+	var wg sync.WaitGroup
+	results := make([]string, queries)
+	errs := make([]error, queries)
+	for i := range queries {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rows, err := mux.QueryContext(ctx, "SELECT ?", fmt.Sprint(i))
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			defer rows.Close()
+			dest := make([]driver.Value, 1)
+			if errs[i] = rows.Next(dest); errs[i] == nil {
+				results[i] = dest[0].(string)
+				errs[i] = rows.Next(dest)
+				if errs[i] == io.EOF {
+					errs[i] = nil
+				}
+			}
+			_ = sc.SessionState()
+		}()
+	}
+	wg.Wait()
+	for i := range queries {
+		assertNilE(t, errs[i])
+		assertEqualE(t, results[i], fmt.Sprint(i))
+	}
+	assertEqualE(t, len(requestIDs), queries, "every query should have its own request ID")
+	assertEqualE(t, maxRunning.Load(), int32(3))
+	assertEqualE(t, len(sc.queryContextCache.entries), 1)
+
+	for range 3 {
+		mux.slots <- struct{}{}
+	}
+	cancelCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	_, err := mux.ExecContext(cancelCtx, "SELECT 1")
+	assertErrIsE(t, err, context.DeadlineExceeded)
+}
@@ -3,9 +3,11 @@ package gosnowflake
 import (
 	"context"
 	"database/sql/driver"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type childResult struct {
@@ -102,3 +104,437 @@ func (sc *snowflakeConn) handleMultiQuery(
 	}
 	return nil
 }
+
+// MultiStatementConnection is implemented by the connections of the driver, which can be reached with sql.Conn.Raw.
+type MultiStatementConnection interface {
+	ExecMultiStatement(ctx context.Context, query string, args []driver.NamedValue, options *MultiStatementOptions) (*MultiStatementResult, error)
+}
+
+// MultiStatementOptions configures MultiStatementConnection.ExecMultiStatement.
+type MultiStatementOptions struct {
+	// ContinueOnError runs the statements after a failed one and reports the error of each failed statement.
+	// The driver then splits the query into statements and sends them one at a time, instead of sending the
+	// query as a single multi-statement request that stops at the first error. Only positional ? bind
+	// variables are supported.
+	ContinueOnError bool
+}
+
+// StatementResult is the result of one statement of a multi-statement query.
+type StatementResult struct {
+	QueryID string
+	SQLText string
+	// StatementTypeID is the type of the statement as reported by Snowflake, e.g. 0x1000 for SELECT.
+	StatementTypeID int64
+	// RowsAffected is the number of rows inserted, updated or deleted by a DML statement.
+	RowsAffected int64
+	// Rows are the rows returned by any statement other than DML, e.g. the status message of DDL.
+	// They also implement SnowflakeRows and are closed by MultiStatementResult.Close.
+	Rows driver.Rows
+	// StartTime and EndTime are when Snowflake ran the statement, zero if they could not be retrieved.
+	StartTime time.Time
+	EndTime   time.Time
+	// Err is the error of a failed statement.
+	Err *SnowflakeError
+}
+
+// Elapsed returns how long the statement ran, or 0 if it is not known.
+func (sr *StatementResult) Elapsed() time.Duration {
+	if sr.StartTime.IsZero() || sr.EndTime.IsZero() {
+		return 0
+	}
+	return sr.EndTime.Sub(sr.StartTime)
+}
+
+// MultiStatementResult is the result of each statement of a multi-statement query, in order.
+type MultiStatementResult struct {
+	// QueryID is the ID of the multi-statement query, empty with ContinueOnError.
+	QueryID    string
+	Statements []StatementResult
+}
+
+// Err returns the error of the first failed statement, or nil if all of them succeeded.
+func (mr *MultiStatementResult) Err() error {
+	for _, sr := range mr.Statements {
+		if sr.Err != nil {
+			return sr.Err
+		}
+	}
+	return nil
+}
+
+// RowsAffected returns the number of rows affected by all statements.
+func (mr *MultiStatementResult) RowsAffected() int64 {
+	var count int64
+	for _, sr := range mr.Statements {
+		count += sr.RowsAffected
+	}
+	return count
+}
+
+// Close closes the rows of all statements.
+func (mr *MultiStatementResult) Close() error {
+	var errs []error
+	for _, sr := range mr.Statements {
+		if sr.Rows != nil {
+			errs = append(errs, sr.Rows.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ExecMultiStatement runs the statements of query and returns the result of each of them.
+// Without ContinueOnError, the number of statements can be set with WithMultiStatement and defaults to any number,
+// and the query stops at the first failed statement. The results of the statements that ran and the error of the
+// failed one are then returned along with that error, and must be closed as well.
+func (sc *snowflakeConn) ExecMultiStatement(
+	ctx context.Context,
+	query string,
+	args []driver.NamedValue,
+	options *MultiStatementOptions) (
+	*MultiStatementResult, error) {
+	if sc.rest == nil {
+		return nil, driver.ErrBadConn
+	}
+	if options == nil {
+		options = &MultiStatementOptions{}
+	}
+	_, _, sessionID := safeGetTokens(sc.rest)
+	ctx = setResultType(context.WithValue(ctx, SFSessionIDKey, sessionID), queryResultType)
+	if options.ContinueOnError {
+		return sc.execStatements(ctx, splitStatements(query), args, true, true)
+	}
+	if ctx.Value(multiStatementCount) == nil {
+		ctx = context.WithValue(ctx, multiStatementCount, 0)
+	}
+	data, err := sc.exec(ctx, query, false, false, false, args)
+	if err != nil {
+		logger.WithContext(ctx).Errorf("error: %v", err)
+		var se *SnowflakeError
+		if !errors.As(err, &se) || se.QueryID == "" {
+			return nil, err
+		}
+		return sc.failedMultiStatementResult(ctx, se)
+	}
+	mr := &MultiStatementResult{QueryID: data.Data.QueryID}
+	if !isMultiStmt(&data.Data) {
+		sr := StatementResult{SQLText: query}
+		if err = sc.fillStatementResult(ctx, &sr, data.Data, true); err != nil {
+			return nil, err
+		}
+		mr.Statements = append(mr.Statements, sr)
+		return mr, nil
+	}
+	if data.Data.ResultIDs == "" {
+		return nil, (&SnowflakeError{
+			Number:   ErrNoResultIDs,
+			SQLState: data.Data.SQLState,
+			Message:  errMsgNoResultIDs,
+			QueryID:  data.Data.QueryID,
+		}).exceptionTelemetry(sc)
+	}
+	if err = sc.addChildResults(ctx, mr, data.Data.ResultIDs, data.Data.ResultTypes); err != nil {
+		return mr, err
+	}
+	return mr, mr.Err()
+}
+
+// failedMultiStatementResult returns the results of the statements of a failed multi-statement query that ran
+// before the failed one, if the server reports them, and the failed statement with the error of the query.
+func (sc *snowflakeConn) failedMultiStatementResult(ctx context.Context, se *SnowflakeError) (*MultiStatementResult, error) {
+	mr := &MultiStatementResult{QueryID: se.QueryID}
+	resp, err := sc.getQueryResultResp(ctx, fmt.Sprintf(urlQueriesResultFmt, se.QueryID))
+	if err != nil {
+		logger.WithContext(ctx).Debugf("failed to get the statements of multi-statement query %v: %v", se.QueryID, err)
+	} else if resp.Data.ResultIDs != "" {
+		if err = sc.addChildResults(ctx, mr, resp.Data.ResultIDs, resp.Data.ResultTypes); err != nil {
+			return mr, errors.Join(se, err)
+		}
+	}
+	if mr.Err() == nil {
+		mr.Statements = append(mr.Statements, StatementResult{QueryID: se.QueryID, Err: se})
+	}
+	return mr, se
+}
+
+// addChildResults adds the result of each child query of a multi-statement query to mr.
+func (sc *snowflakeConn) addChildResults(ctx context.Context, mr *MultiStatementResult, resultIDs, resultTypes string) error {
+	for _, child := range getChildResults(resultIDs, resultTypes) {
+		sr := StatementResult{QueryID: child.id}
+		var err error
+		if sr.StatementTypeID, err = strconv.ParseInt(child.typ, 10, 64); err != nil {
+			return err
+		}
+		resp, err := sc.getQueryResultResp(ctx, fmt.Sprintf(urlQueriesResultFmt, child.id))
+		if err != nil {
+			logger.WithContext(ctx).Errorf("error: %v", err)
+			return err
+		}
+		if resp.Success {
+			err = sc.fillStatementResult(ctx, &sr, resp.Data, true)
+		} else {
+			err = sc.statementError(&sr, resp)
+		}
+		if err != nil {
+			return err
+		}
+		mr.Statements = append(mr.Statements, sr)
+	}
+	return nil
+}
+
+// execStatements runs each of the statements in the session. With continueOnError, the statements after a
+// failed one run as well. The rows returned by statements are only kept withRows.
+func (sc *snowflakeConn) execStatements(
+	ctx context.Context,
+	statements []splitStatement,
+	args []driver.NamedValue,
+	continueOnError bool,
+	withRows bool) (
+	*MultiStatementResult, error) {
+	binds, positional := 0, true
+	for _, stmt := range statements {
+		binds += stmt.binds
+	}
+	for _, arg := range args {
+		positional = positional && arg.Name == ""
+	}
+	if binds != len(args) || !positional {
+		return nil, (&SnowflakeError{
+			Number:      ErrMultiStatementBindings,
+			Message:     errMsgMultiStatementBindings,
+			MessageArgs: []interface{}{len(args), binds},
+		}).exceptionTelemetry(sc)
+	}
+	// each statement is sent on its own
+	ctx = context.WithValue(ctx, multiStatementCount, 1)
+	mr := &MultiStatementResult{}
+	for _, stmt := range statements {
+		sr := StatementResult{SQLText: stmt.text}
+		data, err := sc.exec(ctx, stmt.text, false, false, false, args[:stmt.binds])
+		args = args[stmt.binds:]
+		if err == nil {
+			err = sc.fillStatementResult(ctx, &sr, data.Data, withRows)
+		} else if errors.As(err, &sr.Err) {
+			sr.QueryID = sr.Err.QueryID
+			sc.fillStatementTimes(ctx, &sr)
+			err = nil
+		}
+		if err != nil {
+			logger.WithContext(ctx).Errorf("error: %v", err)
+			return nil, errors.Join(err, mr.Close())
+		}
+		mr.Statements = append(mr.Statements, sr)
+		if sr.Err != nil {
+			if !continueOnError {
+				logger.WithContext(ctx).Warnf("statement failed, skipping the remaining statements: %v", sr.Err)
+				break
+			}
+			logger.WithContext(ctx).Warnf("statement failed, continuing with the next one: %v", sr.Err)
+		}
+	}
+	return mr, nil
+}
+
+// fillStatementResult sets the rows affected by a statement that succeeded, or the rows it returned withRows.
+func (sc *snowflakeConn) fillStatementResult(ctx context.Context, sr *StatementResult, data execResponseData, withRows bool) error {
+	if data.QueryID != "" {
+		sr.QueryID = data.QueryID
+	}
+	sr.StatementTypeID = data.StatementTypeID
+	if isDml(data.StatementTypeID) {
+		count, err := updateRows(data)
+		if err != nil {
+			return err
+		}
+		sr.RowsAffected = count
+	} else if withRows {
+		rows, err := sc.rowsForResponse(ctx, data)
+		if err != nil {
+			return err
+		}
+		sr.Rows = rows
+	}
+	sc.fillStatementTimes(ctx, sr)
+	return nil
+}
+
+// statementError sets the error of a statement whose result is a failure.
+func (sc *snowflakeConn) statementError(sr *StatementResult, resp *execResponse) error {
+	code, err := strconv.Atoi(resp.Code)
+	if err != nil {
+		return err
+	}
+	if resp.Data.QueryID != "" {
+		sr.QueryID = resp.Data.QueryID
+	}
+	sr.Err = (&SnowflakeError{
+		Number:   code,
+		SQLState: resp.Data.SQLState,
+		Message:  resp.Message,
+		QueryID:  sr.QueryID,
+	}).exceptionTelemetry(sc)
+	return nil
+}
+
+// fillStatementTimes sets when a statement ran, and its text if not known, from the query monitoring endpoint.
+func (sc *snowflakeConn) fillStatementTimes(ctx context.Context, sr *StatementResult) {
+	if sr.QueryID == "" {
+		return
+	}
+	status, err := sc.checkQueryStatus(ctx, sr.QueryID)
+	if status == nil {
+		logger.WithContext(ctx).Debugf("failed to get the status of query %v: %v", sr.QueryID, err)
+		return
+	}
+	if sr.SQLText == "" {
+		sr.SQLText = status.SQLText
+	}
+	if status.StartTime > 0 {
+		sr.StartTime = time.UnixMilli(status.StartTime)
+	}
+	if status.EndTime > 0 {
+		sr.EndTime = time.UnixMilli(status.EndTime)
+	}
+}
+
+// splitStatement is a statement of a query and the number of ? bind variables in it.
+type splitStatement struct {
+	text  string
+	binds int
+}
+
+// splitStatements splits query at the semicolons outside of string literals, quoted identifiers,
+// comments, $$ blocks and Snowflake Scripting blocks. Statements with only white space and comments are dropped.
+func splitStatements(query string) []splitStatement {
+	var statements []splitStatement
+	var firstWord, prevWord string
+	start, binds, hasCode := 0, 0, false
+	// depth is the number of open BEGIN ... END blocks and CASE ... END in them,
+	// declares the number of DECLARE sections waiting for the BEGIN of their block
+	depth, declares := 0, 0
+	add := func(end int) {
+		if hasCode {
+			statements = append(statements, splitStatement{strings.TrimSpace(query[start:end]), binds})
+		}
+		start, binds, hasCode, firstWord, prevWord = end+1, 0, false, "", ""
+	}
+	for i := 0; i < len(query); i++ {
+		switch c := query[i]; {
+		case c == ';':
+			if depth == 0 {
+				add(i)
+				continue
+			}
+			prevWord = ""
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			continue
+		case strings.HasPrefix(query[i:], "--") || strings.HasPrefix(query[i:], "//"):
+			i = skipUntil(query, i, "\n") - 1
+			continue
+		case strings.HasPrefix(query[i:], "/*"):
+			i = skipUntil(query, i+2, "*/") - 1
+			continue
+		case strings.HasPrefix(query[i:], "$$"):
+			i = skipUntil(query, i+2, "$$") - 1
+		case c == '\'' || c == '"':
+			i = skipQuoted(query, i)
+		case c == '?':
+			binds++
+		case isWordStart(c):
+			end := skipWord(query, i)
+			word := strings.ToUpper(query[i:end])
+			if !hasCode {
+				firstWord = word
+			}
+			// blocks start a statement, are nested in a block or are the body of a procedure
+			opensBlock := !hasCode || depth > 0 || firstWord == "CREATE" && prevWord == "AS"
+			switch {
+			case word == "DECLARE" && opensBlock:
+				depth++
+				declares++
+			case word == "BEGIN" && declares > 0:
+				declares--
+			case word == "BEGIN" && opensBlock:
+				// BEGIN [ TRANSACTION | WORK | NAME ] starts a transaction, not a block
+				switch peekWord(query, end) {
+				case "", "TRANSACTION", "WORK", "NAME":
+				default:
+					depth++
+				}
+			case word == "CASE" && depth > 0 && prevWord != "END":
+				depth++
+			case word == "END" && depth > 0:
+				// END IF, END FOR, END LOOP, END WHILE and END REPEAT close statements that are not counted
+				switch peekWord(query, end) {
+				case "IF", "FOR", "LOOP", "WHILE", "REPEAT":
+				default:
+					depth--
+				}
+			}
+			prevWord = word
+			i = end - 1
+		}
+		hasCode = true
+	}
+	add(len(query))
+	return statements
+}
+
+func isWordStart(c byte) bool {
+	return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+// skipWord returns the index after the identifier or keyword at i.
+func skipWord(query string, i int) int {
+	for ; i < len(query); i++ {
+		if c := query[i]; !isWordStart(c) && c != '$' && (c < '0' || c > '9') {
+			break
+		}
+	}
+	return i
+}
+
+// peekWord returns the upper case keyword or identifier following i after white space and comments,
+// or an empty string if something else follows.
+func peekWord(query string, i int) string {
+	for i < len(query) {
+		switch {
+		case query[i] == ' ' || query[i] == '\t' || query[i] == '\n' || query[i] == '\r':
+			i++
+		case strings.HasPrefix(query[i:], "--") || strings.HasPrefix(query[i:], "//"):
+			i = skipUntil(query, i, "\n")
+		case strings.HasPrefix(query[i:], "/*"):
+			i = skipUntil(query, i+2, "*/")
+		case isWordStart(query[i]):
+			return strings.ToUpper(query[i:skipWord(query, i)])
+		default:
+			return ""
+		}
+	}
+	return ""
+}
+
+// skipUntil returns the index after the first occurrence of end in query from i, or the length of query.
+func skipUntil(query string, i int, end string) int {
+	if j := strings.Index(query[i:], end); j >= 0 {
+		return i + j + len(end)
+	}
+	return len(query)
+}
+
+// skipQuoted returns the index of the quote closing the one at i, or the length of query.
+// A backslash escapes the next character in string literals, and two quotes are read as two literals.
+func skipQuoted(query string, i int) int {
+	quote := query[i]
+	for i++; i < len(query); i++ {
+		switch query[i] {
+		case '\\':
+			if quote == '\'' {
+				i++
+			}
+		case quote:
+			return i
+		}
+	}
+	return len(query)
+}
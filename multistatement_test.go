@@ -1,7 +1,9 @@
 package gosnowflake
 
 import (
+	"bytes"
 	"context"
+	"database/sql/driver"
 	"encoding/json"
 	"errors"
 	"io"
@@ -9,6 +11,7 @@ import (
 	"net/url"
 	"os"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -625,3 +628,233 @@ func TestMultiStatementArrowFormat(t *testing.T) {
 
 	})
 }
+
+func TestExecMultiStatementResults(t *testing.T) {
+	runDBTest(t, func(dbt *DBTest) {
+		query := "create or replace temporary table test_multi_result (cola int);\n" +
+			"insert into test_multi_result values (1), (2);\n" +
+			"select cola from test_multi_result order by cola;\n" +
+			"insert into missing_table values (3);\n" +
+			"select count(*) from test_multi_result;"
+		err := dbt.conn.Raw(func(x any) error {
+			res, err := x.(MultiStatementConnection).ExecMultiStatement(context.Background(), query, nil,
+				&MultiStatementOptions{ContinueOnError: true})
+			assertNilF(t, err)
+			defer func() {
+				assertNilE(t, res.Close())
+			}()
+			assertEqualF(t, len(res.Statements), 5)
+			assertEqualE(t, res.Statements[1].RowsAffected, int64(2))
+			assertNotNilF(t, res.Statements[2].Rows)
+			assertNotNilF(t, res.Statements[3].Err)
+			assertNotNilE(t, res.Statements[4].Rows, "statements after a failed one should run")
+			assertEqualE(t, res.RowsAffected(), int64(2))
+			for _, sr := range res.Statements {
+				assertTrueE(t, sr.QueryID != "")
+			}
+
+			stopped, err := x.(MultiStatementConnection).ExecMultiStatement(context.Background(), query, nil, nil)
+			assertNotNilE(t, err, "the multi-statement query should stop at the failed statement")
+			assertNotNilF(t, stopped)
+			assertNotNilE(t, stopped.Err())
+			assertNilE(t, stopped.Close())
+			return nil
+		})
+		assertNilF(t, err)
+	})
+}
+
+func newMultiStatementTestConn(t *testing.T, postQuery func(context.Context, *snowflakeRestful, *url.Values,
+	map[string]string, []byte, time.Duration, UUID, *Config) (*execResponse, error)) *snowflakeConn {
+	getMock := func(_ context.Context, _ *snowflakeRestful, u *url.URL, _ map[string]string, _ time.Duration) (*http.Response, error) {
+		var resp any
+		switch u.Path {
+		case "/queries/child-1/result":
+			count := "2"
+			resp = &execResponse{Data: execResponseData{
+				QueryID:         "child-1",
+				StatementTypeID: statementTypeIDDml + 0x100,
+				RowType:         []execResponseRowType{{Name: "number of rows inserted", Type: "fixed"}},
+				RowSet:          [][]*string{{&count}},
+			}, Success: true}
+		case "/queries/child-2/result":
+			value := "a"
+			resp = &execResponse{Data: execResponseData{
+				QueryID:           "child-2",
+				StatementTypeID:   statementTypeIDSelect,
+				QueryResultFormat: "json",
+				RowType:           []execResponseRowType{{Name: "V", Type: "text"}},
+				RowSet:            [][]*string{{&value}},
+				Total:             1,
+				Returned:          1,
+			}, Success: true}
+		case "/queries/failed-parent/result":
+			resp = &execResponse{Data: execResponseData{
+				QueryID:     "failed-parent",
+				ResultIDs:   "child-1,child-3",
+				ResultTypes: "12544,12544",
+			}, Code: "2003", Message: "Table 'T' does not exist", Success: false}
+		case "/queries/child-3/result":
+			resp = &execResponse{Data: execResponseData{QueryID: "child-3", SQLState: "02000"},
+				Code: "2003", Message: "Table 'T' does not exist", Success: false}
+		case "/queries/failed-alone/result":
+			resp = &execResponse{Data: execResponseData{QueryID: "failed-alone"},
+				Code: "2003", Message: "Table 'T' does not exist", Success: false}
+		default:
+			status := &statusResponse{Success: true}
+			status.Data.Queries = []retStatus{{Status: "SUCCESS", SQLText: "sql of " + u.Path, StartTime: 1000, EndTime: 3500}}
+			resp = status
+		}
+		b, err := json.Marshal(resp)
+		assertNilF(t, err)
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewReader(b))}, nil
+	}
+	return &snowflakeConn{
+		cfg:                 &Config{Params: map[string]*string{}},
+		rest:                &snowflakeRestful{FuncPostQuery: postQuery, FuncGet: getMock, TokenAccessor: getSimpleTokenAccessor()},
+		queryContextCache:   (&queryContextCache{}).init(),
+		currentTimeProvider: defaultTimeProvider,
+	}
+}
+
+func TestUnitExecMultiStatement(t *testing.T) {
+	postQueryMock := func(_ context.Context, _ *snowflakeRestful, _ *url.Values, _ map[string]string, body []byte,
+		_ time.Duration, _ UUID, _ *Config) (*execResponse, error) {
+		var req execRequest
+		assertNilF(t, json.Unmarshal(body, &req))
+		assertEqualE(t, req.Parameters[string(multiStatementCount)], float64(0))
+		return &execResponse{Data: execResponseData{
+			QueryID:         "parent",
+			StatementTypeID: statementTypeIDMultistatement,
+			RowType:         []execResponseRowType{{Name: "multiple statement execution", Type: "text"}},
+			ResultIDs:       "child-1,child-2",
+			ResultTypes:     "12544,4096",
+		}, Success: true}, nil
+	}
+	sc := newMultiStatementTestConn(t, postQueryMock)
+
+	res, err := sc.ExecMultiStatement(context.Background(), "insert ...; select ...", nil, nil)
+	assertNilF(t, err)
+	assertEqualE(t, res.QueryID, "parent")
+	assertEqualF(t, len(res.Statements), 2)
+	assertNilE(t, res.Err())
+
+	insert := res.Statements[0]
+	assertEqualE(t, insert.QueryID, "child-1")
+	assertEqualE(t, insert.RowsAffected, int64(2))
+	assertEqualE(t, insert.SQLText, "sql of /monitoring/queries/child-1")
+	assertEqualE(t, insert.Elapsed(), 2500*time.Millisecond)
+
+	sel := res.Statements[1]
+	assertEqualE(t, sel.StatementTypeID, statementTypeIDSelect)
+	assertNotNilF(t, sel.Rows)
+	dest := make([]driver.Value, 1)
+	assertNilF(t, sel.Rows.Next(dest))
+	assertEqualE(t, dest[0], "a")
+	assertEqualE(t, sel.Rows.(SnowflakeRows).GetQueryID(), "child-2")
+	assertNilE(t, res.Close())
+}
+
+func TestUnitExecMultiStatementContinueOnError(t *testing.T) {
+	var queries []string
+	postQueryMock := func(_ context.Context, _ *snowflakeRestful, _ *url.Values, _ map[string]string, body []byte,
+		_ time.Duration, _ UUID, _ *Config) (*execResponse, error) {
+		var req execRequest
+		assertNilF(t, json.Unmarshal(body, &req))
+		queries = append(queries, req.SQLText)
+		if strings.Contains(req.SQLText, "DROP TABLE") {
+			return &execResponse{Data: execResponseData{QueryID: "failed", SQLState: "02000"},
+				Code: "2003", Message: "Table 'T' does not exist", Success: false}, nil
+		}
+		value := "0"
+		if binding, ok := req.Bindings["1"]; ok {
+			value = binding.Value.(string)
+		}
+		return &execResponse{Data: execResponseData{
+			QueryID:         "q" + value,
+			StatementTypeID: statementTypeIDDml + 0x100,
+			RowType:         []execResponseRowType{{Name: "number of rows inserted", Type: "fixed"}},
+			RowSet:          [][]*string{{&value}},
+		}, Success: true}, nil
+	}
+	sc := newMultiStatementTestConn(t, postQueryMock)
+	query := "INSERT INTO t VALUES (?);\n-- drop it; or not\nDROP TABLE t;\nINSERT INTO t SELECT ';' || ?; ;"
+
+	res, err := sc.ExecMultiStatement(context.Background(), query,
+		[]driver.NamedValue{{Ordinal: 1, Value: "1"}, {Ordinal: 2, Value: "3"}},
+		&MultiStatementOptions{ContinueOnError: true})
+	assertNilF(t, err)
+	assertDeepEqualE(t, queries, []string{"INSERT INTO t VALUES (?)", "-- drop it; or not\nDROP TABLE t", "INSERT INTO t SELECT ';' || ?"})
+	assertEqualF(t, len(res.Statements), 3)
+	assertEqualE(t, res.Statements[0].RowsAffected, int64(1))
+	assertEqualE(t, res.Statements[2].RowsAffected, int64(3))
+	assertEqualE(t, res.RowsAffected(), int64(4))
+	failed := res.Statements[1]
+	assertNotNilF(t, failed.Err)
+	assertEqualE(t, failed.Err.Number, 2003)
+	assertEqualE(t, failed.QueryID, "failed")
+	assertEqualE(t, res.Err(), error(failed.Err))
+
+	_, err = sc.ExecMultiStatement(context.Background(), query, []driver.NamedValue{{Ordinal: 1, Value: "1"}},
+		&MultiStatementOptions{ContinueOnError: true})
+	var se *SnowflakeError
+	assertTrueF(t, errors.As(err, &se))
+	assertEqualE(t, se.Number, ErrMultiStatementBindings)
+}
+
+func TestUnitSplitStatements(t *testing.T) {
+	testcases := []struct {
+		query string
+		out   []splitStatement
+	}{
+		{"", nil},
+		{" ;\n-- only a comment;\n/* and ; another */;", nil},
+		{"select 1; select 2", []splitStatement{{"select 1", 0}, {"select 2", 0}}},
+		{"select 'a;b''c\\';?', \"x;y\" from t where a = ?;", []splitStatement{{"select 'a;b''c\\';?', \"x;y\" from t where a = ?", 1}}},
+		{"create function f() returns int as $$ select 1; $$;\ncall p(?, ?) // done;", []splitStatement{
+			{"create function f() returns int as $$ select 1; $$", 0},
+			{"call p(?, ?) // done;", 2}}},
+		{"select 1 /* ; */ + 2;select 'unterminated;", []splitStatement{{"select 1 /* ; */ + 2", 0}, {"select 'unterminated;", 0}}},
+	}
+	for _, test := range testcases {
+		t.Run(test.query, func(t *testing.T) {
+			assertDeepEqualE(t, splitStatements(test.query), test.out)
+		})
+	}
+}
+
+func TestUnitExecMultiStatementStopsAtError(t *testing.T) {
+	for _, parent := range []string{"failed-parent", "failed-alone"} {
+		t.Run(parent, func(t *testing.T) {
+			postQueryMock := func(context.Context, *snowflakeRestful, *url.Values, map[string]string, []byte,
+				time.Duration, UUID, *Config) (*execResponse, error) {
+				return &execResponse{Data: execResponseData{QueryID: parent, SQLState: "02000"},
+					Code: "2003", Message: "Table 'T' does not exist", Success: false}, nil
+			}
+			sc := newMultiStatementTestConn(t, postQueryMock)
+
+			res, err := sc.ExecMultiStatement(context.Background(), "insert ...; drop table t; select ...", nil, nil)
+			var se *SnowflakeError
+			assertTrueF(t, errors.As(err, &se))
+			assertEqualE(t, se.Number, 2003)
+			assertNotNilF(t, res, "the statements that ran should be returned with the error")
+			defer func() {
+				assertNilE(t, res.Close())
+			}()
+			assertEqualE(t, res.QueryID, parent)
+			assertNotNilE(t, res.Err())
+			failed := res.Statements[len(res.Statements)-1]
+			assertNotNilF(t, failed.Err)
+			assertEqualE(t, failed.Err.Number, 2003)
+			if parent == "failed-parent" {
+				assertEqualF(t, len(res.Statements), 2)
+				assertEqualE(t, res.Statements[0].QueryID, "child-1")
+				assertEqualE(t, res.Statements[0].RowsAffected, int64(2))
+				assertEqualE(t, failed.QueryID, "child-3")
+			} else {
+				assertEqualF(t, len(res.Statements), 1)
+				assertEqualE(t, failed.QueryID, parent)
+			}
+		})
+	}
+}
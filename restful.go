@@ -52,11 +52,13 @@ type snowflakeRestful struct {
 	LoginTimeout   time.Duration // Login timeout
 	RequestTimeout time.Duration // request timeout
 	MaxRetryCount  int
+	RetryPolicy    RetryPolicy
 
 	Client        *http.Client
 	JWTClient     *http.Client
 	TokenAccessor TokenAccessor
 	HeartBeat     *heartbeat
+	session       sessionHealth
 
 	Connection *snowflakeConn
 
@@ -110,13 +112,45 @@ func (sr *snowflakeRestful) renewExpiredSessionToken(ctx context.Context, timeou
 	if err != nil {
 		return err
 	}
-	defer sr.TokenAccessor.Unlock()
 	currentToken, _, _ := sr.TokenAccessor.GetTokens()
-	if expiredToken == currentToken || currentToken == "" {
+	if expiredToken != currentToken && currentToken != "" {
 		// Only renew the session if the current token is still the expired token or current token is empty
-		return sr.FuncRenewSession(ctx, sr, timeout)
+		sr.TokenAccessor.Unlock()
+		return nil
+	}
+	err = sr.FuncRenewSession(ctx, sr, timeout)
+	sr.TokenAccessor.Unlock()
+	if err != nil && sr.canLoginAgain() && !sr.session.valid(time.Now()) {
+		// the master token expired or was rejected because the session is gone
+		logger.WithContext(ctx).Infof("cannot renew the session, logging in again. err: %v", err)
+		return sr.loginAgain(ctx, expiredToken)
 	}
-	return nil
+	return err
+}
+
+// canLoginAgain returns true if a session that is gone or whose master token expired is replaced by a new one.
+func (sr *snowflakeRestful) canLoginAgain() bool {
+	return sr.Connection != nil && sr.Connection.cfg.RestoreSessionState
+}
+
+// loginAgain replaces the session of the expired token with a new login, unless another request already did, and
+// replays the session state on the new session.
+func (sr *snowflakeRestful) loginAgain(ctx context.Context, expiredToken string) error {
+	err := sr.TokenAccessor.Lock()
+	if err != nil {
+		return err
+	}
+	if currentToken, _, _ := sr.TokenAccessor.GetTokens(); expiredToken != currentToken && currentToken != "" {
+		sr.TokenAccessor.Unlock()
+		return nil
+	}
+	err = sr.Connection.loginAgain()
+	sr.TokenAccessor.Unlock()
+	if err != nil {
+		return err
+	}
+	// the new session starts with the state from the config
+	return sr.Connection.restoreSessionState(ctx)
 }
 
 type renewSessionResponse struct {
@@ -162,6 +196,7 @@ func postRestful(
 	return newRetryHTTP(ctx, sr.Client, http.NewRequest, fullURL, headers, timeout, sr.MaxRetryCount, currentTimeProvider, cfg).
 		doPost().
 		setBody(body).
+		setRetryPolicy(sr.RetryPolicy).
 		execute()
 }
 
@@ -172,7 +207,9 @@ func getRestful(
 	headers map[string]string,
 	timeout time.Duration) (
 	*http.Response, error) {
-	return newRetryHTTP(ctx, sr.Client, http.NewRequest, fullURL, headers, timeout, sr.MaxRetryCount, defaultTimeProvider, nil).execute()
+	return newRetryHTTP(ctx, sr.Client, http.NewRequest, fullURL, headers, timeout, sr.MaxRetryCount, defaultTimeProvider, nil).
+		setRetryPolicy(sr.RetryPolicy).
+		execute()
 }
 
 func postAuthRestful(
@@ -258,6 +295,14 @@ func postRestfulQueryHelper(
 			}
 			return sr.FuncPostQuery(ctx, sr, params, headers, body, timeout, requestID, cfg)
 		}
+		if respd.Code == strconv.Itoa(ErrSessionGone) && sr.canLoginAgain() {
+			sr.session.markLost()
+			logger.WithContext(ctx).Info("the session is gone, logging in again")
+			if err = sr.loginAgain(ctx, token); err != nil {
+				return nil, err
+			}
+			return sr.FuncPostQuery(ctx, sr, params, headers, body, timeout, requestID, cfg)
+		}
 
 		if queryIDChan := getQueryIDChan(ctx); queryIDChan != nil {
 			queryIDChan <- respd.Data.QueryID
@@ -365,9 +410,15 @@ func closeSession(ctx context.Context, sr *snowflakeRestful, timeout time.Durati
 	}
 }
 
-func renewRestfulSession(ctx context.Context, sr *snowflakeRestful, timeout time.Duration) error {
+func renewRestfulSession(ctx context.Context, sr *snowflakeRestful, timeout time.Duration) (err error) {
+	requestID := getOrGenerateRequestIDFromContext(ctx)
+	ctx, op := sr.Connection.instrumentation().startOperation(ctx, spanNameRenewSession, "renew_session",
+		attrRequestID.String(requestID.String()))
+	defer func() {
+		op.end(ctx, err)
+	}()
 	params := &url.Values{}
-	params.Set(requestIDKey, getOrGenerateRequestIDFromContext(ctx).String())
+	params.Set(requestIDKey, requestID.String())
 	params.Set(requestGUIDKey, NewUUID().String())
 	fullURL := sr.getFullURL(tokenRequestPath, params)
 
@@ -382,7 +433,7 @@ func renewRestfulSession(ctx context.Context, sr *snowflakeRestful, timeout time
 	ctx = context.WithValue(ctx, SFSessionIDKey, sessionID)
 	logger.WithContext(ctx).Info("start renew session")
 	var reqBody []byte
-	reqBody, err := json.Marshal(body)
+	reqBody, err = json.Marshal(body)
 	if err != nil {
 		return err
 	}
@@ -392,8 +443,8 @@ func renewRestfulSession(ctx context.Context, sr *snowflakeRestful, timeout time
 		return err
 	}
 	defer func() {
-		if err = resp.Body.Close(); err != nil {
-			logger.WithContext(ctx).Warnf("failed to close response body for %v. err: %v", fullURL, err)
+		if closeErr := resp.Body.Close(); closeErr != nil {
+			logger.WithContext(ctx).Warnf("failed to close response body for %v. err: %v", fullURL, closeErr)
 		}
 	}()
 	if resp.StatusCode == http.StatusOK {
@@ -404,6 +455,8 @@ func renewRestfulSession(ctx context.Context, sr *snowflakeRestful, timeout time
 			return err
 		}
 		if !respd.Success {
+			// the master token was rejected, the session cannot be renewed anymore
+			sr.session.markLost()
 			c, err := strconv.Atoi(respd.Code)
 			if err != nil {
 				return err
@@ -414,6 +467,7 @@ func renewRestfulSession(ctx context.Context, sr *snowflakeRestful, timeout time
 			}
 		}
 		sr.TokenAccessor.SetTokens(respd.Data.SessionToken, respd.Data.MasterToken, respd.Data.SessionID)
+		sr.session.setMasterTokenValidity(respd.Data.ValidityInSecondsMT)
 		logger.WithContext(ctx).Info("successfully renewed session")
 		return nil
 	}
@@ -14,6 +14,18 @@ const (
 	QueryFailed QueryStatus = "queryFailed"
 )
 
+// CancelQueryResult denotes the outcome of SnowflakeConnection.CancelQueryByID.
+type CancelQueryResult string
+
+const (
+	// QueryCancelled denotes a running query that was cancelled
+	QueryCancelled CancelQueryResult = "queryCancelled"
+	// QueryAlreadyFinished denotes a query that had already succeeded or failed
+	QueryAlreadyFinished CancelQueryResult = "queryAlreadyFinished"
+	// QueryNotFound denotes a query that does not exist, or that the user is not permitted to cancel
+	QueryNotFound CancelQueryResult = "queryNotFound"
+)
+
 // SnowflakeResult provides an API for methods exposed to the clients
 type SnowflakeResult interface {
 	GetQueryID() string
@@ -0,0 +1,389 @@
+package gosnowflake
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultResultCacheMaxEntries = 1000
+	defaultResultCacheMaxBytes   = 64 * 1024 * 1024
+	defaultResultCacheTTL        = 5 * time.Minute
+	resultCacheKeySize           = 32
+	resultCacheIVSize            = 12
+)
+
+// ResultCacheConfig configures a ResultCache.
+type ResultCacheConfig struct {
+	MaxEntries int           // maximum number of cached results. Default is 1000.
+	MaxBytes   int64         // maximum total size of the cached results. Default is 64 MiB.
+	TTL        time.Duration // how long a result is served from the cache. Default is 5 minutes.
+	// Dir, if set, stores the results in files in a new directory inside Dir instead of in memory.
+	// The files are encrypted with a key that exists only in memory.
+	Dir string
+}
+
+// ResultCacheStats is a snapshot of the statistics of a ResultCache.
+type ResultCacheStats struct {
+	Hits        int64 // queries answered from the cache
+	Misses      int64 // cacheable queries sent to the server
+	Evictions   int64 // results removed to stay within MaxEntries and MaxBytes
+	Expirations int64 // results removed because their TTL passed
+	Entries     int   // results currently cached
+	Bytes       int64 // total size of the results currently cached
+}
+
+// ResultCache is an opt-in client-side cache of query results, set with Config.ResultCache.
+// Results are keyed by the normalized SQL text, the bind values, and the account, user, role,
+// warehouse, database, schema and altered session parameters of the connection.
+// Only SELECT results returned entirely in the query response are cached, larger results whose
+// rows are in remote chunks are not. A cache can be shared by several connectors.
+type ResultCache struct {
+	cfg ResultCacheConfig
+	key []byte
+	now func() time.Time
+
+	mu      sync.Mutex
+	dir     string
+	lru     *list.List
+	entries map[string]*list.Element
+	bytes   int64
+	stats   ResultCacheStats
+}
+
+type resultCacheEntry struct {
+	key     string
+	size    int64
+	expires time.Time
+	data    []byte // encoded result, nil if stored in path
+	path    string
+}
+
+// NewResultCache creates a ResultCache.
+func NewResultCache(cfg ResultCacheConfig) (*ResultCache, error) {
+	if cfg.MaxEntries <= 0 {
+		cfg.MaxEntries = defaultResultCacheMaxEntries
+	}
+	if cfg.MaxBytes <= 0 {
+		cfg.MaxBytes = defaultResultCacheMaxBytes
+	}
+	if cfg.TTL <= 0 {
+		cfg.TTL = defaultResultCacheTTL
+	}
+	rc := &ResultCache{
+		cfg:     cfg,
+		now:     time.Now,
+		lru:     list.New(),
+		entries: make(map[string]*list.Element),
+	}
+	if cfg.Dir != "" {
+		dir, err := os.MkdirTemp(cfg.Dir, "snowflake-result-cache-")
+		if err != nil {
+			return nil, fmt.Errorf("creating result cache directory: %w", err)
+		}
+		rc.dir = dir
+		rc.key = getSecureRandom(resultCacheKeySize)
+	}
+	return rc, nil
+}
+
+// Stats returns the statistics of the cache.
+func (rc *ResultCache) Stats() ResultCacheStats {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	stats := rc.stats
+	stats.Entries = rc.lru.Len()
+	stats.Bytes = rc.bytes
+	return stats
+}
+
+// Clear removes all cached results.
+func (rc *ResultCache) Clear() {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	for rc.lru.Len() > 0 {
+		rc.removeLocked(rc.lru.Back())
+	}
+}
+
+// Close removes all cached results and the directory of the cache. The cache can not be used afterwards.
+func (rc *ResultCache) Close() error {
+	rc.Clear()
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if rc.dir == "" {
+		return nil
+	}
+	dir := rc.dir
+	rc.dir = ""
+	return os.RemoveAll(dir)
+}
+
+// WithResultCacheBypass returns a context whose queries are neither answered from nor stored in Config.ResultCache.
+func WithResultCacheBypass(ctx context.Context) context.Context {
+	return context.WithValue(ctx, resultCacheModeKey, resultCacheBypass)
+}
+
+// WithResultCacheRefresh returns a context whose queries are sent to the server, and whose results replace
+// the ones in Config.ResultCache.
+func WithResultCacheRefresh(ctx context.Context) context.Context {
+	return context.WithValue(ctx, resultCacheModeKey, resultCacheRefresh)
+}
+
+type resultCacheMode int
+
+const (
+	resultCacheDefault resultCacheMode = iota
+	resultCacheBypass
+	resultCacheRefresh
+)
+
+func getResultCacheMode(ctx context.Context) resultCacheMode {
+	mode, _ := ctx.Value(resultCacheModeKey).(resultCacheMode)
+	return mode
+}
+
+// resultCacheFor returns the cache to use for the query, or nil if its result must not be cached.
+func (sc *snowflakeConn) resultCacheFor(ctx context.Context, query string) *ResultCache {
+	if sc.cfg == nil || sc.cfg.ResultCache == nil || getResultCacheMode(ctx) == resultCacheBypass {
+		return nil
+	}
+	if isAsyncMode(ctx) || isDescribeOnly(ctx) || isInternal(ctx) || isFileTransfer(query) ||
+		ctx.Value(multiStatementCount) != nil {
+		return nil
+	}
+	return sc.cfg.ResultCache
+}
+
+// resultCacheKey returns the key of the result of the query with the bind values on the connection.
+func (sc *snowflakeConn) resultCacheKey(query string, args []driver.NamedValue) string {
+	state := sc.SessionState()
+	h := sha256.New()
+	write := func(s string) {
+		fmt.Fprintf(h, "%d:%s", len(s), s)
+	}
+	for _, s := range []string{sc.cfg.Host, sc.cfg.Account, sc.cfg.User, state.Role, state.Warehouse, state.Database, state.Schema} {
+		write(strings.ToUpper(s))
+	}
+	names := make([]string, 0, len(state.Parameters))
+	for name := range state.Parameters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		write(name)
+		write(state.Parameters[name])
+	}
+	write(normalizeResultCacheQuery(query))
+	for _, arg := range args {
+		write(arg.Name)
+		write(resultCacheBindValue(arg.Value))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// normalizeResultCacheQuery collapses white space outside of quotes and comments and removes trailing
+// semicolons. Quotes and comments are read as by splitStatements, and the line break ending a line comment is kept.
+func normalizeResultCacheQuery(query string) string {
+	query = strings.TrimSpace(query)
+	var b strings.Builder
+	space, lineBreak := false, false
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		if c == ' ' || c == '\t' || c == '\n' || c == '\r' {
+			space = true
+			continue
+		}
+		if space && !lineBreak {
+			b.WriteByte(' ')
+		}
+		space = false
+		end := i + 1
+		switch {
+		case strings.HasPrefix(query[i:], "--") || strings.HasPrefix(query[i:], "//"):
+			end = skipUntil(query, i, "\n")
+		case strings.HasPrefix(query[i:], "/*"):
+			end = skipUntil(query, i+2, "*/")
+		case strings.HasPrefix(query[i:], "$$"):
+			end = skipUntil(query, i+2, "$$")
+		case c == '\'' || c == '"':
+			end = intMin(skipQuoted(query, i)+1, len(query))
+		}
+		b.WriteString(query[i:end])
+		i = end - 1
+		lineBreak = query[i] == '\n'
+	}
+	return strings.TrimRight(b.String(), "; \n")
+}
+
+func resultCacheBindValue(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "nil"
+	case []byte:
+		return "bytes:" + hex.EncodeToString(v)
+	case time.Time:
+		return "time:" + v.Format(time.RFC3339Nano) + " " + v.Location().String()
+	default:
+		return fmt.Sprintf("%T:%v", v, v)
+	}
+}
+
+// isCacheableResult returns true for SELECT results whose rows are all in the response.
+func isCacheableResult(data *execResponseData) bool {
+	return data.StatementTypeID == statementTypeIDSelect && len(data.RowType) > 0 && len(data.Chunks) == 0 && !isMultiStmt(data)
+}
+
+// get returns the cached result for key, if any.
+func (rc *ResultCache) get(ctx context.Context, key string) (*execResponseData, bool) {
+	rc.mu.Lock()
+	elem, ok := rc.entries[key]
+	if ok && rc.now().After(elem.Value.(*resultCacheEntry).expires) {
+		rc.removeLocked(elem)
+		rc.stats.Expirations++
+		ok = false
+	}
+	if !ok {
+		rc.stats.Misses++
+		rc.mu.Unlock()
+		return nil, false
+	}
+	rc.lru.MoveToFront(elem)
+	entry := elem.Value.(*resultCacheEntry)
+	rc.mu.Unlock()
+
+	data, err := rc.read(entry)
+	if err != nil {
+		logger.WithContext(ctx).Warnf("failed to read cached result: %v", err)
+		rc.mu.Lock()
+		if rc.entries[key] == elem {
+			rc.removeLocked(elem)
+		}
+		rc.stats.Misses++
+		rc.mu.Unlock()
+		return nil, false
+	}
+	rc.mu.Lock()
+	rc.stats.Hits++
+	rc.mu.Unlock()
+	return data, true
+}
+
+// put caches the rows of the result for key, replacing the cached result if any.
+func (rc *ResultCache) put(ctx context.Context, key string, data *execResponseData) {
+	b, err := json.Marshal(&execResponseData{
+		RowType:           data.RowType,
+		RowSet:            data.RowSet,
+		RowSetBase64:      data.RowSetBase64,
+		Total:             data.Total,
+		Returned:          data.Returned,
+		QueryID:           data.QueryID,
+		StatementTypeID:   data.StatementTypeID,
+		QueryResultFormat: data.QueryResultFormat,
+	})
+	if err != nil {
+		logger.WithContext(ctx).Warnf("failed to encode result for the cache: %v", err)
+		return
+	}
+	if int64(len(b)) > rc.cfg.MaxBytes {
+		return
+	}
+	entry := &resultCacheEntry{
+		key:     key,
+		size:    int64(len(b)),
+		expires: rc.now().Add(rc.cfg.TTL),
+		data:    b,
+	}
+	if err = rc.write(entry); err != nil {
+		logger.WithContext(ctx).Warnf("failed to write result to the cache: %v", err)
+		return
+	}
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if elem, ok := rc.entries[key]; ok {
+		rc.removeLocked(elem)
+	}
+	rc.entries[key] = rc.lru.PushFront(entry)
+	rc.bytes += entry.size
+	for rc.lru.Len() > rc.cfg.MaxEntries || rc.bytes > rc.cfg.MaxBytes {
+		rc.removeLocked(rc.lru.Back())
+		rc.stats.Evictions++
+	}
+}
+
+// write moves the data of the entry to a file if the cache is stored on disk.
+func (rc *ResultCache) write(entry *resultCacheEntry) error {
+	if rc.key == nil {
+		return nil
+	}
+	rc.mu.Lock()
+	dir := rc.dir
+	rc.mu.Unlock()
+	if dir == "" {
+		return fmt.Errorf("result cache is closed")
+	}
+	iv := getSecureRandom(resultCacheIVSize)
+	encrypted, err := encryptGCM(iv, entry.data, rc.key, []byte(entry.key))
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(dir, "result-")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(iv, encrypted...))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	entry.path = f.Name()
+	entry.data = nil
+	return nil
+}
+
+func (rc *ResultCache) read(entry *resultCacheEntry) (*execResponseData, error) {
+	b := entry.data
+	if entry.path != "" {
+		encrypted, err := os.ReadFile(entry.path)
+		if err != nil {
+			return nil, err
+		}
+		if len(encrypted) < resultCacheIVSize {
+			return nil, fmt.Errorf("cached result %v is truncated", filepath.Base(entry.path))
+		}
+		if b, err = decryptGCM(encrypted[:resultCacheIVSize], encrypted[resultCacheIVSize:], rc.key, []byte(entry.key)); err != nil {
+			return nil, err
+		}
+	}
+	var data execResponseData
+	if err := json.Unmarshal(b, &data); err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
+
+// removeLocked removes the entry of elem. The caller must hold mu.
+func (rc *ResultCache) removeLocked(elem *list.Element) {
+	entry := rc.lru.Remove(elem).(*resultCacheEntry)
+	delete(rc.entries, entry.key)
+	rc.bytes -= entry.size
+	if entry.path != "" {
+		if err := os.Remove(entry.path); err != nil && !os.IsNotExist(err) {
+			logger.Warnf("failed to remove cached result %v: %v", entry.path, err)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package gosnowflake
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newResultCacheTestConn(t *testing.T, cache *ResultCache, calls *int, statementTypeID int64) *snowflakeConn {
+	postQueryMock := func(_ context.Context, _ *snowflakeRestful,
+		_ *url.Values, _ map[string]string, body []byte, _ time.Duration,
+		_ UUID, _ *Config) (*execResponse, error) {
+		*calls++
+		var req execRequest
+		assertNilF(t, json.Unmarshal(body, &req))
+		value := "1"
+		if binding, ok := req.Bindings["1"]; ok {
+			value = binding.Value.(string)
+		}
+		return &execResponse{
+			Data: execResponseData{
+				QueryID:           NewUUID().String(),
+				StatementTypeID:   statementTypeID,
+				QueryResultFormat: "json",
+				RowType:           []execResponseRowType{{Name: "V", Type: "text"}},
+				RowSet:            [][]*string{{&value}},
+				Total:             1,
+				Returned:          1,
+			},
+			Success: true,
+		}, nil
+	}
+	return &snowflakeConn{
+		cfg:               &Config{Account: "acc", User: "usr", Role: "analyst", Params: map[string]*string{}, ResultCache: cache},
+		rest:              &snowflakeRestful{FuncPostQuery: postQueryMock, TokenAccessor: getSimpleTokenAccessor()},
+		queryContextCache: (&queryContextCache{}).init(),
+	}
+}
+
+func queryResultCacheValue(t *testing.T, ctx context.Context, sc *snowflakeConn, query string, args ...driver.NamedValue) string {
+	rows, err := sc.queryContextInternal(ctx, query, args)
+	assertNilF(t, err)
+	defer func() {
+		assertNilE(t, rows.Close())
+	}()
+	dest := make([]driver.Value, 1)
+	assertNilF(t, rows.Next(dest))
+	return dest[0].(string)
+}
+
+func TestResultCacheHitsAndMisses(t *testing.T) {
+	cache, err := NewResultCache(ResultCacheConfig{})
+	assertNilF(t, err)
+	calls := 0
+	sc := newResultCacheTestConn(t, cache, &calls, statementTypeIDSelect)
+	ctx := context.Background()
+
+	assertEqualE(t, queryResultCacheValue(t, ctx, sc, "SELECT v FROM t"), "1")
+	assertEqualE(t, queryResultCacheValue(t, ctx, sc, "  SELECT v\n  FROM t;"), "1")
+	assertEqualE(t, calls, 1)
+	assertEqualE(t, queryResultCacheValue(t, ctx, sc, "SELECT v FROM t WHERE v = ?", driver.NamedValue{Ordinal: 1, Value: "2"}), "2")
+	assertEqualE(t, queryResultCacheValue(t, ctx, sc, "SELECT v FROM t WHERE v = ?", driver.NamedValue{Ordinal: 1, Value: "3"}), "3")
+	assertEqualE(t, calls, 3)
+
+	sc.cfg.Role = "admin"
+	assertEqualE(t, queryResultCacheValue(t, ctx, sc, "SELECT v FROM t"), "1")
+	assertEqualE(t, calls, 4, "a result should not be shared across roles")
+
+	queryResultCacheValue(t, WithResultCacheBypass(ctx), sc, "SELECT v FROM t")
+	assertEqualE(t, calls, 5)
+	queryResultCacheValue(t, WithResultCacheRefresh(ctx), sc, "SELECT v FROM t")
+	assertEqualE(t, calls, 6)
+	queryResultCacheValue(t, ctx, sc, "SELECT v FROM t")
+	assertEqualE(t, calls, 6)
+
+	stats := cache.Stats()
+	assertEqualE(t, stats.Hits, int64(2))
+	assertEqualE(t, stats.Misses, int64(4))
+	assertEqualE(t, stats.Entries, 4)
+	assertTrueE(t, stats.Bytes > 0)
+
+	cache.Clear()
+	assertEqualE(t, cache.Stats().Entries, 0)
+	assertEqualE(t, cache.Stats().Bytes, int64(0))
+}
+
+func TestResultCacheSkipsDML(t *testing.T) {
+	cache, err := NewResultCache(ResultCacheConfig{})
+	assertNilF(t, err)
+	calls := 0
+	sc := newResultCacheTestConn(t, cache, &calls, statementTypeIDDml)
+
+	for i := 0; i < 2; i++ {
+		_, err = sc.queryContextInternal(context.Background(), "INSERT INTO t VALUES (1)", nil)
+		assertNilF(t, err)
+	}
+	assertEqualE(t, calls, 2)
+	assertEqualE(t, cache.Stats().Entries, 0)
+}
+
+func TestResultCacheExpirationAndEviction(t *testing.T) {
+	cache, err := NewResultCache(ResultCacheConfig{MaxEntries: 2, TTL: time.Minute})
+	assertNilF(t, err)
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+	ctx := context.Background()
+	data := &execResponseData{QueryID: "q", StatementTypeID: statementTypeIDSelect}
+
+	cache.put(ctx, "a", data)
+	cache.put(ctx, "b", data)
+	_, ok := cache.get(ctx, "a")
+	assertTrueE(t, ok)
+	cache.put(ctx, "c", data)
+	_, ok = cache.get(ctx, "b")
+	assertFalseE(t, ok, "the least recently used result should be evicted")
+
+	now = now.Add(2 * time.Minute)
+	_, ok = cache.get(ctx, "a")
+	assertFalseE(t, ok, "an expired result should not be returned")
+
+	stats := cache.Stats()
+	assertEqualE(t, stats.Evictions, int64(1))
+	assertEqualE(t, stats.Expirations, int64(1))
+	assertEqualE(t, stats.Entries, 1)
+
+	small, err := NewResultCache(ResultCacheConfig{MaxBytes: 10})
+	assertNilF(t, err)
+	small.put(ctx, "a", data)
+	assertEqualE(t, small.Stats().Entries, 0, "a result larger than MaxBytes should not be cached")
+}
+
+func TestResultCacheOnDisk(t *testing.T) {
+	dir := t.TempDir()
+	cache, err := NewResultCache(ResultCacheConfig{Dir: dir})
+	assertNilF(t, err)
+	ctx := context.Background()
+	secret := "secret value"
+	cache.put(ctx, "a", &execResponseData{
+		QueryID:         "q",
+		StatementTypeID: statementTypeIDSelect,
+		RowType:         []execResponseRowType{{Name: "V", Type: "text"}},
+		RowSet:          [][]*string{{&secret}},
+	})
+
+	entries, err := os.ReadDir(cache.dir)
+	assertNilF(t, err)
+	assertEqualF(t, len(entries), 1)
+	b, err := os.ReadFile(filepath.Join(cache.dir, entries[0].Name()))
+	assertNilF(t, err)
+	assertFalseE(t, strings.Contains(string(b), secret), "cached results should be encrypted")
+
+	data, ok := cache.get(ctx, "a")
+	assertTrueF(t, ok)
+	assertEqualE(t, *data.RowSet[0][0], secret)
+	assertEqualE(t, data.QueryID, "q")
+
+	assertNilF(t, cache.Close())
+	entries, err = os.ReadDir(dir)
+	assertNilF(t, err)
+	assertEqualE(t, len(entries), 0)
+}
+
+func TestNormalizeResultCacheQuery(t *testing.T) {
+	assertEqualE(t, normalizeResultCacheQuery(" SELECT  a,\n\tb FROM t ; "), "SELECT a, b FROM t")
+	assertEqualE(t, normalizeResultCacheQuery("SELECT 'a  b' FROM \"My  Table\""), "SELECT 'a  b' FROM \"My  Table\"")
+	assertEqualE(t, normalizeResultCacheQuery("SELECT a -- b  c\n  FROM t"), "SELECT a -- b  c\nFROM t")
+	assertEqualE(t, normalizeResultCacheQuery("SELECT 'it\\'s  a' /* b  c */ FROM t;"), "SELECT 'it\\'s  a' /* b  c */ FROM t")
+}
+
+func TestResultCacheKeyCollisions(t *testing.T) {
+	sc := &snowflakeConn{cfg: &Config{Host: "h", Account: "a", User: "u"}}
+	for _, queries := range [][2]string{
+		{"SELECT a -- , b\nFROM t", "SELECT a -- , b FROM t"},
+		{"SELECT a // , b\nFROM t", "SELECT a // , b FROM t"},
+		{"SELECT 'it\\'s  a'", "SELECT 'it\\'s a'"},
+		{"SELECT \"a  b\"", "SELECT \"a b\""},
+		{"SELECT $$a  b$$", "SELECT $$a b$$"},
+	} {
+		t.Run(queries[0], func(t *testing.T) {
+			assertNotEqualE(t, sc.resultCacheKey(queries[0], nil), sc.resultCacheKey(queries[1], nil))
+		})
+	}
+	assertEqualE(t, sc.resultCacheKey("SELECT a\n  FROM t;", nil), sc.resultCacheKey(" SELECT a FROM t ", nil))
+}
@@ -249,6 +249,8 @@ type retryHTTP struct {
 	maxRetryCount       int
 	currentTimeProvider currentTimeProvider
 	cfg                 *Config
+	retryPolicy         RetryPolicy
+	kind                RetryRequestKind
 }
 
 func newRetryHTTP(ctx context.Context,
@@ -272,6 +274,12 @@ func newRetryHTTP(ctx context.Context,
 	instance.bodyCreator = emptyBodyCreator
 	instance.currentTimeProvider = currentTimeProvider
 	instance.cfg = cfg
+	var cfgRetryPolicy RetryPolicy
+	if cfg != nil {
+		cfgRetryPolicy = cfg.RetryPolicy
+	}
+	instance.retryPolicy = retryPolicyFor(ctx, cfgRetryPolicy)
+	instance.kind = retryRequestKindFor(fullURL)
 	return &instance
 }
 
@@ -292,11 +300,29 @@ func (r *retryHTTP) setBodyCreator(bodyCreator bodyCreatorType) *retryHTTP {
 	return r
 }
 
+// setRetryPolicy sets the policy used unless the context specifies its own.
+func (r *retryHTTP) setRetryPolicy(policy RetryPolicy) *retryHTTP {
+	if policy != nil {
+		r.retryPolicy = retryPolicyFor(r.ctx, policy)
+	}
+	return r
+}
+
+func (r *retryHTTP) setRequestKind(kind RetryRequestKind) *retryHTTP {
+	r.kind = kind
+	return r
+}
+
 func (r *retryHTTP) execute() (res *http.Response, err error) {
 	totalTimeout := r.timeout
 	logger.WithContext(r.ctx).Debugf("retryHTTP.totalTimeout: %v", totalTimeout)
 	retryCounter := 0
-	sleepTime := time.Duration(time.Second)
+	var sleepTime time.Duration
+	startTime := time.Now()
+	var breaker *circuitBreaker
+	if endpoint, ok := circuitBreakerEndpointFor(r.kind, r.fullURL.Path); ok {
+		breaker = circuitBreakers.get(r.fullURL.Host, endpoint)
+	}
 	clientStartTime := strconv.FormatInt(r.currentTimeProvider.currentTime(), 10)
 
 	var requestGUIDReplacer requestGUIDReplacer
@@ -321,10 +347,35 @@ func (r *retryHTTP) execute() (res *http.Response, err error) {
 		for k, v := range r.headers {
 			req.Header.Set(k, v)
 		}
+		if breaker != nil {
+			if err = breaker.allow(); err != nil {
+				return nil, err
+			}
+		}
 		res, err = r.client.Do(req)
+		if breaker != nil {
+			if r.ctx.Err() != nil {
+				// cancelled by the caller, not an outcome of the endpoint
+				breaker.cancel()
+			} else {
+				breaker.record(isCircuitBreakerFailure(res, err))
+			}
+		}
 
 		// check if it can retry.
-		retryable, err := isRetryableError(r.ctx, req, res, err)
+		attempt := RetryAttempt{
+			Kind:         r.kind,
+			Attempt:      retryCounter + 1,
+			Request:      req,
+			Response:     res,
+			Err:          err,
+			PreviousWait: sleepTime,
+			Elapsed:      time.Since(startTime),
+		}
+		if res != nil {
+			attempt.StatusCode = res.StatusCode
+		}
+		retryable, err := isRetryableAttempt(r.ctx, r.retryPolicy, attempt)
 		if !retryable {
 			return res, err
 		}
@@ -340,13 +391,9 @@ func (r *retryHTTP) execute() (res *http.Response, err error) {
 				logger.Warnf("failed to close response body. err: %v", closeErr)
 			}
 		}
-		// uses exponential jitter backoff
+		// the default policy uses exponential jitter backoff
 		retryCounter++
-		if isLoginRequest(req) {
-			sleepTime = defaultWaitAlgo.calculateWaitBeforeRetryForAuthRequest(retryCounter, sleepTime)
-		} else {
-			sleepTime = defaultWaitAlgo.calculateWaitBeforeRetry(sleepTime)
-		}
+		sleepTime = r.retryPolicy.WaitTime(attempt)
 		if totalTimeout > 0 { // if any timeout is set
 			totalTimeout -= sleepTime
 		}
@@ -355,7 +402,12 @@ func (r *retryHTTP) execute() (res *http.Response, err error) {
 				return nil, err
 			}
 			if res != nil {
-				return nil, fmt.Errorf("timeout after %s and %v attempts. HTTP Status: %v. Hanging?", r.timeout, retryCounter, res.StatusCode)
+				// the status code comes first, as for the other HTTP errors
+				return nil, &SnowflakeError{
+					Number:      ErrRetryTimeout,
+					Message:     errMsgRetryTimeout,
+					MessageArgs: []interface{}{res.StatusCode, r.timeout, retryCounter},
+				}
 			}
 			return nil, fmt.Errorf("timeout after %s and %v attempts. Hanging?", r.timeout, retryCounter)
 		}
@@ -375,6 +427,7 @@ func (r *retryHTTP) execute() (res *http.Response, err error) {
 			retryReason = res.StatusCode
 		}
 		r.fullURL = retryReasonUpdater.replaceOrAdd(retryReason)
+		recordRetry(r.ctx, r.fullURL.Path, retryCounter, retryReason)
 		r.fullURL = ensureClientStartTimeIsSet(r.fullURL, clientStartTime)
 		logger.WithContext(r.ctx).Debugf("sleeping %v. to timeout: %v. retrying", sleepTime, totalTimeout)
 		logger.WithContext(r.ctx).Debugf("retry count: %v, retry reason: %v", retryCounter, retryReason)
@@ -391,16 +444,24 @@ func (r *retryHTTP) execute() (res *http.Response, err error) {
 }
 
 func isRetryableError(ctx context.Context, req *http.Request, res *http.Response, err error) (bool, error) {
+	attempt := RetryAttempt{Request: req, Response: res, Err: err}
+	if res != nil {
+		attempt.StatusCode = res.StatusCode
+	}
+	return isRetryableAttempt(ctx, defaultRetryPolicy, attempt)
+}
+
+func isRetryableAttempt(ctx context.Context, policy RetryPolicy, attempt RetryAttempt) (bool, error) {
 	if ctx.Err() != nil {
 		return false, ctx.Err()
 	}
-	if err != nil && res == nil { // Failed http connection. Most probably client timeout.
-		return true, err
+	if attempt.Err != nil && attempt.Response == nil { // Failed http connection. Most probably client timeout.
+		return policy.ShouldRetry(attempt), attempt.Err
 	}
-	if res == nil || req == nil {
-		return false, err
+	if attempt.Response == nil || attempt.Request == nil || attempt.StatusCode < http.StatusBadRequest {
+		return false, attempt.Err
 	}
-	return isRetryableStatus(res.StatusCode), err
+	return policy.ShouldRetry(attempt), attempt.Err
 }
 
 func isRetryableStatus(statusCode int) bool {
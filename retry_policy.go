@@ -0,0 +1,132 @@
+package gosnowflake
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// RetryRequestKind identifies the kind of request a RetryPolicy is asked about.
+type RetryRequestKind int
+
+const (
+	// RetryRequestKindOther is any request not covered by other kinds, e.g. session or monitoring requests.
+	RetryRequestKindOther RetryRequestKind = iota
+	// RetryRequestKindLogin is a login, token or authenticator request.
+	RetryRequestKindLogin
+	// RetryRequestKindQuery is a query request.
+	RetryRequestKindQuery
+	// RetryRequestKindChunk is a download of a result set chunk.
+	RetryRequestKindChunk
+	// RetryRequestKindCloudStorage is a PUT or GET transfer to or from a cloud storage stage.
+	RetryRequestKindCloudStorage
+)
+
+func (k RetryRequestKind) String() string {
+	switch k {
+	case RetryRequestKindLogin:
+		return "login"
+	case RetryRequestKindQuery:
+		return "query"
+	case RetryRequestKindChunk:
+		return "chunk"
+	case RetryRequestKindCloudStorage:
+		return "cloud storage"
+	default:
+		return "other"
+	}
+}
+
+// RetryAttempt describes a failed attempt that may be retried.
+type RetryAttempt struct {
+	Kind         RetryRequestKind
+	Attempt      int            // number of the retry being considered, starting from 1
+	Request      *http.Request  // the failed request, nil for cloud storage transfers
+	Response     *http.Response // the response, nil if no response was received
+	StatusCode   int            // HTTP status of the response, 0 if no response was received
+	Err          error          // error returned by the failed attempt, if any
+	PreviousWait time.Duration  // wait before the failed attempt, 0 before the first retry
+	Elapsed      time.Duration  // time since the first attempt was sent
+}
+
+// RetryPolicy decides whether and when failed requests are retried.
+// The driver still enforces MaxRetryCount and the login and request timeouts on top of the policy.
+// Implementations must be safe for concurrent use.
+type RetryPolicy interface {
+	// ShouldRetry reports whether the failed attempt should be retried.
+	// It is called for attempts which returned an error without a response or an HTTP status of 400 or higher.
+	ShouldRetry(attempt RetryAttempt) bool
+	// WaitTime returns how long to wait before the retry.
+	WaitTime(attempt RetryAttempt) time.Duration
+}
+
+// DefaultRetryPolicy returns the retry policy used when none is configured.
+// It retries connection errors, HTTP 5xx, 408 and 429 responses with jittered exponential backoff.
+func DefaultRetryPolicy() RetryPolicy {
+	return defaultRetryPolicy
+}
+
+var defaultRetryPolicy RetryPolicy = &waitAlgoRetryPolicy{}
+
+// waitAlgoRetryPolicy is the default policy backed by defaultWaitAlgo.
+type waitAlgoRetryPolicy struct{}
+
+func (p *waitAlgoRetryPolicy) ShouldRetry(attempt RetryAttempt) bool {
+	if attempt.Kind == RetryRequestKindCloudStorage {
+		// the cloud storage clients decide on their own whether a transfer is retryable
+		return true
+	}
+	if attempt.Response == nil {
+		return attempt.Err != nil
+	}
+	return isRetryableStatus(attempt.StatusCode)
+}
+
+func (p *waitAlgoRetryPolicy) WaitTime(attempt RetryAttempt) time.Duration {
+	previousWait := attempt.PreviousWait
+	if previousWait <= 0 {
+		previousWait = time.Second
+	}
+	switch attempt.Kind {
+	case RetryRequestKindLogin:
+		return defaultWaitAlgo.calculateWaitBeforeRetryForAuthRequest(attempt.Attempt, previousWait)
+	case RetryRequestKindCloudStorage:
+		return time.Duration(intMin(int(math.Exp2(float64(attempt.Attempt-1))), 16)) * time.Second
+	default:
+		return defaultWaitAlgo.calculateWaitBeforeRetry(previousWait)
+	}
+}
+
+// retryPolicyFor returns the policy set on the context, then the fallback, then the default policy.
+func retryPolicyFor(ctx context.Context, fallback RetryPolicy) RetryPolicy {
+	if ctx != nil {
+		if policy, ok := ctx.Value(retryPolicyKey).(RetryPolicy); ok && policy != nil {
+			return policy
+		}
+	}
+	if fallback != nil {
+		return fallback
+	}
+	return defaultRetryPolicy
+}
+
+// withFallbackRetryPolicy returns a context carrying the fallback policy unless the context already has one.
+func withFallbackRetryPolicy(ctx context.Context, fallback RetryPolicy) context.Context {
+	if fallback == nil {
+		return ctx
+	}
+	return WithRetryPolicy(ctx, retryPolicyFor(ctx, fallback))
+}
+
+func retryRequestKindFor(u *url.URL) RetryRequestKind {
+	switch {
+	case contains(authEndpoints, u.Path):
+		return RetryRequestKindLogin
+	case isQueryRequest(u):
+		return RetryRequestKindQuery
+	default:
+		return RetryRequestKindOther
+	}
+}
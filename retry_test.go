@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -702,3 +703,103 @@ func TestRedirectRetry(t *testing.T) {
 	db := sql.OpenDB(connector)
 	runSmokeQuery(t, db)
 }
+
+type recordingRetryPolicy struct {
+	retry    bool
+	wait     time.Duration
+	attempts []RetryAttempt
+}
+
+func (p *recordingRetryPolicy) ShouldRetry(attempt RetryAttempt) bool {
+	p.attempts = append(p.attempts, attempt)
+	return p.retry
+}
+
+func (p *recordingRetryPolicy) WaitTime(_ RetryAttempt) time.Duration {
+	return p.wait
+}
+
+func TestRetryPolicyFailFast(t *testing.T) {
+	client := &fakeHTTPClient{
+		statusCode: http.StatusServiceUnavailable,
+		t:          t,
+	}
+	policy := &recordingRetryPolicy{retry: false}
+	urlPtr, err := url.Parse("https://fakeaccountretryfail.snowflakecomputing.com:443/queries/v1/query-request?" + requestIDKey)
+	assertNilF(t, err, "failed to parse the test URL")
+	res, err := newRetryHTTP(context.Background(),
+		client,
+		emptyRequest, urlPtr, make(map[string]string), 60*time.Second, 3, defaultTimeProvider, &Config{RetryPolicy: policy}).doPost().setBody([]byte{0}).execute()
+	assertNilF(t, err)
+	assertEqualE(t, res.StatusCode, http.StatusServiceUnavailable)
+	assertEqualE(t, client.retryNumber, 1)
+	assertEqualF(t, len(policy.attempts), 1)
+	assertEqualE(t, policy.attempts[0].Kind, RetryRequestKindQuery)
+	assertEqualE(t, policy.attempts[0].Attempt, 1)
+	assertEqualE(t, policy.attempts[0].StatusCode, http.StatusServiceUnavailable)
+}
+
+func TestRetryExhaustedOnErrorStatus(t *testing.T) {
+	client := &fakeHTTPClient{
+		statusCode: http.StatusServiceUnavailable,
+		t:          t,
+	}
+	policy := &recordingRetryPolicy{retry: true, wait: time.Millisecond}
+	urlPtr, err := url.Parse("https://fakeaccountretryfail.snowflakecomputing.com:443/queries/v1/query-request?" + requestIDKey)
+	assertNilF(t, err, "failed to parse the test URL")
+	_, err = newRetryHTTP(context.Background(),
+		client,
+		emptyRequest, urlPtr, make(map[string]string), 60*time.Second, 2, defaultTimeProvider, &Config{RetryPolicy: policy}).doPost().setBody([]byte{0}).execute()
+	var se *SnowflakeError
+	assertTrueF(t, errors.As(err, &se), "expected a SnowflakeError")
+	assertEqualE(t, se.Number, ErrRetryTimeout)
+	assertEqualF(t, len(se.MessageArgs), 3)
+	assertEqualE(t, se.MessageArgs[0], http.StatusServiceUnavailable)
+	assertEqualE(t, client.retryNumber, 3)
+}
+
+func TestRetryPolicyFromContextTakesPrecedence(t *testing.T) {
+	client := &fakeHTTPClient{
+		cnt:        3,
+		success:    true,
+		statusCode: http.StatusForbidden,
+		t:          t,
+	}
+	cfgPolicy := &recordingRetryPolicy{retry: false}
+	ctxPolicy := &recordingRetryPolicy{retry: true, wait: time.Millisecond}
+	urlPtr, err := url.Parse("https://fakeaccountretrysuccess.snowflakecomputing.com:443/session/v1/login-request?" + requestIDKey)
+	assertNilF(t, err, "failed to parse the test URL")
+	res, err := newRetryHTTP(WithRetryPolicy(context.Background(), ctxPolicy),
+		client,
+		emptyRequest, urlPtr, make(map[string]string), 60*time.Second, 3, defaultTimeProvider, &Config{RetryPolicy: cfgPolicy}).doPost().setBody([]byte{0}).execute()
+	assertNilF(t, err)
+	assertEqualE(t, res.StatusCode, http.StatusOK)
+	assertEqualE(t, len(cfgPolicy.attempts), 0)
+	assertEqualF(t, len(ctxPolicy.attempts), 2)
+	assertEqualE(t, ctxPolicy.attempts[0].Kind, RetryRequestKindLogin)
+	assertEqualE(t, ctxPolicy.attempts[1].Attempt, 2)
+	assertEqualE(t, ctxPolicy.attempts[1].PreviousWait, time.Millisecond)
+}
+
+func TestDefaultRetryPolicy(t *testing.T) {
+	policy := DefaultRetryPolicy()
+	testcases := []struct {
+		name     string
+		attempt  RetryAttempt
+		expected bool
+	}{
+		{"connection error", RetryAttempt{Err: &fakeHTTPError{err: "timeout"}}, true},
+		{"service unavailable", RetryAttempt{Response: &http.Response{StatusCode: http.StatusServiceUnavailable}, StatusCode: http.StatusServiceUnavailable}, true},
+		{"too many requests", RetryAttempt{Response: &http.Response{StatusCode: http.StatusTooManyRequests}, StatusCode: http.StatusTooManyRequests}, true},
+		{"forbidden", RetryAttempt{Response: &http.Response{StatusCode: http.StatusForbidden}, StatusCode: http.StatusForbidden}, false},
+		{"cloud storage", RetryAttempt{Kind: RetryRequestKindCloudStorage}, true},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			assertEqualE(t, policy.ShouldRetry(tc.attempt), tc.expected)
+		})
+	}
+	assertEqualE(t, policy.WaitTime(RetryAttempt{Kind: RetryRequestKindCloudStorage, Attempt: 1}), time.Second)
+	assertEqualE(t, policy.WaitTime(RetryAttempt{Kind: RetryRequestKindCloudStorage, Attempt: 10}), 16*time.Second)
+	assertBetweenInclusiveE(t, float64(policy.WaitTime(RetryAttempt{Kind: RetryRequestKindQuery, Attempt: 1})), float64(time.Second), float64(3*time.Second))
+}
@@ -38,10 +38,25 @@ type SnowflakeRows interface {
 	NextResultSet() error
 }
 
+// ResumableRows is implemented by the rows of the driver whose fetching can be resumed from a checkpoint.
+type ResumableRows interface {
+	// GetCursor returns the position after the last row returned by Next.
+	// Fetching can be resumed from it with WithResumeCursor, even from another connection or process.
+	GetCursor() (ResultCursor, error)
+}
+
+// ResultCursor is a serializable position in the result of a query.
+type ResultCursor struct {
+	QueryID    string `json:"queryId"`
+	ChunkIndex int    `json:"chunkIndex"` // 0 is the rowset returned with the query response, n is the n-th downloaded chunk
+	RowOffset  int    `json:"rowOffset"`  // number of rows of the chunk already returned
+}
+
 type snowflakeRows struct {
 	sc                  *snowflakeConn
 	ChunkDownloader     chunkDownloader
 	tailChunkDownloader chunkDownloader
+	multipleResultSets  bool
 	queryID             string
 	status              QueryStatus
 	err                 error
@@ -80,6 +95,11 @@ func (rows *snowflakeRows) Close() (err error) {
 		return err
 	}
 	logger.WithContext(rows.sc.ctx).Debugln("Rows.Close")
+	for dl := rows.ChunkDownloader; dl != nil; dl = dl.getNextChunkDownloader() {
+		if err = dl.close(); err != nil {
+			logger.WithContext(rows.sc.ctx).Warnf("failed to clean up result chunks: %v", err)
+		}
+	}
 	return nil
 }
 
@@ -162,6 +182,21 @@ func (rows *snowflakeRows) GetStatus() QueryStatus {
 	return rows.status
 }
 
+func (rows *snowflakeRows) GetCursor() (ResultCursor, error) {
+	if err := rows.waitForAsyncQueryStatus(); err != nil {
+		return ResultCursor{}, err
+	}
+	scd, ok := rows.ChunkDownloader.(*snowflakeChunkDownloader)
+	if !ok || rows.queryID == "" || rows.multipleResultSets || usesArrowBatches(rows.ctx) {
+		return ResultCursor{}, errInvalidResultCursor(rows.queryID, "cursors are not supported for this result")
+	}
+	return ResultCursor{
+		QueryID:    rows.queryID,
+		ChunkIndex: scd.cursorChunkIndex,
+		RowOffset:  scd.cursorRowOffset,
+	}, nil
+}
+
 // GetArrowBatches returns an array of ArrowBatch objects to retrieve data in arrow.Record format
 func (rows *snowflakeRows) GetArrowBatches() ([]*ArrowBatch, error) {
 	// Wait for all arrow batches before fetching.
@@ -258,4 +293,5 @@ func (rows *snowflakeRows) addDownloader(newDL chunkDownloader) {
 	}
 	rows.tailChunkDownloader.setNextChunkDownloader(newDL)
 	rows.tailChunkDownloader = newDL
+	rows.multipleResultSets = true
 }
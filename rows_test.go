@@ -227,6 +227,106 @@ func TestRowsWithChunkDownloader(t *testing.T) {
 	logger.Info("END TESTS")
 }
 
+func newCursorTestRows(numChunks int, downloaded *sync.Map) *snowflakeRows {
+	cc := make([][]*string, 0)
+	for i := 0; i < 10; i++ {
+		v1 := fmt.Sprintf("%v", i)
+		v2 := fmt.Sprintf("Test%v", i)
+		cc = append(cc, []*string{&v1, &v2})
+	}
+	rt := []execResponseRowType{
+		{Name: "c1", ByteLength: 10, Length: 10, Type: "FIXED", Scale: 0, Nullable: true},
+		{Name: "c2", ByteLength: 100000, Length: 100000, Type: "TEXT", Scale: 0, Nullable: false},
+	}
+	cm := make([]execResponseChunk, 0)
+	for i := 0; i < numChunks; i++ {
+		cm = append(cm, execResponseChunk{URL: fmt.Sprintf("dummyURL%v", i+1), RowCount: rowsInChunk})
+	}
+	sc := &snowflakeConn{
+		cfg: &Config{
+			Params: make(map[string]*string),
+		},
+	}
+	return &snowflakeRows{
+		sc:      sc,
+		ctx:     context.Background(),
+		queryID: "01b2c3d4-0000-0000-0000-000000000001",
+		ChunkDownloader: &snowflakeChunkDownloader{
+			sc:            sc,
+			ctx:           context.Background(),
+			Total:         int64(len(cc) + numChunks*rowsInChunk),
+			ChunkMetas:    cm,
+			TotalRowIndex: int64(-1),
+			FuncDownload: func(ctx context.Context, scd *snowflakeChunkDownloader, idx int) {
+				downloaded.Store(idx, true)
+				downloadChunkTest(ctx, scd, idx)
+			},
+			RowSet: rowSetType{RowType: rt, JSON: cc},
+		},
+	}
+}
+
+func TestRowsResumeFromCursor(t *testing.T) {
+	numChunks := 4
+	testcases := []struct {
+		name      string
+		rowsRead  int
+		cursor    ResultCursor
+		firstC1   string
+		remaining int
+	}{
+		{"start", 0, ResultCursor{ChunkIndex: 0, RowOffset: 0}, "0", 10 + numChunks*rowsInChunk},
+		{"first rowset", 3, ResultCursor{ChunkIndex: 0, RowOffset: 3}, "3", 7 + numChunks*rowsInChunk},
+		{"end of first rowset", 10, ResultCursor{ChunkIndex: 0, RowOffset: 10}, "0", numChunks * rowsInChunk},
+		{"chunk", 10 + 2*rowsInChunk + 5, ResultCursor{ChunkIndex: 3, RowOffset: 5}, "2005", (numChunks-2)*rowsInChunk - 5},
+		{"end of chunk", 10 + 2*rowsInChunk, ResultCursor{ChunkIndex: 2, RowOffset: rowsInChunk}, "2000", (numChunks - 2) * rowsInChunk},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			var downloaded sync.Map
+			rows := newCursorTestRows(numChunks, &downloaded)
+			assertNilF(t, rows.ChunkDownloader.start())
+			dest := make([]driver.Value, 2)
+			for i := 0; i < tc.rowsRead; i++ {
+				assertNilF(t, rows.Next(dest))
+			}
+			cursor, err := rows.GetCursor()
+			assertNilF(t, err)
+			tc.cursor.QueryID = rows.queryID
+			assertEqualF(t, cursor, tc.cursor)
+
+			var resumedDownloads sync.Map
+			resumed := newCursorTestRows(numChunks, &resumedDownloads)
+			assertNilF(t, resumed.ChunkDownloader.(*snowflakeChunkDownloader).resumeAt(cursor.ChunkIndex, cursor.RowOffset))
+			assertNilF(t, resumed.ChunkDownloader.start())
+			assertNilF(t, resumed.Next(dest))
+			assertEqualE(t, dest[0], tc.firstC1)
+			cnt := 1
+			for resumed.Next(dest) == nil {
+				cnt++
+			}
+			assertEqualE(t, cnt, tc.remaining)
+			for i := 0; i < cursor.ChunkIndex-1; i++ {
+				_, ok := resumedDownloads.Load(i)
+				assertFalseE(t, ok, fmt.Sprintf("chunk %v before the cursor should not be downloaded", i))
+			}
+		})
+	}
+}
+
+func TestRowsResumeFromInvalidCursor(t *testing.T) {
+	var downloaded sync.Map
+	for _, cursor := range []ResultCursor{{ChunkIndex: -1}, {ChunkIndex: 5}, {ChunkIndex: 1, RowOffset: rowsInChunk + 1}, {RowOffset: -1}} {
+		rows := newCursorTestRows(4, &downloaded)
+		err := rows.ChunkDownloader.(*snowflakeChunkDownloader).resumeAt(cursor.ChunkIndex, cursor.RowOffset)
+		assertNotNilF(t, err)
+		assertEqualE(t, err.(*SnowflakeError).Number, ErrInvalidResultCursor)
+	}
+	rows := newCursorTestRows(4, &downloaded)
+	assertNilF(t, rows.ChunkDownloader.(*snowflakeChunkDownloader).resumeAt(0, 11))
+	assertNotNilE(t, rows.ChunkDownloader.start(), "offset beyond the first rowset should be rejected")
+}
+
 func downloadChunkTestError(ctx context.Context, scd *snowflakeChunkDownloader, idx int) {
 	// fail to download 6th and 10th chunk, and retry up to N times and success
 	// NOTE: zero based index
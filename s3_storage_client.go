@@ -10,10 +10,13 @@ import (
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/aws/smithy-go"
 	"github.com/aws/smithy-go/logging"
 	"io"
+	"maps"
 	"net/http"
+	"net/url"
 	"os"
 	"strings"
 )
@@ -27,6 +30,9 @@ const (
 	notFound             = "NotFound"
 	expiredToken         = "ExpiredToken"
 	errNoWsaeconnaborted = "10053"
+
+	// s3MaxCopyObjectSize is the largest object a single CopyObject accepts.
+	s3MaxCopyObjectSize = 5 * 1024 * 1024 * 1024
 )
 
 type snowflakeS3Client struct {
@@ -123,7 +129,7 @@ func (util *snowflakeS3Client) getFileHeader(meta *fileMetadata, filename string
 	if meta.mockHeader != nil {
 		s3Cli = meta.mockHeader
 	}
-	out, err := withCloudStorageTimeout(util.cfg, func(ctx context.Context) (*s3.HeadObjectOutput, error) {
+	out, err := withCloudStorageTimeout(meta.transferContext(), util.cfg, func(ctx context.Context) (*s3.HeadObjectOutput, error) {
 		return s3Cli.HeadObject(ctx, headObjInput)
 	})
 	if err != nil {
@@ -179,6 +185,14 @@ type s3UploadAPI interface {
 	Upload(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*manager.Uploader)) (*manager.UploadOutput, error)
 }
 
+type s3CopyObjectAPI interface {
+	CopyObject(ctx context.Context, params *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error)
+}
+
+type s3AbortMultipartUploadAPI interface {
+	AbortMultipartUpload(ctx context.Context, params *s3.AbortMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error)
+}
+
 // cloudUtil implementation
 func (util *snowflakeS3Client) uploadFile(
 	dataFile string,
@@ -194,6 +208,10 @@ func (util *snowflakeS3Client) uploadFile(
 		s3Meta[amzKey] = meta.encryptMeta.key
 		s3Meta[amzMatdesc] = meta.encryptMeta.matdesc
 	}
+	if meta.uploadStream != nil {
+		// the digest of a streamed source is stored once it is uploaded, see storeStreamedDigest
+		delete(s3Meta, sfcDigest)
+	}
 
 	s3loc, err := util.extractBucketNameAndPath(meta.stageInfo.Location)
 	if err != nil {
@@ -217,13 +235,22 @@ func (util *snowflakeS3Client) uploadFile(
 		uploader = meta.mockUploader
 	}
 
-	_, err = withCloudStorageTimeout(util.cfg, func(ctx context.Context) (any, error) {
+	_, err = withCloudStorageTimeout(meta.transferContext(), util.cfg, func(ctx context.Context) (any, error) {
+		if meta.uploadStream != nil {
+			// the uploader reads a non-seekable body one part at a time
+			return uploader.Upload(ctx, &s3.PutObjectInput{
+				Bucket:   &s3loc.bucketName,
+				Key:      &s3path,
+				Body:     meta.progressReader(meta.uploadStream),
+				Metadata: s3Meta,
+			})
+		}
 		if meta.srcStream != nil {
 			uploadStream := cmp.Or(meta.realSrcStream, meta.srcStream)
 			return uploader.Upload(ctx, &s3.PutObjectInput{
 				Bucket:   &s3loc.bucketName,
 				Key:      &s3path,
-				Body:     bytes.NewBuffer(uploadStream.Bytes()),
+				Body:     meta.progressReader(bytes.NewReader(uploadStream.Bytes())),
 				Metadata: s3Meta,
 			})
 		}
@@ -240,13 +267,19 @@ func (util *snowflakeS3Client) uploadFile(
 		return uploader.Upload(ctx, &s3.PutObjectInput{
 			Bucket:   &s3loc.bucketName,
 			Key:      &s3path,
-			Body:     file,
+			Body:     meta.progressFile(file),
 			Metadata: s3Meta,
 		})
 
 	})
 
 	if err != nil {
+		var aborter s3AbortMultipartUploadAPI = client
+		// for testing only
+		if meta.mockAborter != nil {
+			aborter = meta.mockAborter
+		}
+		util.abortCanceledMultipartUpload(meta, aborter, s3loc.bucketName, s3path, err)
 		var ae smithy.APIError
 		if errors.As(err, &ae) {
 			if ae.ErrorCode() == expiredToken {
@@ -262,11 +295,64 @@ func (util *snowflakeS3Client) uploadFile(
 		meta.resStatus = needRetry
 		return fmt.Errorf("error while uploading file. %w", err)
 	}
+	if meta.uploadStream != nil {
+		var copier s3CopyObjectAPI = client
+		// for testing only
+		if meta.mockCopier != nil {
+			copier = meta.mockCopier
+		}
+		if err = util.storeStreamedDigest(meta, copier, s3loc.bucketName, s3path, s3Meta); err != nil {
+			meta.lastError = err
+			meta.resStatus = needRetry
+			return fmt.Errorf("error while storing the digest of %v. %w", meta.name, err)
+		}
+	}
 	meta.dstFileSize = meta.uploadSize
 	meta.resStatus = uploaded
 	return nil
 }
 
+// storeStreamedDigest copies a streamed object onto itself to add the digest
+// that was computed while it was uploaded to its metadata.
+func (util *snowflakeS3Client) storeStreamedDigest(meta *fileMetadata, copier s3CopyObjectAPI, bucket string, key string, s3Meta map[string]string) error {
+	if meta.uploadSize > s3MaxCopyObjectSize {
+		logger.Warnf("%v is too large to store its digest, it is uploaded without it", key)
+		return nil
+	}
+	s3Meta = maps.Clone(s3Meta)
+	s3Meta[sfcDigest] = meta.sha256Digest
+	source := (&url.URL{Path: bucket + "/" + key}).EscapedPath()
+	_, err := withCloudStorageTimeout(meta.transferContext(), util.cfg, func(ctx context.Context) (*s3.CopyObjectOutput, error) {
+		return copier.CopyObject(ctx, &s3.CopyObjectInput{
+			Bucket:            &bucket,
+			Key:               &key,
+			CopySource:        &source,
+			Metadata:          s3Meta,
+			MetadataDirective: types.MetadataDirectiveReplace,
+		})
+	})
+	return err
+}
+
+// abortCanceledMultipartUpload aborts the multipart upload of a cancelled PUT.
+// The uploader aborts failed uploads itself, but with the cancelled context.
+func (util *snowflakeS3Client) abortCanceledMultipartUpload(meta *fileMetadata, aborter s3AbortMultipartUploadAPI, bucket string, key string, err error) {
+	var failure manager.MultiUploadFailure
+	if meta.transferContext().Err() == nil || !errors.As(err, &failure) {
+		return
+	}
+	_, abortErr := withCloudStorageTimeout(context.Background(), util.cfg, func(ctx context.Context) (*s3.AbortMultipartUploadOutput, error) {
+		return aborter.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
+			Bucket:   &bucket,
+			Key:      &key,
+			UploadId: aws.String(failure.UploadID()),
+		})
+	})
+	if abortErr != nil {
+		logger.Warnf("failed to abort multipart upload %v of %v: %v", failure.UploadID(), key, abortErr)
+	}
+}
+
 type s3DownloadAPI interface {
 	Download(ctx context.Context, w io.WriterAt, params *s3.GetObjectInput, optFns ...func(*manager.Downloader)) (int64, error)
 }
@@ -296,10 +382,10 @@ func (util *snowflakeS3Client) nativeDownloadFile(
 		downloader = meta.mockDownloader
 	}
 
-	_, err := withCloudStorageTimeout(util.cfg, func(ctx context.Context) (any, error) {
+	_, err := withCloudStorageTimeout(meta.transferContext(), util.cfg, func(ctx context.Context) (any, error) {
 		if meta.options != nil && meta.options.GetFileToStream {
 			buf := manager.NewWriteAtBuffer([]byte{})
-			if _, err := downloader.Download(ctx, buf, &s3.GetObjectInput{
+			if _, err := downloader.Download(ctx, meta.progressWriterAt(buf), &s3.GetObjectInput{
 				Bucket: s3Obj.Bucket,
 				Key:    s3Obj.Key,
 			}); err != nil {
@@ -316,7 +402,7 @@ func (util *snowflakeS3Client) nativeDownloadFile(
 					logger.Warnf("failed to close %v file: %v", fullDstFileName, err)
 				}
 			}()
-			if _, err = downloader.Download(ctx, f, &s3.GetObjectInput{
+			if _, err = downloader.Download(ctx, meta.progressWriterAt(f), &s3.GetObjectInput{
 				Bucket: s3Obj.Bucket,
 				Key:    s3Obj.Key,
 			}); err != nil {
@@ -56,6 +56,15 @@ func (m mockUploadObjectAPI) Upload(
 	return m(ctx, params, optFns...)
 }
 
+type mockCopyObjectAPI func(ctx context.Context, params *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error)
+
+func (m mockCopyObjectAPI) CopyObject(
+	ctx context.Context,
+	params *s3.CopyObjectInput,
+	optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error) {
+	return m(ctx, params, optFns...)
+}
+
 func TestUploadOneFileToS3WSAEConnAborted(t *testing.T) {
 	info := execResponseStageInfo{
 		Location:     "sfc-customer-stage/rwyi-testacco/users/9220/",
@@ -0,0 +1,166 @@
+package gosnowflake
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ScriptOptions configures ExecScript.
+type ScriptOptions struct {
+	// Variables are substituted for &name and &{name} in the statements, like the variables of SnowSQL.
+	// Names are case-insensitive and references in comments are not substituted. Substitution is disabled
+	// if Variables is nil, otherwise a reference to an undefined variable is an error.
+	Variables map[string]string
+	// ContinueOnError runs the statements after a failed one. By default the script stops at the first
+	// failed statement.
+	ContinueOnError bool
+}
+
+// ExecScript runs the statements of a SQL script one at a time in a single session of db and returns the
+// result of each statement that ran. The script is split at the semicolons outside of string literals,
+// quoted identifiers, comments, $$ blocks and Snowflake Scripting BEGIN ... END blocks. The rows returned
+// by queries are not kept. A ? in the script is not a bind variable.
+//
+// If the script stops at a failed statement, the result is returned along with the error of the statement.
+// With ContinueOnError, MultiStatementResult.Err returns the error of the first failed statement.
+func ExecScript(ctx context.Context, db *sql.DB, script io.Reader, options *ScriptOptions) (*MultiStatementResult, error) {
+	if options == nil {
+		options = &ScriptOptions{}
+	}
+	b, err := io.ReadAll(script)
+	if err != nil {
+		return nil, err
+	}
+	statements := splitStatements(string(b))
+	for i := range statements {
+		// scripts have no bindings, a ? is sent as is, e.g. in a JSON path
+		statements[i].binds = 0
+	}
+	if options.Variables != nil {
+		if statements, err = substituteVariables(statements, options.Variables); err != nil {
+			return nil, err
+		}
+	}
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := conn.Close(); err != nil {
+			logger.WithContext(ctx).Warnf("failed to close the connection of the script. err: %v", err)
+		}
+	}()
+	var mr *MultiStatementResult
+	err = conn.Raw(func(x any) error {
+		sc, ok := x.(*snowflakeConn)
+		if !ok {
+			return fmt.Errorf("ExecScript requires a Snowflake connection, got %T", x)
+		}
+		if sc.rest == nil {
+			return driver.ErrBadConn
+		}
+		_, _, sessionID := safeGetTokens(sc.rest)
+		ctx := context.WithValue(ctx, SFSessionIDKey, sessionID)
+		mr, err = sc.execStatements(ctx, statements, nil, options.ContinueOnError, false)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !options.ContinueOnError {
+		return mr, mr.Err()
+	}
+	return mr, nil
+}
+
+// substituteVariables returns the statements with the references to variables replaced by their values.
+func substituteVariables(statements []splitStatement, variables map[string]string) ([]splitStatement, error) {
+	values := make(map[string]string, len(variables))
+	for name, value := range variables {
+		values[strings.ToLower(name)] = value
+	}
+	substituted := make([]splitStatement, len(statements))
+	for i, stmt := range statements {
+		text, err := substituteStatementVariables(stmt.text, values)
+		if err != nil {
+			return nil, err
+		}
+		substituted[i] = splitStatement{text, stmt.binds}
+	}
+	return substituted, nil
+}
+
+// substituteStatementVariables replaces &name and &{name} outside of comments with the values of the
+// variables, whose names are in lower case.
+func substituteStatementVariables(text string, values map[string]string) (string, error) {
+	var b strings.Builder
+	var closing string // delimiter closing the literal or $$ block being copied
+	for i := 0; i < len(text); {
+		rest := text[i:]
+		n := 1
+		switch {
+		case closing != "" && strings.HasPrefix(rest, closing):
+			n, closing = len(closing), ""
+		case closing == "'" && rest[0] == '\\':
+			n = min(2, len(rest))
+		case closing == "" && (strings.HasPrefix(rest, "--") || strings.HasPrefix(rest, "//")):
+			n = skipUntil(text, i, "\n") - i
+		case closing == "" && strings.HasPrefix(rest, "/*"):
+			n = skipUntil(text, i+2, "*/") - i
+		case closing == "" && strings.HasPrefix(rest, "$$"):
+			n, closing = 2, "$$"
+		case closing == "" && (rest[0] == '\'' || rest[0] == '"'):
+			closing = rest[:1]
+		case rest[0] == '&':
+			name, end := variableReference(rest)
+			if name == "" {
+				break
+			}
+			value, ok := values[strings.ToLower(name)]
+			if !ok {
+				return "", &SnowflakeError{
+					Number:      ErrScriptVariable,
+					Message:     errMsgScriptVariable,
+					MessageArgs: []interface{}{name},
+				}
+			}
+			b.WriteString(value)
+			i += end
+			continue
+		}
+		b.WriteString(rest[:n])
+		i += n
+	}
+	return b.String(), nil
+}
+
+// variableReference returns the name of the variable referenced by &name or &{name} at the start of s
+// and the length of the reference, or an empty name if s does not start with a reference.
+func variableReference(s string) (string, int) {
+	if strings.HasPrefix(s, "&{") {
+		end := strings.IndexByte(s, '}')
+		if end < 0 || !isVariableName(s[2:end]) {
+			return "", 0
+		}
+		return s[2:end], end + 1
+	}
+	end := 1
+	for end < len(s) && (isWordStart(s[end]) || end > 1 && '0' <= s[end] && s[end] <= '9') {
+		end++
+	}
+	return s[1:end], end
+}
+
+func isVariableName(name string) bool {
+	for i := 0; i < len(name); i++ {
+		if !isWordStart(name[i]) && (i == 0 || name[i] < '0' || name[i] > '9') {
+			return false
+		}
+	}
+	return name != ""
+}
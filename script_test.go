@@ -0,0 +1,169 @@
+package gosnowflake
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+type scriptTestConnector struct {
+	sc *snowflakeConn
+}
+
+func (c scriptTestConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.sc, nil
+}
+
+func (c scriptTestConnector) Driver() driver.Driver {
+	return SnowflakeDriver{}
+}
+
+func TestUnitExecScript(t *testing.T) {
+	var queries []string
+	postQueryMock := func(_ context.Context, _ *snowflakeRestful, _ *url.Values, _ map[string]string, body []byte,
+		_ time.Duration, _ UUID, _ *Config) (*execResponse, error) {
+		var req execRequest
+		assertNilF(t, json.Unmarshal(body, &req))
+		queries = append(queries, req.SQLText)
+		if strings.Contains(req.SQLText, "missing") {
+			return &execResponse{Data: execResponseData{QueryID: "failed", SQLState: "42S02"},
+				Code: "2003", Message: "Table 'MISSING' does not exist", Success: false}, nil
+		}
+		count := "1"
+		return &execResponse{Data: execResponseData{
+			QueryID:         NewUUID().String(),
+			StatementTypeID: statementTypeIDDml + 0x100,
+			RowType:         []execResponseRowType{{Name: "number of rows inserted", Type: "fixed"}},
+			RowSet:          [][]*string{{&count}},
+		}, Success: true}, nil
+	}
+	sc := newMultiStatementTestConn(t, postQueryMock)
+	sc.cfg.KeepSessionAlive = true
+	sc.telemetry = &snowflakeTelemetry{enabled: false}
+	db := sql.OpenDB(scriptTestConnector{sc})
+	defer db.Close()
+	script := "insert into &{schema}.t values (1);\ninsert into missing values (2);\n" +
+		"// inserts into &undefined\ninsert into &SCHEMA.t values ('&&');"
+	options := &ScriptOptions{Variables: map[string]string{"schema": "public"}}
+
+	res, err := ExecScript(context.Background(), db, strings.NewReader(script), options)
+	var se *SnowflakeError
+	assertTrueF(t, errors.As(err, &se))
+	assertEqualE(t, se.Number, 2003)
+	assertNotNilF(t, res)
+	assertEqualF(t, len(res.Statements), 2, "the script should stop at the failed statement")
+	assertEqualE(t, res.Statements[0].SQLText, "insert into public.t values (1)")
+	assertEqualE(t, res.Statements[0].RowsAffected, int64(1))
+	assertEqualE(t, res.Statements[1].QueryID, "failed")
+
+	queries = nil
+	options.ContinueOnError = true
+	res, err = ExecScript(context.Background(), db, strings.NewReader(script), options)
+	assertNilF(t, err)
+	assertEqualF(t, len(res.Statements), 3)
+	assertNotNilE(t, res.Err())
+	assertEqualE(t, res.RowsAffected(), int64(2))
+	assertDeepEqualE(t, queries, []string{
+		"insert into public.t values (1)",
+		"insert into missing values (2)",
+		"// inserts into &undefined\ninsert into public.t values ('&&')",
+	})
+
+	_, err = ExecScript(context.Background(), db, strings.NewReader(script), &ScriptOptions{Variables: map[string]string{}})
+	assertTrueF(t, errors.As(err, &se))
+	assertEqualE(t, se.Number, ErrScriptVariable)
+}
+
+func TestUnitExecScriptWithQuestionMarks(t *testing.T) {
+	var queries []string
+	postQueryMock := func(_ context.Context, _ *snowflakeRestful, _ *url.Values, _ map[string]string, body []byte,
+		_ time.Duration, _ UUID, _ *Config) (*execResponse, error) {
+		var req execRequest
+		assertNilF(t, json.Unmarshal(body, &req))
+		assertEqualE(t, len(req.Bindings), 0)
+		queries = append(queries, req.SQLText)
+		return &execResponse{Data: execResponseData{QueryID: NewUUID().String()}, Success: true}, nil
+	}
+	sc := newMultiStatementTestConn(t, postQueryMock)
+	sc.cfg.KeepSessionAlive = true
+	sc.telemetry = &snowflakeTelemetry{enabled: false}
+	db := sql.OpenDB(scriptTestConnector{sc})
+	defer db.Close()
+	script := "select v:\"a?\" from t where v ? 'key';\nselect parse_json('{\"q\": \"?\"}')"
+
+	res, err := ExecScript(context.Background(), db, strings.NewReader(script), nil)
+	assertNilF(t, err)
+	assertEqualE(t, len(res.Statements), 2)
+	assertDeepEqualE(t, queries, []string{
+		"select v:\"a?\" from t where v ? 'key'",
+		"select parse_json('{\"q\": \"?\"}')",
+	})
+}
+
+func TestUnitSplitScriptStatements(t *testing.T) {
+	testcases := []struct {
+		name   string
+		script string
+		out    []string
+	}{
+		{"anonymous block", "BEGIN\n  CREATE TABLE t (a INT);\n  INSERT INTO t VALUES (1);\nEND;\nSELECT 1;",
+			[]string{"BEGIN\n  CREATE TABLE t (a INT);\n  INSERT INTO t VALUES (1);\nEND", "SELECT 1"}},
+		{"transaction", "BEGIN;\nINSERT INTO t VALUES (1);\nbegin transaction;\nCOMMIT;",
+			[]string{"BEGIN", "INSERT INTO t VALUES (1)", "begin transaction", "COMMIT"}},
+		{"declare and control statements",
+			"DECLARE\n  x INT DEFAULT 0;\nBEGIN\n  FOR i IN 1 TO 3 DO\n    x := x + i;\n  END FOR;\n" +
+				"  IF (x > 5) THEN\n    RETURN CASE WHEN x > 10 THEN 'big' ELSE 'small' END;\n  END IF;\n" +
+				"  CASE (x)\n    WHEN 6 THEN RETURN 'six';\n  END CASE;\nEND;\nSELECT 2",
+			[]string{"DECLARE\n  x INT DEFAULT 0;\nBEGIN\n  FOR i IN 1 TO 3 DO\n    x := x + i;\n  END FOR;\n" +
+				"  IF (x > 5) THEN\n    RETURN CASE WHEN x > 10 THEN 'big' ELSE 'small' END;\n  END IF;\n" +
+				"  CASE (x)\n    WHEN 6 THEN RETURN 'six';\n  END CASE;\nEND", "SELECT 2"}},
+		{"nested block", "BEGIN\n  BEGIN\n    SELECT 1;\n  EXCEPTION\n    WHEN OTHER THEN RETURN 0;\n  END;\n  LOOP\n    BREAK;\n  END LOOP;\nEND;",
+			[]string{"BEGIN\n  BEGIN\n    SELECT 1;\n  EXCEPTION\n    WHEN OTHER THEN RETURN 0;\n  END;\n  LOOP\n    BREAK;\n  END LOOP;\nEND"}},
+		{"procedure", "CREATE OR REPLACE PROCEDURE p() RETURNS INT LANGUAGE SQL AS BEGIN RETURN 1; END;\nCALL p();",
+			[]string{"CREATE OR REPLACE PROCEDURE p() RETURNS INT LANGUAGE SQL AS BEGIN RETURN 1; END", "CALL p()"}},
+		{"case outside of blocks", "SELECT CASE WHEN a THEN 1 END AS end_value FROM t; SELECT 3",
+			[]string{"SELECT CASE WHEN a THEN 1 END AS end_value FROM t", "SELECT 3"}},
+		{"execute immediate", "EXECUTE IMMEDIATE $$ BEGIN RETURN 1; END; $$; SELECT 4",
+			[]string{"EXECUTE IMMEDIATE $$ BEGIN RETURN 1; END; $$", "SELECT 4"}},
+	}
+	for _, test := range testcases {
+		t.Run(test.name, func(t *testing.T) {
+			var texts []string
+			for _, stmt := range splitStatements(test.script) {
+				texts = append(texts, stmt.text)
+			}
+			assertDeepEqualE(t, texts, test.out)
+		})
+	}
+}
+
+func TestUnitSubstituteStatementVariables(t *testing.T) {
+	values := map[string]string{"db": "prod", "table_1": "events"}
+	testcases := []struct {
+		in  string
+		out string
+	}{
+		{"select * from &db.public.&{table_1}", "select * from prod.public.events"},
+		{"select '&db', \"&db\" -- &undefined\n/* &undefined */", "select 'prod', \"prod\" -- &undefined\n/* &undefined */"},
+		{"select 'it''s &db', 'a\\'&db' from t where a && b & 1", "select 'it''s prod', 'a\\'prod' from t where a && b & 1"},
+		{"create function f() returns string language javascript as $$ return '&db'; // &db\n $$", "create function f() returns string language javascript as $$ return 'prod'; // prod\n $$"},
+		{"select &{not a name}", "select &{not a name}"},
+	}
+	for _, test := range testcases {
+		t.Run(test.in, func(t *testing.T) {
+			out, err := substituteStatementVariables(test.in, values)
+			assertNilF(t, err)
+			assertEqualE(t, out, test.out)
+		})
+	}
+
+	_, err := substituteStatementVariables("select &Undefined", values)
+	assertNotNilF(t, err)
+	assertEqualE(t, err.Error(), "267003: variable Undefined is not defined")
+}
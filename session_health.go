@@ -0,0 +1,69 @@
+package gosnowflake
+
+import (
+	"context"
+	"database/sql/driver"
+	"sync"
+	"time"
+)
+
+// sessionHealth is what is known about the session without a round trip to the server.
+type sessionHealth struct {
+	mu sync.Mutex
+	// masterTokenExpiry is when the master token expires, zero if not known.
+	masterTokenExpiry time.Time
+	// lost is set when the server reported the session gone or rejected the master token.
+	lost bool
+}
+
+// setMasterTokenValidity records the validity of a new master token, as decoded from the number of seconds
+// in the login and renewal responses.
+func (h *sessionHealth) setMasterTokenValidity(validity time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.lost = false
+	h.masterTokenExpiry = time.Time{}
+	if validity > 0 {
+		h.masterTokenExpiry = time.Now().Add(validity * time.Second)
+	}
+}
+
+func (h *sessionHealth) markLost() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.lost = true
+}
+
+func (h *sessionHealth) valid(now time.Time) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return !h.lost && (h.masterTokenExpiry.IsZero() || now.Before(h.masterTokenExpiry))
+}
+
+// IsValid implements driver.Validator. It reports without a round trip whether the session can still be used,
+// i.e. the connection is not closed, its master token has not expired and neither the heartbeat nor a session
+// renewal found the session gone. database/sql discards invalid connections instead of returning them to the pool.
+func (sc *snowflakeConn) IsValid() bool {
+	return sc.rest != nil && sc.rest.session.valid(time.Now())
+}
+
+// ResetSession implements driver.SessionResetter. database/sql calls it before reusing a connection from the pool.
+// It rolls back a transaction left open by the previous user of the connection, and restores the role, warehouse,
+// database, schema and session parameters set at login if they were changed. The connection is discarded if its
+// session is not valid anymore or cannot be reset.
+func (sc *snowflakeConn) ResetSession(ctx context.Context) error {
+	if !sc.IsValid() {
+		logger.WithContext(ctx).Info("discarding a connection with an invalid session")
+		return driver.ErrBadConn
+	}
+	_, _, sessionID := safeGetTokens(sc.rest)
+	ctx = context.WithValue(ctx, SFSessionIDKey, sessionID)
+	for _, stmt := range sc.resetSessionStatements() {
+		if _, err := sc.exec(ctx, stmt, false, true, false, nil); err != nil {
+			logger.WithContext(ctx).Warnf("failed to reset the session, discarding the connection. err: %v", err)
+			return driver.ErrBadConn
+		}
+	}
+	sc.resetSessionState()
+	return nil
+}
@@ -0,0 +1,98 @@
+package gosnowflake
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestUnitIsValid(t *testing.T) {
+	sr := &snowflakeRestful{TokenAccessor: getSimpleTokenAccessor()}
+	sc := &snowflakeConn{cfg: &Config{}, rest: sr}
+	assertTrueE(t, sc.IsValid(), "a session with an unknown validity should be valid")
+
+	sr.session.setMasterTokenValidity(4 * 3600)
+	assertTrueE(t, sc.IsValid())
+	assertFalseE(t, sr.session.valid(time.Now().Add(5*time.Hour)), "the master token should have expired")
+
+	sr.FuncPost = func(context.Context, *snowflakeRestful, *url.URL, map[string]string, []byte, time.Duration, currentTimeProvider, *Config) (*http.Response, error) {
+		body := `{"code": "390111", "message": "Session no longer exists.", "success": false}`
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewReader([]byte(body)))}, nil
+	}
+	err := newDefaultHeartBeat(sr).heartbeatMain()
+	assertNotNilF(t, err)
+	assertEqualE(t, err.(*SnowflakeError).Number, ErrSessionGone)
+	assertFalseE(t, sc.IsValid(), "the heartbeat found the session gone")
+
+	sr.session.setMasterTokenValidity(4 * 3600)
+	assertTrueE(t, sc.IsValid(), "a renewed session should be valid")
+	sc.rest = nil
+	assertFalseE(t, sc.IsValid(), "a closed connection should be invalid")
+}
+
+func TestUnitResetSession(t *testing.T) {
+	var queries []string
+	postQueryMock := func(_ context.Context, _ *snowflakeRestful, _ *url.Values, _ map[string]string, body []byte,
+		_ time.Duration, _ UUID, _ *Config) (*execResponse, error) {
+		var req execRequest
+		assertNilF(t, json.Unmarshal(body, &req))
+		queries = append(queries, req.SQLText)
+		data := execResponseData{QueryID: NewUUID().String()}
+		switch req.SQLText {
+		case "begin":
+			data.StatementTypeID = statementTypeIDTcl
+		case "use role analyst":
+			data.FinalRoleName = "ANALYST"
+		case "alter session set query_tag = 'etl', timezone = 'UTC'":
+			data.Parameters = []nameValueParameter{{Name: "QUERY_TAG", Value: "etl"}, {Name: "TIMEZONE", Value: "UTC"}}
+		}
+		return &execResponse{Data: data, Success: true}, nil
+	}
+	timezone := "America/Los_Angeles"
+	sc := &snowflakeConn{
+		cfg:                 &Config{Role: "sysadmin", Params: map[string]*string{"timezone": &timezone}, KeepSessionAlive: true},
+		rest:                &snowflakeRestful{FuncPostQuery: postQueryMock, TokenAccessor: getSimpleTokenAccessor()},
+		telemetry:           &snowflakeTelemetry{enabled: false},
+		queryContextCache:   (&queryContextCache{}).init(),
+		currentTimeProvider: defaultTimeProvider,
+	}
+	sc.setInitialSessionState(authResponseSessionInfo{RoleName: "SYSADMIN", WarehouseName: "WH"})
+	db := sql.OpenDB(scriptTestConnector{sc})
+	defer db.Close()
+	ctx := context.Background()
+
+	conn, err := db.Conn(ctx)
+	assertNilF(t, err)
+	for _, query := range []string{"begin", "use role analyst", "alter session set query_tag = 'etl', timezone = 'UTC'"} {
+		_, err = conn.ExecContext(ctx, query)
+		assertNilF(t, err)
+	}
+	assertNilF(t, conn.Close())
+	queries = nil
+	_, err = db.ExecContext(ctx, "select 1")
+	assertNilF(t, err)
+	assertDeepEqualE(t, queries, []string{
+		"ROLLBACK",
+		"USE ROLE SYSADMIN",
+		"ALTER SESSION SET TIMEZONE = 'America/Los_Angeles'",
+		"ALTER SESSION UNSET QUERY_TAG",
+		"select 1",
+	})
+	assertEqualE(t, sc.SessionState().Role, "SYSADMIN")
+	assertEqualE(t, len(sc.SessionState().Parameters), 0)
+
+	queries = nil
+	_, err = db.ExecContext(ctx, "select 2")
+	assertNilF(t, err)
+	assertDeepEqualE(t, queries, []string{"select 2"}, "an unchanged session should not be reset")
+
+	sc.rest.session.markLost()
+	assertErrIsE(t, sc.ResetSession(ctx), driver.ErrBadConn)
+}
@@ -0,0 +1,303 @@
+package gosnowflake
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// SessionState is the session-level state of a connection, as reported by the server after each query.
+// Temporary objects and session variables are not part of it.
+type SessionState struct {
+	Role      string
+	Warehouse string
+	Database  string
+	Schema    string
+	// Parameters contains the session parameters changed since the connection was opened, e.g. with ALTER SESSION.
+	Parameters map[string]string
+}
+
+// SessionStateConnection is implemented by the connections of the driver, which can be reached with sql.Conn.Raw.
+type SessionStateConnection interface {
+	SessionState() SessionState
+}
+
+type sessionStateTracker struct {
+	mu         sync.Mutex
+	parameters map[string]string
+	// initial is the state of the session after login, with the parameters in lower case.
+	initial SessionState
+	// initialChanges are the parameters changed by the init statements and hooks of the config.
+	initialChanges map[string]string
+	// transactionOpen is set when a statement may have left a transaction open.
+	transactionOpen atomic.Bool
+}
+
+// SessionState returns the current session state of the connection.
+func (sc *snowflakeConn) SessionState() SessionState {
+	sc.sessionState.mu.Lock()
+	defer sc.sessionState.mu.Unlock()
+	state := SessionState{
+		Role:       sc.cfg.Role,
+		Warehouse:  sc.cfg.Warehouse,
+		Database:   sc.cfg.Database,
+		Schema:     sc.cfg.Schema,
+		Parameters: make(map[string]string),
+	}
+	for name, value := range sc.sessionState.parameters {
+		state.Parameters[name] = value
+	}
+	return state
+}
+
+// updateSessionNames records the current database, schema, warehouse and role returned with a query result.
+func (sc *snowflakeConn) updateSessionNames(data *execResponseData) {
+	sc.sessionState.mu.Lock()
+	defer sc.sessionState.mu.Unlock()
+	sc.cfg.Database = cmp.Or(data.FinalDatabaseName, sc.cfg.Database)
+	sc.cfg.Schema = cmp.Or(data.FinalSchemaName, sc.cfg.Schema)
+	sc.cfg.Warehouse = cmp.Or(data.FinalWarehouseName, sc.cfg.Warehouse)
+	sc.cfg.Role = cmp.Or(data.FinalRoleName, sc.cfg.Role)
+}
+
+// trackSessionParameters records the parameters returned with a query result whose values differ from the known ones.
+// It must be called before the parameters are applied to the config.
+func (sc *snowflakeConn) trackSessionParameters(parameters []nameValueParameter) {
+	sc.sessionState.mu.Lock()
+	defer sc.sessionState.mu.Unlock()
+	for _, param := range parameters {
+		value := sessionParameterString(param.Value)
+		paramsMutex.Lock()
+		current, ok := sc.cfg.Params[strings.ToLower(param.Name)]
+		paramsMutex.Unlock()
+		if ok && *current == value {
+			continue
+		}
+		if sc.sessionState.parameters == nil {
+			sc.sessionState.parameters = make(map[string]string)
+		}
+		sc.sessionState.parameters[strings.ToUpper(param.Name)] = value
+	}
+}
+
+func sessionParameterString(value any) string {
+	switch v := value.(type) {
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case string:
+		return v
+	default:
+		return ""
+	}
+}
+
+// setInitialSessionState records the state of the session after login, which ResetSession restores.
+func (sc *snowflakeConn) setInitialSessionState(info authResponseSessionInfo) {
+	initial := SessionState{
+		Role:       cmp.Or(info.RoleName, sc.cfg.Role),
+		Warehouse:  cmp.Or(info.WarehouseName, sc.cfg.Warehouse),
+		Database:   cmp.Or(info.DatabaseName, sc.cfg.Database),
+		Schema:     cmp.Or(info.SchemaName, sc.cfg.Schema),
+		Parameters: sc.parameterValues(),
+	}
+	sc.sessionState.mu.Lock()
+	defer sc.sessionState.mu.Unlock()
+	sc.sessionState.initial = initial
+}
+
+// updateInitialSessionState makes the current state of the session the one ResetSession restores, after the
+// init statements and hooks of the config changed it.
+func (sc *snowflakeConn) updateInitialSessionState() {
+	current := sc.SessionState()
+	parameters := sc.parameterValues()
+	sc.sessionState.mu.Lock()
+	defer sc.sessionState.mu.Unlock()
+	initial := &sc.sessionState.initial
+	initial.Role = cmp.Or(current.Role, initial.Role)
+	initial.Warehouse = cmp.Or(current.Warehouse, initial.Warehouse)
+	initial.Database = cmp.Or(current.Database, initial.Database)
+	initial.Schema = cmp.Or(current.Schema, initial.Schema)
+	initial.Parameters = parameters
+	sc.sessionState.initialChanges = current.Parameters
+}
+
+func (sc *snowflakeConn) parameterValues() map[string]string {
+	paramsMutex.Lock()
+	defer paramsMutex.Unlock()
+	values := make(map[string]string, len(sc.cfg.Params))
+	for name, value := range sc.cfg.Params {
+		if value != nil {
+			values[name] = *value
+		}
+	}
+	return values
+}
+
+// trackTransaction records whether a transaction control statement may have left a transaction open.
+func (sc *snowflakeConn) trackTransaction(query string, statementTypeID int64) {
+	if statementTypeID != statementTypeIDTcl {
+		return
+	}
+	command := peekWord(query, 0)
+	sc.sessionState.transactionOpen.Store(command != "COMMIT" && command != "ROLLBACK")
+}
+
+// resetSessionStatements returns the statements rolling back an open transaction and restoring the state of the
+// session after login.
+func (sc *snowflakeConn) resetSessionStatements() []string {
+	var stmts []string
+	if sc.sessionState.transactionOpen.Load() {
+		stmts = append(stmts, "ROLLBACK")
+	}
+	current := sc.SessionState()
+	sc.sessionState.mu.Lock()
+	defer sc.sessionState.mu.Unlock()
+	initial := sc.sessionState.initial
+	// names from the config may differ in case from the ones reported by the server, and are empty until the
+	// server reported them if the config does not set them
+	changed := func(initial, current string) bool {
+		return initial != "" && current != "" && !strings.EqualFold(initial, current)
+	}
+	var restore SessionState
+	if changed(initial.Role, current.Role) {
+		restore.Role = initial.Role
+	}
+	if changed(initial.Warehouse, current.Warehouse) {
+		restore.Warehouse = initial.Warehouse
+	}
+	// USE DATABASE also changes the schema
+	if changed(initial.Database, current.Database) {
+		restore.Database, restore.Schema = initial.Database, initial.Schema
+	}
+	if changed(initial.Schema, current.Schema) {
+		restore.Schema = initial.Schema
+	}
+	var unset []string
+	for name, value := range current.Parameters {
+		initialValue, ok := initial.Parameters[strings.ToLower(name)]
+		switch {
+		case !ok:
+			unset = append(unset, name)
+		case value != initialValue:
+			if restore.Parameters == nil {
+				restore.Parameters = make(map[string]string)
+			}
+			restore.Parameters[name] = initialValue
+		}
+	}
+	stmts = append(stmts, sessionStateStatements(restore)...)
+	if len(unset) > 0 {
+		sort.Strings(unset)
+		stmts = append(stmts, "ALTER SESSION UNSET "+strings.Join(unset, ", "))
+	}
+	return stmts
+}
+
+// resetSessionState forgets the changes to the session state after it was restored by ResetSession.
+func (sc *snowflakeConn) resetSessionState() {
+	sc.sessionState.transactionOpen.Store(false)
+	sc.sessionState.mu.Lock()
+	defer sc.sessionState.mu.Unlock()
+	sc.sessionState.parameters = maps.Clone(sc.sessionState.initialChanges)
+	initial := sc.sessionState.initial
+	sc.cfg.Role = cmp.Or(initial.Role, sc.cfg.Role)
+	sc.cfg.Warehouse = cmp.Or(initial.Warehouse, sc.cfg.Warehouse)
+	sc.cfg.Database = cmp.Or(initial.Database, sc.cfg.Database)
+	sc.cfg.Schema = cmp.Or(initial.Schema, sc.cfg.Schema)
+}
+
+// loginAgain opens a new session for the connection. The state of the session after the first login is kept, so
+// that ResetSession still restores it.
+func (sc *snowflakeConn) loginAgain() error {
+	sc.sessionState.mu.Lock()
+	initial, initialChanges := sc.sessionState.initial, sc.sessionState.initialChanges
+	sc.sessionState.mu.Unlock()
+	if err := authenticateWithConfig(sc); err != nil {
+		return err
+	}
+	sc.sessionState.mu.Lock()
+	defer sc.sessionState.mu.Unlock()
+	sc.sessionState.initial, sc.sessionState.initialChanges = initial, initialChanges
+	return nil
+}
+
+// restoreSessionState replays the session state on a new session if Config.RestoreSessionState is enabled.
+// ctx is the context of the request that replaced the session. Only its deadline is kept, so that the replay is not
+// sent e.g. as a multi-statement, asynchronous or retried request of the query that triggered it.
+func (sc *snowflakeConn) restoreSessionState(ctx context.Context) error {
+	if !sc.cfg.RestoreSessionState {
+		return nil
+	}
+	_, _, sessionID := safeGetTokens(sc.rest)
+	replayCtx := context.WithValue(context.Background(), SFSessionIDKey, sessionID)
+	if deadline, ok := ctx.Deadline(); ok {
+		var cancel context.CancelFunc
+		replayCtx, cancel = context.WithDeadline(replayCtx, deadline)
+		defer cancel()
+	}
+	ctx = WithInternal(replayCtx)
+	logger.WithContext(ctx).Info("restoring session state on the new session")
+	for _, stmt := range sessionStateStatements(sc.SessionState()) {
+		if _, err := sc.exec(ctx, stmt, false, true, false, nil); err != nil {
+			return fmt.Errorf("restoring session state: %w", err)
+		}
+	}
+	return nil
+}
+
+func sessionStateStatements(state SessionState) []string {
+	var stmts []string
+	for _, use := range []struct{ kind, name string }{
+		{"ROLE", state.Role},
+		{"WAREHOUSE", state.Warehouse},
+		{"DATABASE", state.Database},
+		{"SCHEMA", state.Schema},
+	} {
+		if use.name != "" {
+			stmts = append(stmts, fmt.Sprintf("USE %v %v", use.kind, quoteSessionIdentifier(use.name)))
+		}
+	}
+	if len(state.Parameters) == 0 {
+		return stmts
+	}
+	names := make([]string, 0, len(state.Parameters))
+	for name := range state.Parameters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	assignments := make([]string, len(names))
+	for i, name := range names {
+		assignments[i] = name + " = " + sessionParameterLiteral(state.Parameters[name])
+	}
+	return append(stmts, "ALTER SESSION SET "+strings.Join(assignments, ", "))
+}
+
+func sessionParameterLiteral(value string) string {
+	if _, err := strconv.ParseFloat(value, 64); err == nil {
+		return value
+	}
+	if _, err := strconv.ParseBool(value); err == nil {
+		return value
+	}
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+// quoteSessionIdentifier quotes the name unless it is a plain upper-case identifier, as returned for unquoted names.
+func quoteSessionIdentifier(name string) string {
+	for i, r := range name {
+		if !(r >= 'A' && r <= 'Z' || r == '_' || i > 0 && (r >= '0' && r <= '9' || r == '$')) {
+			return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+		}
+	}
+	return name
+}
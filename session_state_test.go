@@ -0,0 +1,193 @@
+package gosnowflake
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTrackSessionParameters(t *testing.T) {
+	timezone := "UTC"
+	sc := &snowflakeConn{cfg: &Config{
+		Role: "R", Warehouse: "WH", Database: "DB", Schema: "S",
+		Params: map[string]*string{"timezone": &timezone},
+	}}
+	params := []nameValueParameter{
+		{Name: "TIMEZONE", Value: "UTC"},
+		{Name: "QUERY_TAG", Value: "it's"},
+		{Name: "LOCK_TIMEOUT", Value: float64(10)},
+	}
+	sc.trackSessionParameters(params)
+	sc.populateSessionParameters(params)
+	sc.trackSessionParameters([]nameValueParameter{{Name: "TIMEZONE", Value: "Europe/Warsaw"}})
+
+	state := sc.SessionState()
+	assertEqualE(t, state.Role, "R")
+	assertEqualE(t, state.Schema, "S")
+	assertDeepEqualE(t, state.Parameters, map[string]string{"QUERY_TAG": "it's", "LOCK_TIMEOUT": "10", "TIMEZONE": "Europe/Warsaw"})
+	state.Parameters["X"] = "y"
+	assertEqualE(t, len(sc.SessionState().Parameters), 3, "returned state should be a copy")
+}
+
+func TestSessionStateStatements(t *testing.T) {
+	stmts := sessionStateStatements(SessionState{
+		Role:       "PUBLIC",
+		Warehouse:  "my wh",
+		Schema:     "S_1",
+		Parameters: map[string]string{"QUERY_TAG": "it's", "LOCK_TIMEOUT": "10", "AUTOCOMMIT": "false"},
+	})
+	assertDeepEqualE(t, stmts, []string{
+		"USE ROLE PUBLIC",
+		`USE WAREHOUSE "my wh"`,
+		"USE SCHEMA S_1",
+		"ALTER SESSION SET AUTOCOMMIT = false, LOCK_TIMEOUT = 10, QUERY_TAG = 'it''s'",
+	})
+	assertEqualE(t, len(sessionStateStatements(SessionState{})), 0)
+}
+
+// renewalRejectedPost answers the session renewal requests with the error code of a master token that expired.
+func renewalRejectedPost(_ context.Context, _ *snowflakeRestful, _ *url.URL, _ map[string]string, _ []byte, _ time.Duration, _ currentTimeProvider, _ *Config) (*http.Response, error) {
+	body := `{"code": "390114", "message": "Authentication token has expired.", "success": false}`
+	return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body))}, nil
+}
+
+// loginNewSession answers the login requests with session 2.
+func loginNewSession(logins *int) func(context.Context, *snowflakeRestful, *http.Client, *url.Values, map[string]string, bodyCreatorType, time.Duration) (*authResponse, error) {
+	return func(context.Context, *snowflakeRestful, *http.Client, *url.Values, map[string]string, bodyCreatorType, time.Duration) (*authResponse, error) {
+		*logins++
+		return &authResponse{
+			Success: true,
+			Data: authResponseMain{
+				Token:          "new",
+				MasterToken:    "new master",
+				SessionID:      2,
+				MasterValidity: 4 * 3600,
+				SessionInfo:    authResponseSessionInfo{RoleName: "PUBLIC"},
+			},
+		}, nil
+	}
+}
+
+func TestRestoreSessionStateAfterLoginAgain(t *testing.T) {
+	// the login waits for the platform detection started with the first connection
+	initPlatformDetection()
+	for _, tc := range []struct {
+		name string
+		// code is the error code of the first query on the old session
+		code    string
+		restore bool
+	}{
+		{"master token expired", sessionExpiredCode, true},
+		{"session gone", strconv.Itoa(ErrSessionGone), true},
+		{"master token expired without restore", sessionExpiredCode, false},
+		{"session gone without restore", strconv.Itoa(ErrSessionGone), false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ta := getSimpleTokenAccessor()
+			ta.SetTokens("old", "old master", 1)
+			var queries []string
+			logins := 0
+			sr := &snowflakeRestful{
+				TokenAccessor:       ta,
+				FuncRenewSession:    renewRestfulSession,
+				FuncPostAuth:        loginNewSession(&logins),
+				FuncPostQuery:       postRestfulQuery,
+				FuncPostQueryHelper: postRestfulQueryHelper,
+				FuncPost: func(ctx context.Context, sr *snowflakeRestful, fullURL *url.URL, headers map[string]string, body []byte, timeout time.Duration, tp currentTimeProvider, cfg *Config) (*http.Response, error) {
+					if fullURL.Path == tokenRequestPath {
+						return renewalRejectedPost(ctx, sr, fullURL, headers, body, timeout, tp, cfg)
+					}
+					var req execRequest
+					assertNilF(t, json.Unmarshal(body, &req))
+					queries = append(queries, req.SQLText)
+					resp := `{"data": {"queryId": "q"}, "success": true}`
+					if headers[headerAuthorizationKey] == fmt.Sprintf(headerSnowflakeToken, "old") {
+						resp = fmt.Sprintf(`{"code": %q, "message": "session", "success": false}`, tc.code)
+					}
+					return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(resp))}, nil
+				},
+			}
+			sc := &snowflakeConn{
+				cfg:                 &Config{Role: "R", RestoreSessionState: tc.restore, Params: map[string]*string{}},
+				rest:                sr,
+				ctx:                 context.Background(),
+				telemetry:           &snowflakeTelemetry{enabled: false},
+				queryContextCache:   (&queryContextCache{}).init(),
+				currentTimeProvider: defaultTimeProvider,
+			}
+			sr.Connection = sc
+			sc.setInitialSessionState(authResponseSessionInfo{RoleName: "R"})
+			sc.trackSessionParameters([]nameValueParameter{{Name: "QUERY_TAG", Value: "tag"}})
+
+			_, err := sc.exec(context.Background(), "select 1", false, false, false, nil)
+			if !tc.restore {
+				assertNotNilF(t, err)
+				assertEqualE(t, logins, 0)
+				assertDeepEqualE(t, queries, []string{"select 1"})
+				return
+			}
+			assertNilF(t, err)
+			assertEqualE(t, logins, 1)
+			_, _, sessionID := ta.GetTokens()
+			assertEqualE(t, sessionID, int64(2))
+			assertTrueE(t, sc.IsValid(), "the new session should be valid")
+			assertDeepEqualE(t, queries, []string{"select 1", "USE ROLE R", "ALTER SESSION SET QUERY_TAG = 'tag'", "select 1"})
+			assertEqualE(t, sc.sessionState.initial.Role, "R", "ResetSession should still restore the state of the first login")
+		})
+	}
+}
+
+func TestRestoreSessionStateDuringMultiStatementQuery(t *testing.T) {
+	initPlatformDetection()
+	ta := getSimpleTokenAccessor()
+	ta.SetTokens("expired", "master", 1)
+	userRequestID := NewUUID()
+	deadline := time.Now().Add(time.Minute)
+	var requests []execRequest
+	logins := 0
+	sr := &snowflakeRestful{
+		TokenAccessor:    ta,
+		FuncRenewSession: renewRestfulSession,
+		FuncPost:         renewalRejectedPost,
+		FuncPostAuth:     loginNewSession(&logins),
+		FuncPostQuery: func(ctx context.Context, _ *snowflakeRestful, _ *url.Values, _ map[string]string, body []byte, _ time.Duration, requestID UUID, _ *Config) (*execResponse, error) {
+			var req execRequest
+			assertNilF(t, json.Unmarshal(body, &req))
+			requests = append(requests, req)
+			assertTrueE(t, requestID != userRequestID, "the replay should not reuse the request ID of the query")
+			assertFalseE(t, isAsyncMode(ctx), "the replay should not run in async mode")
+			replayDeadline, ok := ctx.Deadline()
+			assertTrueE(t, ok && replayDeadline.Equal(deadline), "the replay should keep the deadline of the query")
+			assertEqualE(t, ctx.Value(SFSessionIDKey), int64(2))
+			return &execResponse{Success: true}, nil
+		},
+	}
+	sc := &snowflakeConn{
+		cfg:       &Config{Role: "R", RestoreSessionState: true, Params: map[string]*string{}},
+		rest:      sr,
+		ctx:       context.Background(),
+		telemetry: &snowflakeTelemetry{enabled: false},
+	}
+	sr.Connection = sc
+
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+	ctx, err := WithMultiStatement(ctx, 3)
+	assertNilF(t, err)
+	ctx = WithAsyncMode(WithRequestID(ctx, userRequestID))
+	assertNilF(t, sr.renewExpiredSessionToken(ctx, time.Second, "expired"))
+	assertEqualE(t, logins, 1)
+	assertEqualF(t, len(requests), 1)
+	assertEqualE(t, requests[0].SQLText, "USE ROLE R")
+	_, ok := requests[0].Parameters[string(multiStatementCount)]
+	assertFalseE(t, ok, "a single replayed statement should not be sent with MULTI_STATEMENT_COUNT")
+	assertFalseE(t, requests[0].AsyncExec)
+	assertTrueE(t, requests[0].IsInternal)
+}
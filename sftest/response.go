@@ -0,0 +1,326 @@
+package sftest
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/snowflakedb/gosnowflake"
+)
+
+const (
+	statementTypeIDSelect = int64(0x1000)
+	statementTypeIDDml    = int64(0x3000)
+)
+
+// Column describes a column of a result set. Type is the Snowflake type
+// of the column as sent by the server, e.g. fixed, real, text, boolean,
+// date, time, timestamp_ntz, timestamp_ltz, timestamp_tz, binary or variant.
+type Column struct {
+	Name      string
+	Type      string
+	Precision int64
+	Scale     int64
+	Length    int64
+	Nullable  bool
+}
+
+// Response is a canned response to the statements matching a pattern.
+type Response struct {
+	// Columns are the columns of the result set. They are required for Rows,
+	// and taken from the schema of Arrow if empty.
+	Columns []Column
+	// Rows are the rows of the result set, sent as JSON. A string is sent as
+	// it is, so it must be in the format of the server for the column type.
+	// nil, integers, floats, booleans, []byte and time.Time values are
+	// converted to that format.
+	Rows [][]any
+	// Arrow is sent as a base64 Arrow rowset instead of Rows. The record
+	// must use the Arrow types the server uses for the columns.
+	Arrow arrow.Record
+	// RowsAffected is the number of rows affected by a statement without a
+	// result set.
+	RowsAffected int64
+	// Error makes the statement fail with the number, SQL state and message
+	// of the error.
+	Error *gosnowflake.SnowflakeError
+	// Running is the number of times the statement is reported as running
+	// before its result is returned, when the driver fetches the result or
+	// the status of the query.
+	Running int
+	// Transfer answers a PUT or GET command with the files to transfer.
+	Transfer *FileTransfer
+}
+
+// FileTransfer tells the driver which files a PUT or GET command transfers
+// and where the stage is.
+type FileTransfer struct {
+	// Command is UPLOAD for PUT and DOWNLOAD for GET.
+	Command string
+	// SrcLocations are the local files to upload or the stage files to
+	// download.
+	SrcLocations []string
+	// LocalLocation is the directory GET downloads to.
+	LocalLocation     string
+	Parallel          int64
+	AutoCompress      bool
+	Overwrite         bool
+	SourceCompression string
+	// LocationType and Location are the type of the stage, e.g. S3, and its
+	// location.
+	LocationType string
+	Location     string
+	// EncryptionMaterials are the keys of the uploaded file or of each
+	// downloaded file. They are empty for stages without client side
+	// encryption.
+	EncryptionMaterials []EncryptionMaterial
+}
+
+// EncryptionMaterial is the key to encrypt or decrypt a stage file.
+type EncryptionMaterial struct {
+	QueryStageMasterKey string `json:"queryStageMasterKey"`
+	QueryID             string `json:"queryId"`
+	SMKID               int64  `json:"smkId"`
+}
+
+type rowType struct {
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	Precision int64  `json:"precision"`
+	Scale     int64  `json:"scale"`
+	Length    int64  `json:"length"`
+	Nullable  bool   `json:"nullable"`
+}
+
+type responseData struct {
+	QueryID           string      `json:"queryId"`
+	SQLState          string      `json:"sqlState,omitempty"`
+	StatementTypeID   int64       `json:"statementTypeId,omitempty"`
+	QueryResultFormat string      `json:"queryResultFormat,omitempty"`
+	RowType           []rowType   `json:"rowtype,omitempty"`
+	RowSet            [][]*string `json:"rowset,omitempty"`
+	RowSetBase64      string      `json:"rowsetbase64,omitempty"`
+	Total             int64       `json:"total,omitempty"`
+	Returned          int64       `json:"returned,omitempty"`
+	GetResultURL      string      `json:"getResultUrl,omitempty"`
+
+	Command            string     `json:"command,omitempty"`
+	SrcLocations       []string   `json:"src_locations,omitempty"`
+	LocalLocation      string     `json:"localLocation,omitempty"`
+	Parallel           int64      `json:"parallel,omitempty"`
+	AutoCompress       bool       `json:"autoCompress,omitempty"`
+	Overwrite          bool       `json:"overwrite,omitempty"`
+	SourceCompression  string     `json:"sourceCompression,omitempty"`
+	StageInfo          *stageInfo `json:"stageInfo,omitempty"`
+	EncryptionMaterial any        `json:"encryptionMaterial,omitempty"`
+}
+
+type stageInfo struct {
+	LocationType string `json:"locationType"`
+	Location     string `json:"location"`
+}
+
+type response struct {
+	Data    any    `json:"data"`
+	Code    string `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+	Success bool   `json:"success"`
+}
+
+func errorResponse(queryID string, number int, sqlState, message string) *response {
+	return &response{
+		Data:    &responseData{QueryID: queryID, SQLState: sqlState},
+		Code:    fmt.Sprintf("%06d", number),
+		Message: message,
+	}
+}
+
+// build returns the response of the server to a query once it has finished.
+func (r *Response) build(queryID string) (*response, error) {
+	if r.Error != nil {
+		return errorResponse(queryID, r.Error.Number, r.Error.SQLState, r.Error.Message), nil
+	}
+	data := &responseData{QueryID: queryID}
+	columns := r.Columns
+	switch {
+	case r.Transfer != nil:
+		r.Transfer.fill(data)
+	case r.Arrow != nil:
+		if len(columns) == 0 {
+			columns = arrowColumns(r.Arrow.Schema())
+		}
+		rowSet, err := encodeArrow(r.Arrow)
+		if err != nil {
+			return nil, err
+		}
+		data.StatementTypeID = statementTypeIDSelect
+		data.QueryResultFormat = "arrow"
+		data.RowSetBase64 = rowSet
+		data.Total = r.Arrow.NumRows()
+	case len(columns) > 0:
+		rowSet, err := formatRows(columns, r.Rows)
+		if err != nil {
+			return nil, err
+		}
+		data.StatementTypeID = statementTypeIDSelect
+		data.QueryResultFormat = "json"
+		data.RowSet = rowSet
+		data.Total = int64(len(rowSet))
+	default:
+		columns = []Column{{Name: "number of rows affected", Type: "fixed", Precision: 19}}
+		data.StatementTypeID = statementTypeIDDml
+		data.QueryResultFormat = "json"
+		data.RowSet = [][]*string{{ptr(strconv.FormatInt(r.RowsAffected, 10))}}
+		data.Total = 1
+	}
+	data.Returned = data.Total
+	for _, c := range columns {
+		data.RowType = append(data.RowType, rowType(c))
+	}
+	return &response{Data: data, Success: true}, nil
+}
+
+func (t *FileTransfer) fill(data *responseData) {
+	data.Command = t.Command
+	data.SrcLocations = t.SrcLocations
+	data.LocalLocation = t.LocalLocation
+	data.Parallel = t.Parallel
+	data.AutoCompress = t.AutoCompress
+	data.Overwrite = t.Overwrite
+	data.SourceCompression = t.SourceCompression
+	data.StageInfo = &stageInfo{LocationType: t.LocationType, Location: t.Location}
+	switch {
+	case len(t.EncryptionMaterials) == 0:
+	case t.Command == "UPLOAD":
+		// the server sends a single key for PUT and one per file for GET
+		data.EncryptionMaterial = t.EncryptionMaterials[0]
+	default:
+		data.EncryptionMaterial = t.EncryptionMaterials
+	}
+}
+
+func encodeArrow(rec arrow.Record) (string, error) {
+	var buf bytes.Buffer
+	w := ipc.NewWriter(&buf, ipc.WithSchema(rec.Schema()))
+	if err := w.Write(rec); err != nil {
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// arrowColumns returns the columns of a schema, using the logicalType, scale,
+// precision and charLength field metadata the server sets if present.
+func arrowColumns(schema *arrow.Schema) []Column {
+	columns := make([]Column, 0, schema.NumFields())
+	for _, f := range schema.Fields() {
+		c := Column{Name: f.Name, Nullable: f.Nullable}
+		if logicalType, ok := f.Metadata.GetValue("logicalType"); ok {
+			c.Type = strings.ToLower(logicalType)
+		} else {
+			switch f.Type.ID() {
+			case arrow.INT8, arrow.INT16, arrow.INT32, arrow.INT64, arrow.DECIMAL128:
+				c.Type = "fixed"
+			case arrow.FLOAT32, arrow.FLOAT64:
+				c.Type = "real"
+			case arrow.BOOL:
+				c.Type = "boolean"
+			case arrow.BINARY:
+				c.Type = "binary"
+			case arrow.DATE32:
+				c.Type = "date"
+			default:
+				c.Type = "text"
+			}
+		}
+		for key, dst := range map[string]*int64{"scale": &c.Scale, "precision": &c.Precision, "charLength": &c.Length} {
+			if v, ok := f.Metadata.GetValue(key); ok {
+				*dst, _ = strconv.ParseInt(v, 10, 64)
+			}
+		}
+		columns = append(columns, c)
+	}
+	return columns
+}
+
+func formatRows(columns []Column, rows [][]any) ([][]*string, error) {
+	rowSet := make([][]*string, 0, len(rows))
+	for i, row := range rows {
+		if len(row) != len(columns) {
+			return nil, fmt.Errorf("row %v has %v values, expected %v", i, len(row), len(columns))
+		}
+		values := make([]*string, len(row))
+		for j, v := range row {
+			s, err := formatValue(columns[j].Type, v)
+			if err != nil {
+				return nil, fmt.Errorf("row %v, column %v: %w", i, columns[j].Name, err)
+			}
+			values[j] = s
+		}
+		rowSet = append(rowSet, values)
+	}
+	return rowSet, nil
+}
+
+// formatValue returns v in the format of the JSON result sets of the server.
+func formatValue(typ string, v any) (*string, error) {
+	switch v := v.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return ptr(v), nil
+	case *string:
+		return v, nil
+	case []byte:
+		return ptr(hex.EncodeToString(v)), nil
+	case bool:
+		if v {
+			return ptr("1"), nil
+		}
+		return ptr("0"), nil
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return ptr(fmt.Sprint(v)), nil
+	case float32:
+		return ptr(strconv.FormatFloat(float64(v), 'g', -1, 32)), nil
+	case float64:
+		return ptr(strconv.FormatFloat(v, 'g', -1, 64)), nil
+	case time.Time:
+		return formatTime(typ, v)
+	default:
+		return nil, fmt.Errorf("unsupported value type %T", v)
+	}
+}
+
+func formatTime(typ string, t time.Time) (*string, error) {
+	switch strings.ToLower(typ) {
+	case "date":
+		y, m, d := t.Date()
+		return ptr(strconv.FormatInt(time.Date(y, m, d, 0, 0, 0, 0, time.UTC).Unix()/86400, 10)), nil
+	case "time":
+		y, m, d := t.Date()
+		since := t.Sub(time.Date(y, m, d, 0, 0, 0, 0, t.Location()))
+		return ptr(fmt.Sprintf("%d.%09d", since/time.Second, since%time.Second)), nil
+	case "timestamp_ntz":
+		u := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+		return ptr(fmt.Sprintf("%d.%09d", u.Unix(), u.Nanosecond())), nil
+	case "timestamp_ltz":
+		return ptr(fmt.Sprintf("%d.%09d", t.Unix(), t.Nanosecond())), nil
+	case "timestamp_tz":
+		_, offset := t.Zone()
+		return ptr(fmt.Sprintf("%d.%09d %d", t.Unix(), t.Nanosecond(), offset/60+1440)), nil
+	default:
+		return nil, fmt.Errorf("time.Time is not supported for %v columns", typ)
+	}
+}
+
+func ptr(s string) *string {
+	return &s
+}
@@ -0,0 +1,408 @@
+// Package sftest provides an in-process stand-in for Snowflake to unit test
+// code using the driver through database/sql.
+//
+// A Server speaks the login, query, query monitoring and session endpoints
+// of Snowflake. Tests register canned responses for the statements their
+// code runs, matched with regular expressions:
+//
+//	srv := sftest.NewServer()
+//	defer srv.Close()
+//	srv.Handle(`^SELECT id, name FROM users WHERE id = \?$`, sftest.Response{
+//		Columns: []sftest.Column{{Name: "ID", Type: "fixed"}, {Name: "NAME", Type: "text"}},
+//		Rows:    [][]any{{1, "alice"}},
+//	}).ExpectBindings(1)
+//	db := sql.OpenDB(gosnowflake.NewConnector(gosnowflake.SnowflakeDriver{}, *srv.Config()))
+//
+// HandleFunc computes the response from the statement instead, e.g. to
+// answer PUT and GET with a Response.Transfer.
+//
+// Statements are matched against the handlers in the order they were
+// registered. A statement without a handler fails with error 002003.
+// Every statement received is recorded with its bindings, see
+// Server.Queries and Handler.Queries.
+package sftest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/snowflakedb/gosnowflake"
+)
+
+const (
+	queryInProgressCode      = "333333"
+	queryInProgressAsyncCode = "333334"
+	errNoHandler             = 2003
+	errBindingsMismatch      = 2049
+	sqlStateNoHandler        = "02000"
+	sqlStateBindingsMismatch = "22000"
+	resultPathFmt            = "/queries/%v/result"
+	monitoringPathPrefix     = "/monitoring/queries/"
+)
+
+// Binding is a parameter bound to a statement, as sent by the driver.
+// Value is a string, nil, or a slice of them for array bindings.
+type Binding struct {
+	Type  string `json:"type"`
+	Value any    `json:"value"`
+}
+
+// Query is a statement received by the server.
+type Query struct {
+	// ID is the query ID returned to the driver.
+	ID string
+	// SQL is the text of the statement.
+	SQL string
+	// Async is true for statements run with gosnowflake.WithAsyncMode.
+	Async bool
+	// Bindings are the bound parameters by position, starting at "1",
+	// or by name.
+	Bindings map[string]Binding
+}
+
+// Values returns the values of the positional bindings in order.
+func (q Query) Values() []any {
+	positions := make([]int, 0, len(q.Bindings))
+	for key := range q.Bindings {
+		if i, err := strconv.Atoi(key); err == nil {
+			positions = append(positions, i)
+		}
+	}
+	sort.Ints(positions)
+	values := make([]any, 0, len(positions))
+	for _, i := range positions {
+		values = append(values, q.Bindings[strconv.Itoa(i)].Value)
+	}
+	return values
+}
+
+// Handler answers the statements matching its pattern with a Response.
+type Handler struct {
+	pattern  *regexp.Regexp
+	response Response
+	fn       func(Query) Response
+
+	mu       sync.Mutex
+	bindings []any
+	expect   bool
+	queries  []Query
+}
+
+// ExpectBindings makes the statements fail with error 002049 unless they
+// are bound to values, in order. Values are compared in the text format
+// the driver sends them in, e.g. 1 matches "1" and nil matches a NULL.
+func (h *Handler) ExpectBindings(values ...any) *Handler {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.bindings = values
+	h.expect = true
+	return h
+}
+
+// Queries returns the statements the handler received.
+func (h *Handler) Queries() []Query {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]Query(nil), h.queries...)
+}
+
+func (h *Handler) checkBindings(q Query) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.expect {
+		return nil
+	}
+	actual := q.Values()
+	if len(actual) == len(h.bindings) {
+		matches := true
+		for i, v := range h.bindings {
+			matches = matches && bindingMatches(v, actual[i])
+		}
+		if matches {
+			return nil
+		}
+	}
+	return fmt.Errorf("sftest: %v is bound to %v, expected %v", q.SQL, actual, h.bindings)
+}
+
+func bindingMatches(expected, actual any) bool {
+	if expected == nil || actual == nil {
+		return expected == nil && actual == nil
+	}
+	if s, err := formatValue("", expected); err == nil && s != nil {
+		expected = *s
+	}
+	if reflect.DeepEqual(expected, actual) {
+		return true
+	}
+	return fmt.Sprint(expected) == fmt.Sprint(actual)
+}
+
+// Server is an in-process Snowflake stand-in. Create it with NewServer.
+type Server struct {
+	srv *httptest.Server
+
+	mu       sync.Mutex
+	handlers []*Handler
+	queries  []Query
+	results  map[string]*queryResult
+}
+
+// queryResult is the response to a statement, returned once the driver
+// polled it polls times.
+type queryResult struct {
+	sqlText  string
+	response *Response
+	polls    int
+}
+
+// NewServer starts a Server. Close it when done.
+func NewServer() *Server {
+	s := &Server{results: make(map[string]*queryResult)}
+	s.srv = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// Close stops the server.
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// URL returns the base URL of the server.
+func (s *Server) URL() string {
+	return s.srv.URL
+}
+
+// Config returns the configuration to connect to the server.
+func (s *Server) Config() *gosnowflake.Config {
+	host, port, _ := net.SplitHostPort(s.srv.Listener.Addr().String())
+	portNumber, _ := strconv.Atoi(port)
+	return &gosnowflake.Config{
+		Account:  "sftest",
+		User:     "sftest",
+		Password: "sftest",
+		Host:     host,
+		Port:     portNumber,
+		Protocol: "http",
+	}
+}
+
+// DSN returns the data source name to connect to the server.
+func (s *Server) DSN() (string, error) {
+	return gosnowflake.DSN(s.Config())
+}
+
+// Handle registers the response to the statements matching pattern, a
+// regular expression matched case-insensitively against the SQL text.
+// It panics if pattern is not a valid regular expression.
+func (s *Server) Handle(pattern string, response Response) *Handler {
+	h := &Handler{
+		pattern:  regexp.MustCompile("(?is)" + pattern),
+		response: response,
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers = append(s.handlers, h)
+	return h
+}
+
+// HandleFunc registers a function returning the response to the statements
+// matching pattern, like Handle. It is called once per statement.
+func (s *Server) HandleFunc(pattern string, fn func(Query) Response) *Handler {
+	h := s.Handle(pattern, Response{})
+	h.fn = fn
+	return h
+}
+
+// Queries returns the statements the server received, in order.
+func (s *Server) Queries() []Query {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Query(nil), s.queries...)
+}
+
+type queryRequest struct {
+	SQLText   string             `json:"sqlText"`
+	AsyncExec bool               `json:"asyncExec"`
+	Bindings  map[string]Binding `json:"bindings"`
+}
+
+type queryStatus struct {
+	ID           string `json:"id"`
+	Status       string `json:"status"`
+	SQLText      string `json:"sqlText"`
+	ErrorCode    string `json:"errorCode,omitempty"`
+	ErrorMessage string `json:"errorMessage,omitempty"`
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	var resp any
+	switch path := r.URL.Path; {
+	case path == "/session/v1/login-request":
+		resp = &response{
+			Data: map[string]any{
+				"token":             "sftest session token",
+				"masterToken":       "sftest master token",
+				"validityInSeconds": 3600,
+				"sessionId":         1,
+				"parameters":        []any{},
+			},
+			Success: true,
+		}
+	case path == "/session/token-request":
+		resp = &response{
+			Data: map[string]any{
+				"sessionToken":        "sftest session token",
+				"validityInSecondsST": 3600,
+				"masterToken":         "sftest master token",
+				"validityInSecondsMT": 14400,
+				"sessionId":           1,
+			},
+			Success: true,
+		}
+	case path == "/queries/v1/query-request":
+		var req queryRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		resp = s.query(req)
+	case strings.HasPrefix(path, "/queries/") && strings.HasSuffix(path, "/result"):
+		resp = s.result(strings.TrimSuffix(strings.TrimPrefix(path, "/queries/"), "/result"))
+	case strings.HasPrefix(path, monitoringPathPrefix):
+		resp = s.status(strings.TrimPrefix(path, monitoringPathPrefix))
+	default:
+		// session, heartbeat, abort and telemetry requests
+		resp = &response{Success: true}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (s *Server) query(req queryRequest) *response {
+	q := Query{
+		ID:       gosnowflake.NewUUID().String(),
+		SQL:      req.SQLText,
+		Async:    req.AsyncExec,
+		Bindings: req.Bindings,
+	}
+	s.mu.Lock()
+	s.queries = append(s.queries, q)
+	var h *Handler
+	for _, candidate := range s.handlers {
+		if candidate.pattern.MatchString(q.SQL) {
+			h = candidate
+			break
+		}
+	}
+	s.mu.Unlock()
+	if h == nil {
+		return errorResponse(q.ID, errNoHandler, sqlStateNoHandler, fmt.Sprintf("sftest: no handler for %v", q.SQL))
+	}
+
+	h.mu.Lock()
+	h.queries = append(h.queries, q)
+	h.mu.Unlock()
+	if err := h.checkBindings(q); err != nil {
+		return errorResponse(q.ID, errBindingsMismatch, sqlStateBindingsMismatch, err.Error())
+	}
+	r := &h.response
+	if h.fn != nil {
+		resp := h.fn(q)
+		r = &resp
+	}
+	s.mu.Lock()
+	s.results[q.ID] = &queryResult{q.SQL, r, r.Running}
+	s.mu.Unlock()
+	if r.Running > 0 || q.Async {
+		code := queryInProgressCode
+		if q.Async {
+			code = queryInProgressAsyncCode
+		}
+		return &response{
+			Data: &responseData{
+				QueryID:      q.ID,
+				GetResultURL: fmt.Sprintf(resultPathFmt, q.ID),
+			},
+			Code:    code,
+			Message: "query execution in progress",
+			Success: true,
+		}
+	}
+	return s.build(r, q.ID)
+}
+
+// poll returns the result of a query and its response, or a nil response
+// while it is still running.
+func (s *Server) poll(queryID string) (*queryResult, *Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	qr, ok := s.results[queryID]
+	if !ok {
+		return nil, nil
+	}
+	if qr.polls > 0 {
+		qr.polls--
+		return qr, nil
+	}
+	return qr, qr.response
+}
+
+func (s *Server) result(queryID string) *response {
+	qr, r := s.poll(queryID)
+	switch {
+	case qr == nil:
+		return errorResponse(queryID, errNoHandler, sqlStateNoHandler, fmt.Sprintf("sftest: unknown query %v", queryID))
+	case r == nil:
+		return &response{
+			Data: &responseData{
+				QueryID:      queryID,
+				GetResultURL: fmt.Sprintf(resultPathFmt, queryID),
+			},
+			Code:    queryInProgressAsyncCode,
+			Message: "query execution in progress",
+			Success: true,
+		}
+	default:
+		return s.build(r, queryID)
+	}
+}
+
+func (s *Server) status(queryID string) *response {
+	qr, r := s.poll(queryID)
+	if qr == nil {
+		return &response{Message: fmt.Sprintf("sftest: unknown query %v", queryID)}
+	}
+	st := queryStatus{ID: queryID, Status: "SUCCESS", SQLText: qr.sqlText}
+	switch {
+	case r == nil:
+		st.Status = "RUNNING"
+	case r.Error != nil:
+		st.Status = "FAILED_WITH_ERROR"
+		st.ErrorCode = strconv.Itoa(r.Error.Number)
+		st.ErrorMessage = r.Error.Message
+	}
+	return &response{
+		Data:    map[string]any{"queries": []queryStatus{st}},
+		Success: true,
+	}
+}
+
+func (s *Server) build(r *Response, queryID string) *response {
+	resp, err := r.build(queryID)
+	if err != nil {
+		return errorResponse(queryID, errNoHandler, sqlStateNoHandler, fmt.Sprintf("sftest: invalid response: %v", err))
+	}
+	return resp
+}
@@ -0,0 +1,249 @@
+package sftest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/snowflakedb/gosnowflake"
+)
+
+func openTestDB(t *testing.T) (*Server, *sql.DB) {
+	srv := NewServer()
+	t.Cleanup(srv.Close)
+	db := sql.OpenDB(gosnowflake.NewConnector(gosnowflake.SnowflakeDriver{}, *srv.Config()))
+	t.Cleanup(func() {
+		if err := db.Close(); err != nil {
+			t.Error(err)
+		}
+	})
+	return srv, db
+}
+
+func snowflakeErrorNumber(t *testing.T, err error) int {
+	var se *gosnowflake.SnowflakeError
+	if !errors.As(err, &se) {
+		t.Fatalf("expected a SnowflakeError, got %v", err)
+	}
+	return se.Number
+}
+
+func TestQueryRows(t *testing.T) {
+	srv, db := openTestDB(t)
+	h := srv.Handle(`^SELECT id, name FROM users WHERE id = \?$`, Response{
+		Columns: []Column{{Name: "ID", Type: "fixed", Precision: 38}, {Name: "NAME", Type: "text", Nullable: true}},
+		Rows:    [][]any{{1, "alice"}, {2, nil}},
+	}).ExpectBindings(1)
+
+	rows, err := db.Query("SELECT id, name FROM users WHERE id = ?", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var names []sql.NullString
+	for rows.Next() {
+		var id int64
+		var name sql.NullString
+		if err = rows.Scan(&id, &name); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+	}
+	if err = rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(names, []sql.NullString{{String: "alice", Valid: true}, {}}) {
+		t.Errorf("unexpected names: %v", names)
+	}
+	queries := h.Queries()
+	if len(queries) != 1 || !reflect.DeepEqual(queries[0].Values(), []any{"1"}) {
+		t.Errorf("unexpected queries: %v", queries)
+	}
+}
+
+func TestExpectBindingsMismatch(t *testing.T) {
+	srv, db := openTestDB(t)
+	srv.Handle(`^INSERT INTO users`, Response{RowsAffected: 1}).ExpectBindings(1, "alice")
+
+	_, err := db.Exec("INSERT INTO users VALUES (?, ?)", 2, "bob")
+	if n := snowflakeErrorNumber(t, err); n != errBindingsMismatch {
+		t.Errorf("unexpected error: %v", err)
+	}
+	res, err := db.Exec("INSERT INTO users VALUES (?, ?)", 1, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := res.RowsAffected(); err != nil || n != 1 {
+		t.Errorf("unexpected rows affected: %v, %v", n, err)
+	}
+	if got := len(srv.Queries()); got != 2 {
+		t.Errorf("expected 2 queries, got %v", got)
+	}
+}
+
+func TestErrorResponse(t *testing.T) {
+	srv, db := openTestDB(t)
+	srv.Handle(`DROP TABLE`, Response{Error: &gosnowflake.SnowflakeError{
+		Number:   2003,
+		SQLState: "02000",
+		Message:  "Table 'USERS' does not exist or not authorized.",
+	}})
+
+	_, err := db.Exec("DROP TABLE users")
+	var se *gosnowflake.SnowflakeError
+	if !errors.As(err, &se) {
+		t.Fatalf("expected a SnowflakeError, got %v", err)
+	}
+	if se.Number != 2003 || se.SQLState != "02000" || se.QueryID == "" {
+		t.Errorf("unexpected error: %#v", se)
+	}
+
+	_, err = db.Exec("TRUNCATE TABLE users")
+	if n := snowflakeErrorNumber(t, err); n != errNoHandler {
+		t.Errorf("unexpected error for a statement without a handler: %v", err)
+	}
+}
+
+func TestHandleFunc(t *testing.T) {
+	srv, db := openTestDB(t)
+	h := srv.HandleFunc(`^SELECT \? \+ 1$`, func(q Query) Response {
+		n, err := strconv.Atoi(q.Values()[0].(string))
+		if err != nil {
+			return Response{Error: &gosnowflake.SnowflakeError{Number: 1003, SQLState: "42000", Message: err.Error()}}
+		}
+		return Response{Columns: []Column{{Name: "N", Type: "fixed"}}, Rows: [][]any{{n + 1}}}
+	})
+
+	for _, n := range []int{1, 41} {
+		var got int
+		if err := db.QueryRow("SELECT ? + 1", n).Scan(&got); err != nil {
+			t.Fatal(err)
+		}
+		if got != n+1 {
+			t.Errorf("expected %v, got %v", n+1, got)
+		}
+	}
+	if _, err := db.Exec("SELECT ? + 1", "x"); snowflakeErrorNumber(t, err) != 1003 {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if got := len(h.Queries()); got != 3 {
+		t.Errorf("expected 3 queries, got %v", got)
+	}
+}
+
+func TestArrowRows(t *testing.T) {
+	srv, db := openTestDB(t)
+	pool := memory.NewGoAllocator()
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "ID", Type: arrow.PrimitiveTypes.Int64},
+		{Name: "NAME", Type: arrow.BinaryTypes.String},
+	}, nil)
+	b := array.NewRecordBuilder(pool, schema)
+	defer b.Release()
+	b.Field(0).(*array.Int64Builder).AppendValues([]int64{1, 2}, nil)
+	b.Field(1).(*array.StringBuilder).AppendValues([]string{"alice", "bob"}, nil)
+	rec := b.NewRecord()
+	defer rec.Release()
+	srv.Handle(`^SELECT id, name FROM users$`, Response{Arrow: rec})
+
+	rows, err := db.Query("SELECT id, name FROM users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var names []string
+	for rows.Next() {
+		var id int64
+		var name string
+		if err = rows.Scan(&id, &name); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+	}
+	if err = rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(names, []string{"alice", "bob"}) {
+		t.Errorf("unexpected names: %v", names)
+	}
+}
+
+func TestTimeValues(t *testing.T) {
+	srv, db := openTestDB(t)
+	ts := time.Date(2024, 2, 29, 13, 14, 15, 123456789, time.UTC)
+	srv.Handle(`^SELECT d, ts FROM events$`, Response{
+		Columns: []Column{{Name: "D", Type: "date"}, {Name: "TS", Type: "timestamp_ntz", Scale: 9}},
+		Rows:    [][]any{{ts, ts}},
+	})
+
+	var d, got time.Time
+	if err := db.QueryRow("SELECT d, ts FROM events").Scan(&d, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !d.Equal(time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)) || !got.Equal(ts) {
+		t.Errorf("unexpected values: %v, %v", d, got)
+	}
+}
+
+func TestRunningQuery(t *testing.T) {
+	srv, db := openTestDB(t)
+	srv.Handle(`^SELECT 1$`, Response{
+		Columns: []Column{{Name: "1", Type: "fixed"}},
+		Rows:    [][]any{{1}},
+		Running: 2,
+	})
+
+	var v int
+	if err := db.QueryRow("SELECT 1").Scan(&v); err != nil {
+		t.Fatal(err)
+	}
+	if v != 1 {
+		t.Errorf("unexpected value: %v", v)
+	}
+
+	ctx := gosnowflake.WithAsyncMode(context.Background())
+	if err := db.QueryRowContext(ctx, "SELECT 1").Scan(&v); err != nil {
+		t.Fatal(err)
+	}
+	if v != 1 {
+		t.Errorf("unexpected value: %v", v)
+	}
+	if queries := srv.Queries(); len(queries) != 2 || queries[0].Async || !queries[1].Async {
+		t.Errorf("unexpected queries: %v", queries)
+	}
+}
+
+func TestQueryStatus(t *testing.T) {
+	srv, db := openTestDB(t)
+	srv.Handle(`^DELETE FROM users$`, Response{RowsAffected: 3})
+	if _, err := db.Exec("DELETE FROM users"); err != nil {
+		t.Fatal(err)
+	}
+	queryID := srv.Queries()[0].ID
+
+	conn, err := db.Conn(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	err = conn.Raw(func(x any) error {
+		status, err := x.(gosnowflake.SnowflakeConnection).GetQueryStatus(context.Background(), queryID)
+		if err != nil {
+			return err
+		}
+		if status.SQLText != "DELETE FROM users" {
+			t.Errorf("unexpected status: %#v", status)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -2,7 +2,6 @@ package gosnowflake
 
 import (
 	"fmt"
-	"math"
 	"os"
 	"path"
 	"path/filepath"
@@ -55,6 +54,12 @@ func (rsu *remoteStorageUtil) getNativeCloudType(cli string, cfg *Config) cloudU
 			cfg,
 			rsu.telemetry,
 		}
+	} else if cloudType(cli) == fakeStageClient {
+		logger.Info("Using fake stage client for remote storage")
+		return &snowflakeFakeStageClient{
+			cfg,
+			rsu.telemetry,
+		}
 	}
 	return nil
 }
@@ -72,16 +77,19 @@ func (rsu *remoteStorageUtil) uploadOneFile(meta *fileMetadata) error {
 	var timer time.Time
 	var elapsedTime string
 	maxRetry := defaultMaxRetry
+	retryPolicy := meta.sfa.retryPolicy()
+	startTime := time.Now()
 	logger.Debugf(
 		"Started Uploading. File: %v, location: %v", meta.realSrcFileName, meta.stageInfo.Location)
 	for retry := 0; retry < maxRetry; retry++ {
 		timer = time.Now()
+		var uploadErr error
 		if !meta.overwrite {
 			header, err := utilClass.getFileHeader(meta, meta.dstFileName)
 			if meta.resStatus == notFoundFile {
-				err := utilClass.uploadFile(meta.realSrcFileName, meta, maxConcurrency, meta.options.MultiPartThreshold)
-				if err != nil {
-					logger.Warnf("Error uploading %v. err: %v", meta.realSrcFileName, err)
+				uploadErr = utilClass.uploadFile(meta.realSrcFileName, meta, maxConcurrency, meta.options.MultiPartThreshold)
+				if uploadErr != nil {
+					logger.Warnf("Error uploading %v. err: %v", meta.realSrcFileName, uploadErr)
 				}
 			} else if err != nil {
 				return err
@@ -93,32 +101,52 @@ func (rsu *remoteStorageUtil) uploadOneFile(meta *fileMetadata) error {
 			}
 		}
 		if meta.overwrite || meta.resStatus == notFoundFile {
-			err := utilClass.uploadFile(meta.realSrcFileName, meta, maxConcurrency, meta.options.MultiPartThreshold)
-			if err != nil {
-				logger.Warnf("Error uploading %v. err: %v", meta.realSrcFileName, err)
+			uploadErr = utilClass.uploadFile(meta.realSrcFileName, meta, maxConcurrency, meta.options.MultiPartThreshold)
+			if uploadErr != nil {
+				logger.Warnf("Error uploading %v. err: %v", meta.realSrcFileName, uploadErr)
 			}
 		}
 		elapsedTime = time.Since(timer).String()
+		if meta.uploadStream != nil && meta.resStatus != uploaded {
+			// the source was already consumed by the failed attempt
+			meta.resStatus = errStatus
+			return fmt.Errorf("streamed upload of %v cannot be retried: %w", meta.name, uploadErr)
+		}
+		if err := meta.transferContext().Err(); err != nil {
+			return err
+		}
 		switch meta.resStatus {
 		case uploaded, renewToken, renewPresignedURL:
 			logger.Debugf("Uploading file: %v finished in %v ms with the status: %v.", meta.realSrcFileName, elapsedTime, meta.resStatus)
 			return nil
 		case needRetry:
+			attempt := RetryAttempt{Kind: RetryRequestKindCloudStorage, Attempt: retry + 1, Err: meta.lastError, Elapsed: time.Since(startTime)}
+			if !retryPolicy.ShouldRetry(attempt) {
+				logger.Debugf("Retry policy rejected retrying upload of file: %v. Current retry: %v.", meta.realSrcFileName, retry)
+				return meta.lastError
+			}
+			meta.reportRetried(meta.lastError)
 			if !meta.noSleepingTime {
-				sleepingTime := intMin(int(math.Exp2(float64(retry))), 16)
+				sleepingTime := retryPolicy.WaitTime(attempt)
 				logger.Debugf("Need to retry for uploading file: %v. Current retry: %v, Sleeping time: %v.", meta.realSrcFileName, retry, sleepingTime)
-				time.Sleep(time.Second * time.Duration(sleepingTime))
+				time.Sleep(sleepingTime)
 			} else {
 				logger.Debugf("Need to retry for uploading file:  %v. Current retry: %v without the sleeping time.", meta.realSrcFileName, retry)
 			}
 		case needRetryWithLowerConcurrency:
+			attempt := RetryAttempt{Kind: RetryRequestKindCloudStorage, Attempt: retry + 1, Err: meta.lastError, Elapsed: time.Since(startTime)}
+			if !retryPolicy.ShouldRetry(attempt) {
+				logger.Debugf("Retry policy rejected retrying upload of file: %v. Current retry: %v.", meta.realSrcFileName, retry)
+				return meta.lastError
+			}
+			meta.reportRetried(meta.lastError)
 			maxConcurrency = int(meta.parallel) - (retry * int(meta.parallel) / maxRetry)
 			maxConcurrency = intMax(defaultConcurrency, maxConcurrency)
 			meta.lastMaxConcurrency = maxConcurrency
 			if !meta.noSleepingTime {
-				sleepingTime := intMin(int(math.Exp2(float64(retry))), 16)
+				sleepingTime := retryPolicy.WaitTime(attempt)
 				logger.Debugf("Need to retry with lower concurrency for uploading file: %v. Current retry: %v, Sleeping time: %v.", meta.realSrcFileName, retry, sleepingTime)
-				time.Sleep(time.Second * time.Duration(sleepingTime))
+				time.Sleep(sleepingTime)
 			} else {
 				logger.Debugf("Need to retry with lower concurrency for uploading file: %v. Current retry: %v without Sleeping time.", meta.realSrcFileName, retry)
 
@@ -212,6 +240,9 @@ func (rsu *remoteStorageUtil) downloadOneFile(meta *fileMetadata) error {
 
 	timer := time.Now()
 	for retry := 0; retry < maxRetry; retry++ {
+		if err = meta.transferContext().Err(); err != nil {
+			return err
+		}
 		tempDownloadFile := fullDstFileName + ".tmp"
 		defer func() {
 			// Clean up temp file if it still exists
@@ -281,6 +312,10 @@ func (rsu *remoteStorageUtil) downloadOneFile(meta *fileMetadata) error {
 			return nil
 		}
 		lastErr = meta.lastError
+		if !meta.sfa.retryPolicy().ShouldRetry(RetryAttempt{Kind: RetryRequestKindCloudStorage, Attempt: retry + 1, Err: lastErr, Elapsed: time.Since(timer)}) {
+			break
+		}
+		meta.reportRetried(lastErr)
 	}
 	if lastErr != nil {
 		logger.Errorf(`Failed to downloading file: %v, with error: %v`, meta.srcFileName, lastErr)
@@ -0,0 +1,92 @@
+package gosnowflake
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"io"
+)
+
+// defaultStreamUploadPartSize is the part size of streaming uploads when
+// MultiPartThreshold is not set.
+const defaultStreamUploadPartSize int64 = 8 * 1024 * 1024
+
+// streamDetectionSize is how much of a streamed source is peeked at to
+// detect its compression.
+const streamDetectionSize = 3072
+
+// streamUpload compresses, digests and encrypts a WithFileStream source while
+// the storage client reads it, so only the parts in flight are held in memory
+// besides the source.
+type streamUpload struct {
+	reader *io.PipeReader
+	done   chan error
+}
+
+// newStreamUpload starts the pipeline for meta.fileStream, or meta.srcStream
+// if the source was read into memory. The encryption
+// metadata is set on meta before it returns; sha256Digest and uploadSize are
+// set once the source is exhausted.
+func newStreamUpload(meta *fileMetadata, ct cloudType) (*streamUpload, error) {
+	src := meta.fileStream
+	if meta.srcStream != nil {
+		// read without consuming it, so that the upload can be started over
+		src = bytes.NewReader(meta.srcStream.Bytes())
+	}
+	pr, pw := io.Pipe()
+	var out io.Writer = pw
+	var enc *cbcEncryptWriter
+	if ct != local && meta.encryptionMaterial != nil {
+		var err error
+		enc, meta.encryptMeta, err = newCBCEncryptWriter(meta.encryptionMaterial, pw)
+		if err != nil {
+			return nil, err
+		}
+		out = enc
+	}
+	su := &streamUpload{
+		reader: pr,
+		done:   make(chan error, 1),
+	}
+	go func() {
+		digest := sha256.New()
+		cw := &countingWriter{w: io.MultiWriter(out, digest)}
+		err := su.copy(cw, meta, src)
+		if err == nil && enc != nil {
+			err = enc.Close()
+		}
+		if err == nil {
+			meta.sha256Digest = base64.StdEncoding.EncodeToString(digest.Sum(nil))
+			meta.uploadSize = cw.n
+		}
+		su.done <- err
+		pw.CloseWithError(err)
+	}()
+	return su, nil
+}
+
+func (su *streamUpload) copy(dst io.Writer, meta *fileMetadata, src io.Reader) error {
+	if meta.requireCompress {
+		return compressTo(dst, src, meta.dstCompressionType)
+	}
+	_, err := io.Copy(dst, src)
+	return err
+}
+
+// wait stops the pipeline if the upload gave up early and returns the error
+// of reading, compressing or encrypting the source, if any.
+func (su *streamUpload) wait() error {
+	su.reader.CloseWithError(io.ErrClosedPipe)
+	return <-su.done
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
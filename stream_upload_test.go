@@ -0,0 +1,285 @@
+package gosnowflake
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+)
+
+func testStreamUploadEncryption() *snowflakeFileEncryption {
+	return &snowflakeFileEncryption{
+		QueryStageMasterKey: "YWJjZGVmMTIzNDU2Nzg5MA==",
+		QueryID:             "unused",
+		SMKID:               9223372036854775807,
+	}
+}
+
+func TestCBCEncryptWriter(t *testing.T) {
+	sfe := testStreamUploadEncryption()
+	for _, size := range []int{0, 5, 16, 100, 4096} {
+		data := bytes.Repeat([]byte("0123456789abcdef"), 256)[:size]
+		var encrypted bytes.Buffer
+		w, encryptMeta, err := newCBCEncryptWriter(sfe, &encrypted)
+		assertNilF(t, err)
+		for i := 0; i < len(data); i += 7 {
+			_, err = w.Write(data[i:intMin(i+7, len(data))])
+			assertNilF(t, err)
+		}
+		assertNilF(t, w.Close())
+		assertEqualE(t, encrypted.Len(), size+16-size%16)
+
+		var decrypted bytes.Buffer
+		n, err := decryptStreamCBC(encryptMeta, sfe, 0, &encrypted, &decrypted)
+		assertNilF(t, err)
+		assertEqualE(t, string(decrypted.Bytes()[:n]), string(data))
+	}
+}
+
+func TestStreamUploadToS3(t *testing.T) {
+	data := strings.Repeat("streamed,row\n", 100000)
+	var body bytes.Buffer
+	var s3Meta map[string]string
+	var copyInput *s3.CopyObjectInput
+	info := execResponseStageInfo{
+		Location:     "sfc-teststage/rwyitestacco/users/1234/",
+		LocationType: "S3",
+	}
+	s3Cli, err := new(snowflakeS3Client).createClient(&info, false, &snowflakeTelemetry{})
+	assertNilF(t, err)
+	meta := &fileMetadata{
+		name:               "data.csv",
+		stageLocationType:  s3Client,
+		stageInfo:          &info,
+		client:             s3Cli,
+		parallel:           4,
+		dstFileName:        "data.csv.gz",
+		fileStream:         strings.NewReader(data),
+		streamUpload:       true,
+		requireCompress:    true,
+		dstCompressionType: compressionTypes["GZIP"],
+		encryptionMaterial: testStreamUploadEncryption(),
+		overwrite:          true,
+		options:            &SnowflakeFileTransferOptions{},
+		mockUploader: mockUploadObjectAPI(func(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*manager.Uploader)) (*manager.UploadOutput, error) {
+			s3Meta = params.Metadata
+			_, err := io.Copy(&body, params.Body)
+			return &manager.UploadOutput{}, err
+		}),
+		mockCopier: mockCopyObjectAPI(func(ctx context.Context, params *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error) {
+			copyInput = params
+			return &s3.CopyObjectOutput{}, nil
+		}),
+		sfa: &snowflakeFileTransferAgent{
+			sc: &snowflakeConn{
+				cfg: &Config{},
+			},
+		},
+	}
+
+	su, err := newStreamUpload(meta, s3Client)
+	assertNilF(t, err)
+	meta.uploadStream = su.reader
+	assertNilF(t, new(remoteStorageUtil).uploadOneFile(meta))
+	assertNilF(t, su.wait())
+	assertEqualE(t, meta.resStatus, uploaded)
+	assertEqualE(t, s3Meta[amzIv], meta.encryptMeta.iv)
+	_, ok := s3Meta[sfcDigest]
+	assertFalseE(t, ok, "a streamed upload should not send a digest up front")
+	assertNotNilF(t, copyInput, "the digest should be stored after the upload")
+	assertEqualE(t, *copyInput.Key, "rwyitestacco/users/1234/data.csv.gz")
+	assertEqualE(t, *copyInput.CopySource, "sfc-teststage/rwyitestacco/users/1234/data.csv.gz")
+	assertEqualE(t, copyInput.MetadataDirective, types.MetadataDirectiveReplace)
+	assertEqualE(t, copyInput.Metadata[sfcDigest], meta.sha256Digest)
+	assertEqualE(t, copyInput.Metadata[amzIv], meta.encryptMeta.iv)
+
+	var compressed bytes.Buffer
+	n, err := decryptStreamCBC(meta.encryptMeta, meta.encryptionMaterial, 0, &body, &compressed)
+	assertNilF(t, err)
+	compressed.Truncate(n)
+	assertEqualE(t, meta.uploadSize, int64(n))
+	digest := sha256.Sum256(compressed.Bytes())
+	assertEqualE(t, meta.sha256Digest, base64.StdEncoding.EncodeToString(digest[:]))
+
+	r, err := gzip.NewReader(&compressed)
+	assertNilF(t, err)
+	uncompressed, err := io.ReadAll(r)
+	assertNilF(t, err)
+	assertEqualE(t, string(uncompressed), data)
+}
+
+func TestStreamUploadToGCS(t *testing.T) {
+	info := execResponseStageInfo{
+		Location:     "gcs-blob/storage/users/456/",
+		LocationType: "GCS",
+		Creds:        execResponseCredentials{GcsAccessToken: "token"},
+	}
+	gcsCli, err := new(snowflakeGcsClient).createClient(&info, false, &snowflakeTelemetry{})
+	assertNilF(t, err)
+	var patchURL string
+	var patched map[string]map[string]string
+	meta := &fileMetadata{
+		name:              "data.csv",
+		stageLocationType: gcsClient,
+		stageInfo:         &info,
+		client:            gcsCli,
+		parallel:          1,
+		dstFileName:       "data.csv",
+		fileStream:        strings.NewReader(strings.Repeat("x", 1024)),
+		streamUpload:      true,
+		overwrite:         true,
+		options:           &SnowflakeFileTransferOptions{},
+		mockGcsClient: &clientMock{
+			DoFunc: func(req *http.Request) (*http.Response, error) {
+				switch req.Method {
+				case "PUT":
+					_, ok := req.Header[http.CanonicalHeaderKey(gcsMetadataSfcDigest)]
+					assertFalseE(t, ok, "a streamed upload should not send a digest up front")
+					_, err := io.Copy(io.Discard, req.Body)
+					assertNilE(t, err)
+				case "PATCH":
+					patchURL = req.URL.String()
+					assertEqualE(t, req.Header.Get("Authorization"), "Bearer token")
+					assertNilE(t, json.NewDecoder(req.Body).Decode(&patched))
+				}
+				return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+			},
+		},
+		sfa: &snowflakeFileTransferAgent{
+			sc: &snowflakeConn{
+				cfg: &Config{},
+			},
+		},
+	}
+
+	su, err := newStreamUpload(meta, gcsClient)
+	assertNilF(t, err)
+	meta.uploadStream = su.reader
+	assertNilF(t, new(remoteStorageUtil).uploadOneFile(meta))
+	assertNilF(t, su.wait())
+	assertEqualE(t, meta.resStatus, uploaded)
+	assertEqualE(t, patchURL, "https://storage.googleapis.com/storage/v1/b/gcs-blob/o/storage%2Fusers%2F456%2Fdata.csv")
+	assertEqualE(t, patched["metadata"][sfcDigest], meta.sha256Digest)
+}
+
+func TestUploadCompressedSrcStream(t *testing.T) {
+	content := strings.Repeat("1,abc,2024-01-01\n", 1000)
+	info := execResponseStageInfo{
+		Location:     "sfc-teststage/rwyitestacco/users/1234/",
+		LocationType: "S3",
+	}
+	s3Cli, err := new(snowflakeS3Client).createClient(&info, false, &snowflakeTelemetry{})
+	assertNilF(t, err)
+	sfa := &snowflakeFileTransferAgent{
+		ctx:               context.Background(),
+		sc:                &snowflakeConn{cfg: &Config{}},
+		stageLocationType: s3Client,
+	}
+	var body bytes.Buffer
+	calls := 0
+	meta := &fileMetadata{
+		name:               "data.csv",
+		stageLocationType:  s3Client,
+		stageInfo:          &info,
+		client:             s3Cli,
+		parallel:           1,
+		dstFileName:        "data.csv.zst",
+		srcStream:          bytes.NewBufferString(content),
+		requireCompress:    true,
+		dstCompressionType: compressionTypes["ZSTD"],
+		encryptionMaterial: testStreamUploadEncryption(),
+		overwrite:          true,
+		noSleepingTime:     true,
+		options:            &SnowflakeFileTransferOptions{},
+		mockUploader: mockUploadObjectAPI(func(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*manager.Uploader)) (*manager.UploadOutput, error) {
+			calls++
+			_, isBuffer := params.Body.(*bytes.Buffer)
+			assertFalseE(t, isBuffer, "the compressed stream should not be buffered")
+			if calls == 1 {
+				_, err := params.Body.Read(make([]byte, 10))
+				assertNilE(t, err)
+				return nil, &smithy.GenericAPIError{Code: "InternalError", Message: "mock err"}
+			}
+			body.Reset()
+			_, err := io.Copy(&body, params.Body)
+			return &manager.UploadOutput{}, err
+		}),
+		mockCopier: mockCopyObjectAPI(func(ctx context.Context, params *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error) {
+			return &s3.CopyObjectOutput{}, nil
+		}),
+		sfa: sfa,
+	}
+
+	_, err = sfa.uploadOneFile(meta)
+	assertNilF(t, err)
+	assertEqualE(t, calls, 2, "the upload of a buffered source should be started over")
+	assertEqualE(t, meta.resStatus, uploaded)
+	assertNilE(t, meta.realSrcStream, "the compressed data should not be buffered")
+	assertEqualE(t, meta.srcStream.String(), content, "the source should be preserved")
+
+	var compressed bytes.Buffer
+	n, err := decryptStreamCBC(meta.encryptMeta, meta.encryptionMaterial, 0, &body, &compressed)
+	assertNilF(t, err)
+	compressed.Truncate(n) // drop the padding
+	assertEqualE(t, string(decompressForTest(t, &compressed, meta.dstCompressionType)), content)
+}
+
+func TestStreamUploadIsNotRetried(t *testing.T) {
+	info := execResponseStageInfo{
+		Location:     "sfc-teststage/rwyitestacco/users/1234/",
+		LocationType: "S3",
+	}
+	s3Cli, err := new(snowflakeS3Client).createClient(&info, false, &snowflakeTelemetry{})
+	assertNilF(t, err)
+	calls := 0
+	meta := &fileMetadata{
+		name:              "data.csv",
+		stageLocationType: s3Client,
+		stageInfo:         &info,
+		client:            s3Cli,
+		parallel:          1,
+		dstFileName:       "data.csv",
+		fileStream:        strings.NewReader(strings.Repeat("x", 1024)),
+		streamUpload:      true,
+		overwrite:         true,
+		noSleepingTime:    true,
+		options:           &SnowflakeFileTransferOptions{},
+		mockUploader: mockUploadObjectAPI(func(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*manager.Uploader)) (*manager.UploadOutput, error) {
+			calls++
+			_, err := params.Body.Read(make([]byte, 10))
+			assertNilE(t, err)
+			return nil, &smithy.GenericAPIError{
+				Code:    "InternalError",
+				Message: "mock err",
+			}
+		}),
+		sfa: &snowflakeFileTransferAgent{
+			sc: &snowflakeConn{
+				cfg: &Config{},
+			},
+		},
+	}
+
+	su, err := newStreamUpload(meta, s3Client)
+	assertNilF(t, err)
+	meta.uploadStream = su.reader
+	err = new(remoteStorageUtil).uploadOneFile(meta)
+	assertNotNilF(t, err)
+	var apiErr smithy.APIError
+	assertTrueE(t, errors.As(err, &apiErr))
+	assertEqualE(t, calls, 1)
+	assertEqualE(t, meta.resStatus, errStatus)
+	assertTrueE(t, errors.Is(su.wait(), io.ErrClosedPipe))
+}
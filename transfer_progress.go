@@ -0,0 +1,191 @@
+package gosnowflake
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"os"
+)
+
+// TransferProgressListener receives the progress of every file of a PUT or GET
+// command. Set it with SnowflakeFileTransferOptions.ProgressListener.
+// Files are transferred in parallel, so the methods can be called
+// concurrently for different files. They should return quickly.
+type TransferProgressListener interface {
+	// TransferStarted is called before a file is transferred for the first time.
+	TransferStarted(file TransferFile)
+	// BytesTransferred is called with the number of bytes of the file sent or
+	// received so far. For encrypted stages, it counts encrypted bytes.
+	BytesTransferred(file TransferFile, transferred int64)
+	// TransferRetried is called before a file is transferred again after err.
+	TransferRetried(file TransferFile, err error)
+	// TransferSkipped is called when a file is not uploaded because it already
+	// exists on the stage.
+	TransferSkipped(file TransferFile)
+	// TransferFinished is called when a file has been transferred.
+	TransferFinished(file TransferFile)
+	// TransferFailed is called when a file could not be transferred.
+	TransferFailed(file TransferFile, err error)
+}
+
+// TransferFile identifies a file in TransferProgressListener events.
+type TransferFile struct {
+	// Name is the base name of the file.
+	Name string
+	// Source is the local file for uploads and the stage file for downloads.
+	Source string
+	// Destination is the stage file for uploads and the local file for downloads.
+	Destination string
+	// Upload is true for PUT and false for GET.
+	Upload bool
+	// Size is the size of the source, or 0 if it is not known yet.
+	Size int64
+}
+
+func (meta *fileMetadata) progressListener() TransferProgressListener {
+	if meta.options == nil {
+		return nil
+	}
+	return meta.options.ProgressListener
+}
+
+func (meta *fileMetadata) transferFile() TransferFile {
+	return TransferFile{
+		Name:        meta.name,
+		Source:      meta.srcFileName,
+		Destination: meta.dstFileName,
+		Upload:      meta.sfa != nil && meta.sfa.commandType == uploadCommand,
+		Size:        meta.srcFileSize,
+	}
+}
+
+func (meta *fileMetadata) reportStarted() {
+	if l := meta.progressListener(); l != nil && !meta.transferStarted {
+		meta.transferStarted = true
+		l.TransferStarted(meta.transferFile())
+	}
+}
+
+// reportBytes adds n bytes to the bytes transferred by the current attempt.
+func (meta *fileMetadata) reportBytes(n int64) {
+	if l := meta.progressListener(); l != nil && n > 0 {
+		l.BytesTransferred(meta.transferFile(), meta.transferred.Add(n))
+	}
+}
+
+// reportTotalBytes sets the bytes transferred by the current attempt.
+func (meta *fileMetadata) reportTotalBytes(total int64) {
+	if l := meta.progressListener(); l != nil {
+		meta.transferred.Store(total)
+		l.BytesTransferred(meta.transferFile(), total)
+	}
+}
+
+func (meta *fileMetadata) reportRetried(err error) {
+	meta.transferred.Store(0)
+	if l := meta.progressListener(); l != nil {
+		l.TransferRetried(meta.transferFile(), err)
+	}
+}
+
+// reportResult reports the outcome of a file once it will not be retried.
+func (meta *fileMetadata) reportResult(err error) {
+	l := meta.progressListener()
+	if l == nil {
+		return
+	}
+	switch {
+	case err != nil:
+		l.TransferFailed(meta.transferFile(), err)
+	case meta.resStatus == skipped:
+		l.TransferSkipped(meta.transferFile())
+	case meta.resStatus == uploaded || meta.resStatus == downloaded:
+		l.TransferFinished(meta.transferFile())
+	case meta.resStatus == renewToken || meta.resStatus == renewPresignedURL:
+		// retried by uploadFilesParallel or downloadFilesParallel
+	default:
+		err = cmp.Or(meta.errorDetails, meta.lastError)
+		if err == nil {
+			err = fmt.Errorf("transfer of %v ended with status %v", meta.name, meta.resStatus)
+		}
+		l.TransferFailed(meta.transferFile(), err)
+	}
+}
+
+// transferContext returns the context of the PUT or GET command. Cancelling
+// it aborts the cloud storage requests of the transfer.
+func (meta *fileMetadata) transferContext() context.Context {
+	if meta.sfa != nil && meta.sfa.ctx != nil {
+		return meta.sfa.ctx
+	}
+	return context.Background()
+}
+
+// progressReader returns r counting the bytes read from it, or r itself when
+// there is no listener.
+func (meta *fileMetadata) progressReader(r io.Reader) io.Reader {
+	if meta.progressListener() == nil {
+		return r
+	}
+	return &progressReader{r: r, meta: meta}
+}
+
+// progressFile keeps io.ReaderAt and io.Seeker of a file, so that multipart
+// uploads can still read its parts concurrently.
+func (meta *fileMetadata) progressFile(f *os.File) io.Reader {
+	if meta.progressListener() == nil {
+		return f
+	}
+	return &progressFile{f: f, meta: meta}
+}
+
+func (meta *fileMetadata) progressWriterAt(w io.WriterAt) io.WriterAt {
+	if meta.progressListener() == nil {
+		return w
+	}
+	return &progressWriterAt{w: w, meta: meta}
+}
+
+type progressReader struct {
+	r    io.Reader
+	meta *fileMetadata
+}
+
+func (pr *progressReader) Read(p []byte) (int, error) {
+	n, err := pr.r.Read(p)
+	pr.meta.reportBytes(int64(n))
+	return n, err
+}
+
+type progressFile struct {
+	f    *os.File
+	meta *fileMetadata
+}
+
+func (pf *progressFile) Read(p []byte) (int, error) {
+	n, err := pf.f.Read(p)
+	pf.meta.reportBytes(int64(n))
+	return n, err
+}
+
+func (pf *progressFile) ReadAt(p []byte, off int64) (int, error) {
+	n, err := pf.f.ReadAt(p, off)
+	pf.meta.reportBytes(int64(n))
+	return n, err
+}
+
+func (pf *progressFile) Seek(offset int64, whence int) (int64, error) {
+	return pf.f.Seek(offset, whence)
+}
+
+type progressWriterAt struct {
+	w    io.WriterAt
+	meta *fileMetadata
+}
+
+func (pw *progressWriterAt) WriteAt(p []byte, off int64) (int, error) {
+	n, err := pw.w.WriteAt(p, off)
+	pw.meta.reportBytes(int64(n))
+	return n, err
+}
@@ -0,0 +1,166 @@
+package gosnowflake
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go"
+)
+
+type recordingProgressListener struct {
+	mu          sync.Mutex
+	events      []string
+	transferred int64
+	err         error
+}
+
+func (l *recordingProgressListener) record(event string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if n := len(l.events); n == 0 || l.events[n-1] != event {
+		l.events = append(l.events, event)
+	}
+}
+
+func (l *recordingProgressListener) TransferStarted(TransferFile) { l.record("started") }
+
+func (l *recordingProgressListener) BytesTransferred(_ TransferFile, transferred int64) {
+	l.record("bytes")
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.transferred = transferred
+}
+
+func (l *recordingProgressListener) TransferRetried(TransferFile, error) { l.record("retried") }
+
+func (l *recordingProgressListener) TransferSkipped(TransferFile) { l.record("skipped") }
+
+func (l *recordingProgressListener) TransferFinished(TransferFile) { l.record("finished") }
+
+func (l *recordingProgressListener) TransferFailed(_ TransferFile, err error) {
+	l.record("failed")
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.err = err
+}
+
+type mockAbortMultipartUploadAPI func(ctx context.Context, params *s3.AbortMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error)
+
+func (m mockAbortMultipartUploadAPI) AbortMultipartUpload(
+	ctx context.Context,
+	params *s3.AbortMultipartUploadInput,
+	optFns ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error) {
+	return m(ctx, params, optFns...)
+}
+
+type mockMultiUploadFailure struct {
+	error
+	uploadID string
+}
+
+func (f mockMultiUploadFailure) UploadID() string {
+	return f.uploadID
+}
+
+func newProgressTestUpload(t *testing.T, ctx context.Context, listener TransferProgressListener) (*snowflakeFileTransferAgent, *fileMetadata) {
+	info := execResponseStageInfo{
+		Location:     "sfc-teststage/rwyitestacco/users/1234/",
+		LocationType: "S3",
+	}
+	s3Cli, err := new(snowflakeS3Client).createClient(&info, false, &snowflakeTelemetry{})
+	assertNilF(t, err)
+	dir, err := os.Getwd()
+	assertNilF(t, err)
+	sfa := &snowflakeFileTransferAgent{
+		ctx: ctx,
+		sc: &snowflakeConn{
+			cfg: &Config{},
+		},
+		commandType:       uploadCommand,
+		stageLocationType: s3Client,
+		parallel:          1,
+	}
+	meta := &fileMetadata{
+		name:              "put_get_1.txt",
+		sfa:               sfa,
+		stageLocationType: s3Client,
+		noSleepingTime:    true,
+		parallel:          1,
+		client:            s3Cli,
+		stageInfo:         &info,
+		dstFileName:       "put_get_1.txt",
+		srcFileName:       path.Join(dir, "test_data", "put_get_1.txt"),
+		overwrite:         true,
+		options: &SnowflakeFileTransferOptions{
+			ProgressListener: listener,
+		},
+		mockHeader: mockHeaderAPI(func(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+			return &s3.HeadObjectOutput{}, nil
+		}),
+	}
+	return sfa, meta
+}
+
+func TestTransferProgressListenerUpload(t *testing.T) {
+	listener := &recordingProgressListener{}
+	sfa, meta := newProgressTestUpload(t, context.Background(), listener)
+	calls := 0
+	meta.mockUploader = mockUploadObjectAPI(func(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*manager.Uploader)) (*manager.UploadOutput, error) {
+		calls++
+		if _, err := io.Copy(io.Discard, params.Body); err != nil {
+			return nil, err
+		}
+		if calls == 1 {
+			return nil, &smithy.GenericAPIError{Code: "InternalError", Message: "mock err"}
+		}
+		return &manager.UploadOutput{}, nil
+	})
+
+	assertNilF(t, sfa.uploadFilesParallel([]*fileMetadata{meta}))
+	fi, err := os.Stat(meta.srcFileName)
+	assertNilF(t, err)
+	assertDeepEqualE(t, listener.events, []string{"started", "bytes", "retried", "bytes", "finished"})
+	assertEqualE(t, listener.transferred, fi.Size())
+}
+
+func TestTransferProgressListenerSkipped(t *testing.T) {
+	listener := &recordingProgressListener{}
+	sfa, meta := newProgressTestUpload(t, context.Background(), listener)
+	meta.overwrite = false
+
+	assertNilF(t, sfa.uploadFilesParallel([]*fileMetadata{meta}))
+	assertDeepEqualE(t, listener.events, []string{"started", "skipped"})
+}
+
+func TestCanceledUploadAbortsMultipartUpload(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	listener := &recordingProgressListener{}
+	sfa, meta := newProgressTestUpload(t, ctx, listener)
+	meta.mockUploader = mockUploadObjectAPI(func(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*manager.Uploader)) (*manager.UploadOutput, error) {
+		cancel()
+		return nil, mockMultiUploadFailure{error: context.Canceled, uploadID: "upload-1"}
+	})
+	var abortedUploadID string
+	meta.mockAborter = mockAbortMultipartUploadAPI(func(ctx context.Context, params *s3.AbortMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error) {
+		assertNilE(t, ctx.Err())
+		abortedUploadID = *params.UploadId
+		return &s3.AbortMultipartUploadOutput{}, nil
+	})
+
+	err := sfa.uploadFilesParallel([]*fileMetadata{meta})
+	assertTrueF(t, errors.Is(err, context.Canceled))
+	assertEqualE(t, abortedUploadID, "upload-1")
+	assertDeepEqualE(t, listener.events, []string{"started", "failed"})
+	assertTrueE(t, errors.Is(listener.err, context.Canceled))
+
+	_, err = sfa.uploadOneFile(meta)
+	assertTrueE(t, errors.Is(err, context.Canceled), "a canceled transfer should not start new files")
+}
@@ -41,6 +41,15 @@ const (
 	streamChunkDownload              contextKey = "STREAM_CHUNK_DOWNLOAD"
 	logQueryText                     contextKey = "LOG_QUERY_TEXT"
 	logQueryParameters               contextKey = "LOG_QUERY_PARAMETERS"
+	retryPolicyKey                   contextKey = "RETRY_POLICY"
+	resultCursorKey                  contextKey = "RESULT_CURSOR"
+	chunkDownloadConcurrencyKey      contextKey = "CHUNK_DOWNLOAD_CONCURRENCY"
+	resultMemoryLimitKey             contextKey = "RESULT_MEMORY_LIMIT"
+	resultSpillKey                   contextKey = "RESULT_SPILL"
+	resultCacheModeKey               contextKey = "RESULT_CACHE_MODE"
+	admissionKeyKey                  contextKey = "ADMISSION_KEY"
+	admissionPriorityKey             contextKey = "ADMISSION_PRIORITY"
+	admissionBypassKey               contextKey = "ADMISSION_BYPASS"
 )
 
 var (
@@ -77,6 +86,17 @@ func WithFetchResultByID(ctx context.Context, queryID string) context.Context {
 	return context.WithValue(ctx, fetchResultByID, queryID)
 }
 
+// WithResumeCursor returns a context that retrieves the result of the cursor's query by query ID,
+// starting from the row following the cursor. See SnowflakeRows.GetCursor.
+func WithResumeCursor(ctx context.Context, cursor ResultCursor) context.Context {
+	return context.WithValue(WithFetchResultByID(ctx, cursor.QueryID), resultCursorKey, cursor)
+}
+
+func getResumeCursor(ctx context.Context) (ResultCursor, bool) {
+	cursor, ok := ctx.Value(resultCursorKey).(ResultCursor)
+	return cursor, ok
+}
+
 // WithFileStream returns a context that contains the address of the file stream to be PUT
 func WithFileStream(ctx context.Context, reader io.Reader) context.Context {
 	return context.WithValue(ctx, fileStreamFile, reader)
@@ -196,6 +216,12 @@ func WithLogQueryParameters(ctx context.Context) context.Context {
 	return context.WithValue(ctx, logQueryParameters, true)
 }
 
+// WithRetryPolicy returns a context that uses the given retry policy for all requests made with it.
+// It takes precedence over Config.RetryPolicy.
+func WithRetryPolicy(ctx context.Context, policy RetryPolicy) context.Context {
+	return context.WithValue(ctx, retryPolicyKey, policy)
+}
+
 // Get the request ID from the context if specified, otherwise generate one
 func getOrGenerateRequestIDFromContext(ctx context.Context) UUID {
 	requestID, ok := ctx.Value(snowflakeRequestIDKey).(UUID)